@@ -2,17 +2,15 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"sync"
 
-	_ "github.com/glebarez/go-sqlite"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/aib"
 	"github.com/xoltia/botsu/internal/guilds"
 	"github.com/xoltia/botsu/internal/users"
 )
@@ -39,7 +37,7 @@ type aibGuild struct {
 func main() {
 	flag.Parse()
 
-	db, err := sql.Open("sqlite", *dbLocation)
+	db, err := aib.Open(*dbLocation)
 
 	if err != nil {
 		log.Fatal(err)
@@ -142,97 +140,32 @@ func main() {
 		}
 	}
 
-	activityRows, err := db.QueryContext(ctx, "SELECT id, user_id, name, type, url, date, duration, raw_duration, raw_duration_unit, speed FROM activities")
+	as, err := aib.ReadActivities(ctx, db, "", 0)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	i := 0
-	sem := make(chan struct{}, *maxConcurrency)
-	wg := sync.WaitGroup{}
-
-	for activityRows.Next() {
-		fmt.Printf("Processing row %d\n", i)
-
-		var activity aibActivity
-
-		err = activityRows.Scan(
-			&activity.ID,
-			&activity.UserID,
-			&activity.Name,
-			&activity.Type,
-			&activity.URL,
-			&activity.Date,
-			&activity.Duration,
-			&activity.RawDuration,
-			&activity.RawDurationUnit,
-			&activity.Speed,
-		)
+	converted, err := aib.ConvertAll(ctx, as, aib.Options{
+		VideoMetadata:  *populateVideoMeta,
+		MaxConcurrency: *maxConcurrency,
+		Progress: func(done, total int) {
+			fmt.Printf("Processed activity %d/%d\n", done, total)
+		},
+		VideoError: func(a *aib.Activity, err error) {
+			log.Println(err)
+		},
+	})
 
-		if err != nil {
-			log.Fatal(err)
-		}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		// get tags, relations are in tags_to_activities (tag_id, activity_id)
-		// and tags (id, name)
-		tagsRows, err := db.QueryContext(ctx, `
-			SELECT tags.name
-			FROM tags_to_activities
-			INNER JOIN tags ON tags_to_activities.tag_id = tags.id
-			WHERE tags_to_activities.activity_id = ?
-		`, activity.ID)
+	for _, newActivity := range converted {
+		err = activityRepo.Create(ctx, newActivity)
 
 		if err != nil {
-			log.Fatal(err)
-		}
-
-		for tagsRows.Next() {
-			var tag string
-
-			err = tagsRows.Scan(&tag)
-
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			activity.Tags = append(activity.Tags, tag)
-		}
-
-		newActivity := activity.asNewFormatActivity()
-
-		if *populateVideoMeta && newActivity.MediaType != nil &&
-			*newActivity.MediaType == activities.ActivityMediaTypeVideo && activity.URL != nil {
-			fmt.Printf("Processing video %s\n", *activity.URL)
-			wg.Add(1)
-			sem <- struct{}{}
-
-			go func() {
-				defer wg.Done()
-				defer func() { <-sem }()
-
-				err = populateVideoMetadata(ctx, newActivity, *activity.URL)
-
-				if err != nil {
-					log.Println(err)
-				}
-
-				err = activityRepo.Create(ctx, newActivity)
-
-				if err != nil {
-					log.Println(err)
-				}
-			}()
-		} else {
-			err = activityRepo.Create(ctx, newActivity)
-
-			if err != nil {
-				log.Println(err)
-			}
+			log.Println(err)
 		}
-
-		i++
 	}
-
-	wg.Wait()
 }
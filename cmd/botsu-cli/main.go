@@ -15,7 +15,9 @@ import (
 	"time"
 
 	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/aib"
 	"github.com/xoltia/botsu/internal/videos"
+	activitiesPub "github.com/xoltia/botsu/pkg/activities"
 	"github.com/xoltia/botsu/pkg/ref"
 )
 
@@ -44,6 +46,12 @@ var (
 	videoURLShortFlag             = flag.String("vu", "", "URL of the video (shorthand)")
 	destinationFlag               = flag.String("o", "", "destination of the log file")
 	serverFlag                    = flag.Bool("server", false, "run as a server")
+	aibFlag                       = flag.String("aib", "", "convert the activities of an AnotherImmersionBot database")
+	aibUserFlag                   = flag.String("aib-user", "", "only convert the activities of this user ID")
+	aibVideoMetaFlag              = flag.Bool("aib-vid-meta", true, "fetch video metadata when converting")
+	dryRunFlag                    = flag.String("dry-run", "", "report what importing a file would do")
+	existingFlag                  = flag.String("existing", "", "exported activities to compare a dry run against")
+	csvMappingFlag                = flag.String("csv-mapping", "", "columns to read from CSV files, e.g. name=Title,duration=Minutes")
 )
 
 func getOneOfNumberFlags[T float64](flags ...*T) T {
@@ -232,6 +240,7 @@ func Usage() {
 	fmt.Fprintln(out, "Examples:")
 	fmt.Fprintf(out, "  %s -a -n \"Bocchi the Rock\" -d 20\n", os.Args[0])
 	fmt.Fprintf(out, "  %s -vu \"https://youtu.be/Wb5A5fAuzJM\"\n", os.Args[0])
+	fmt.Fprintf(out, "  %s -aib bot_data.db -aib-user 123456789012345678 -o aib.jsonl.gz\n", os.Args[0])
 	fmt.Fprintln(out)
 
 	fmt.Fprintln(out, "Flags:")
@@ -259,9 +268,190 @@ func Usage() {
 	fmt.Fprintln(out, "        URL of the video")
 	fmt.Fprintln(out, "  -o string")
 	fmt.Fprintln(out, "        destination of the log file (default: ~/.local/share/botsu/YYYY-MM-DD.jsonl)")
+	fmt.Fprintln(out, "        or of the converted activities (default: stdout, compressed if ending in .gz)")
+	fmt.Fprintln(out, "  -aib string")
+	fmt.Fprintln(out, "        convert the activities of an AnotherImmersionBot database to a file for /import botsu-file")
+	fmt.Fprintln(out, "  -aib-user string")
+	fmt.Fprintln(out, "        only convert the activities of this user ID")
+	fmt.Fprintln(out, "  -aib-vid-meta")
+	fmt.Fprintln(out, "        fetch video metadata when converting (default: true)")
+	fmt.Fprintln(out, "  -dry-run string")
+	fmt.Fprintln(out, "        report the new, duplicate, near duplicate and invalid activities of a file to import")
+	fmt.Fprintln(out, "  -existing string")
+	fmt.Fprintln(out, "        activities exported with /export to compare a dry run against")
+	fmt.Fprintln(out, "  -csv-mapping string")
+	fmt.Fprintln(out, "        columns to read from CSV files, e.g. name=Title,duration=Minutes")
 	fmt.Fprintln(out)
 }
 
+// readImportFile reads the activities of a file in any format /import
+// accepts by its extension.
+func readImportFile(name string) (rows []activities.ImportRow, err error) {
+	f, err := os.Open(name)
+
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	var as []*activities.Activity
+
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		as, err = activitiesPub.ReadCompressedJSONL(f)
+	case strings.HasSuffix(name, ".jsonl"):
+		as, err = activitiesPub.ReadJSONL(f)
+	case strings.HasSuffix(name, ".csv"):
+		var mapping activitiesPub.CSVMapping
+
+		if mapping, err = activitiesPub.ParseCSVMapping(*csvMappingFlag); err != nil {
+			return
+		}
+
+		rows, _, err = activitiesPub.ReadCSV(f, mapping, time.Now())
+		return
+	default:
+		err = fmt.Errorf("unknown file type: %s", name)
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	for i, a := range as {
+		rows = append(rows, activities.ImportRow{Line: i + 1, Activity: a})
+	}
+
+	return
+}
+
+func dryRunImport() (err error) {
+	rows, err := readImportFile(*dryRunFlag)
+
+	if err != nil {
+		err = fmt.Errorf("failed to read %s: %w", *dryRunFlag, err)
+		return
+	}
+
+	var existing []*activities.Activity
+
+	if *existingFlag != "" {
+		existingRows, err := readImportFile(*existingFlag)
+
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", *existingFlag, err)
+		}
+
+		for _, row := range existingRows {
+			if row.Activity != nil {
+				existing = append(existing, row.Activity)
+			}
+		}
+	}
+
+	plan := activities.PlanOfflineImport(rows, existing)
+
+	fmt.Println(plan.Summary())
+
+	printRows := func(title string, rows []activities.ImportRow) {
+		if len(rows) == 0 {
+			return
+		}
+
+		fmt.Printf("\n%s:\n", title)
+
+		for _, row := range rows {
+			if row.Err != nil {
+				fmt.Printf("  Line %d: %s\n", row.Line, row.Err)
+				continue
+			}
+
+			fmt.Printf(
+				"  Line %d: %s, %s on %s\n",
+				row.Line,
+				row.Activity.Name,
+				row.Activity.Duration.Round(time.Minute),
+				row.Activity.Date.Format("2006/01/02 15:04"),
+			)
+		}
+	}
+
+	printRows("Duplicates (skipped by default)", plan.Duplicates)
+	printRows("Near duplicates", plan.NearDuplicates)
+	printRows("Invalid", plan.Invalid)
+
+	return
+}
+
+func convertAIB() (err error) {
+	db, err := aib.Open(*aibFlag)
+
+	if err != nil {
+		return
+	}
+
+	defer db.Close()
+
+	ctx := context.Background()
+	as, err := aib.ReadActivities(ctx, db, *aibUserFlag, 0)
+
+	if err != nil {
+		err = fmt.Errorf("failed to read database: %w", err)
+		return
+	}
+
+	converted, err := aib.ConvertAll(ctx, as, aib.Options{
+		VideoMetadata:  *aibVideoMetaFlag,
+		MaxConcurrency: 3,
+		Progress: func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rConverted %d/%d activities", done, total)
+		},
+		VideoError: func(a *aib.Activity, err error) {
+			fmt.Fprintf(os.Stderr, "\n\u001b[33mWarning: %s: %s\u001b[0m\n", *a.URL, err)
+		},
+	})
+
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return
+	}
+
+	var out io.Writer = os.Stdout
+	format := activitiesPub.FormatJSONL
+
+	if *destinationFlag != "" {
+		f, err := os.OpenFile(*destinationFlag, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+		if err != nil {
+			return fmt.Errorf("failed to open file: %s: %w", *destinationFlag, err)
+		}
+
+		defer f.Close()
+		out = f
+
+		if strings.HasSuffix(*destinationFlag, ".gz") {
+			format = activitiesPub.FormatCompressedJSONL
+		}
+	}
+
+	w, err := activitiesPub.NewWriter(format, out)
+
+	if err != nil {
+		return
+	}
+
+	for _, a := range converted {
+		if err = w.Write(a); err != nil {
+			return
+		}
+	}
+
+	return w.Close()
+}
+
 func runServer() error {
 	mu := sync.Mutex{}
 
@@ -277,10 +467,7 @@ func runServer() error {
 			return
 		}
 
-		// workaround for UserID check
-		a.UserID = strings.Repeat("0", 18)
-
-		if err := activities.ValidateExternalActivity(a); err != nil {
+		if err := activities.ValidateOfflineActivity(a); err != nil {
 			fmt.Fprintf(os.Stderr, "\u001b[31mError: %s\u001b[0m\n", err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -304,6 +491,24 @@ func main() {
 	flag.Usage = Usage
 	flag.Parse()
 
+	if *dryRunFlag != "" {
+		if err := dryRunImport(); err != nil {
+			fmt.Fprintf(os.Stderr, "\u001b[31mError: %s\u001b[0m\n\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if *aibFlag != "" {
+		if err := convertAIB(); err != nil {
+			fmt.Fprintf(os.Stderr, "\u001b[31mError: %s\u001b[0m\n\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	if *serverFlag {
 		err := runServer()
 
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +21,14 @@ type Config struct {
 	NoPanic            bool           `toml:"no_panic"`
 	DataUpdateInterval time.Duration  `toml:"data_update_interval"`
 	GoogleAPIKey       string         `toml:"google_api_key"`
+	// How long soft-deleted activities are kept before being
+	// permanently removed. Zero keeps them forever.
+	TrashRetention time.Duration `toml:"trash_retention"`
+	// Timers running for longer than this are stopped and logged automatically.
+	TimerMaxDuration time.Duration `toml:"timer_max_duration"`
+	// Font files used in charts for characters missing from
+	// the built-in font, such as Japanese text.
+	ChartFonts []string `toml:"chart_fonts"`
 }
 
 type DatabaseConfig struct {
@@ -63,6 +72,10 @@ func (c *Config) LoadDefaults() {
 		c.DataUpdateInterval = 7 * 24 * time.Hour
 	}
 
+	if c.TimerMaxDuration <= 0 {
+		c.TimerMaxDuration = 12 * time.Hour
+	}
+
 	if c.Database.SSLMode == "" {
 		c.Database.SSLMode = "disable"
 	}
@@ -172,6 +185,31 @@ func (c *Config) LoadEnv() error {
 		c.GoogleAPIKey = googleAPIKey
 	}
 
+	trashRetention, ok := os.LookupEnv("BOTSU_TRASH_RETENTION")
+	if ok {
+		duration, err := time.ParseDuration(trashRetention)
+		if err != nil {
+			return err
+		}
+
+		c.TrashRetention = duration
+	}
+
+	timerMaxDuration, ok := os.LookupEnv("BOTSU_TIMER_MAX_DURATION")
+	if ok {
+		duration, err := time.ParseDuration(timerMaxDuration)
+		if err != nil {
+			return err
+		}
+
+		c.TimerMaxDuration = duration
+	}
+
+	chartFonts, ok := os.LookupEnv("BOTSU_CHART_FONTS")
+	if ok {
+		c.ChartFonts = filepath.SplitList(chartFonts)
+	}
+
 	return nil
 }
 
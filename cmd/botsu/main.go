@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lmittmann/tint"
 	"github.com/xoltia/botsu/internal/activities"
@@ -25,9 +27,14 @@ import (
 	"github.com/xoltia/botsu/internal/goals"
 	"github.com/xoltia/botsu/internal/guilds"
 	"github.com/xoltia/botsu/internal/mediadata"
+	"github.com/xoltia/botsu/internal/privacy"
+	"github.com/xoltia/botsu/internal/scoring"
+	"github.com/xoltia/botsu/internal/timers"
 	"github.com/xoltia/botsu/internal/users"
 	"github.com/xoltia/botsu/internal/videos"
+	"github.com/xoltia/botsu/internal/works"
 	"github.com/xoltia/botsu/migrations"
+	"github.com/xoltia/botsu/pkg/chart"
 )
 
 var (
@@ -35,6 +42,9 @@ var (
 	enableProfiling = flag.Bool("profiling", false, "Enable profiling")
 	skipMigration   = flag.Bool("skip-migration", false, "Skip automatic migration")
 	skipDataUpdate  = flag.Bool("skip-data-update", false, "Skip automatic data update")
+	exportUserData  = flag.String("export-user-data", "", "Write all data of the user with the given ID to a JSON file, then exit")
+	deleteUserData  = flag.String("delete-user-data", "", "Permanently delete all data of the user with the given ID, then exit")
+	backfillTotals  = flag.Bool("backfill-totals", false, "Rebuild daily activity totals and score activities for leaderboards and charts, then exit")
 )
 
 func main() {
@@ -197,7 +207,140 @@ func main() {
 	guildRepo := guilds.NewGuildRepository(pool)
 	timeService := users.NewUserTimeService(userRepo, guildRepo)
 	goalRepo := goals.NewGoalRepository(pool)
-	goalService := goals.NewGoalService(goalRepo, timeService)
+	timerRepo := timers.NewTimerRepository(pool)
+	workRepo := works.NewWorkRepository(pool)
+	workService := works.NewWorkService(workRepo, activityRepo)
+	scoringService := scoring.NewScoringService(pool)
+	goalService := goals.NewGoalService(goalRepo, activityRepo, timeService, scoringService)
+	workService.OnChange(func(ctx context.Context, tx pgx.Tx, change works.ActivityChange, userID string, as []*activities.Activity) error {
+		// Points are kept for deleted activities, so restoring them needs no scoring
+		if change != works.ActivitiesLogged && change != works.ActivitiesImported {
+			return nil
+		}
+		return scoringService.ScoreActivitiesTx(ctx, tx, userID, as)
+	})
+	workService.OnChange(func(ctx context.Context, tx pgx.Tx, _ works.ActivityChange, userID string, _ []*activities.Activity) error {
+		return goalService.ContributeTx(ctx, tx, userID)
+	})
+	workService.OnChange(func(ctx context.Context, tx pgx.Tx, change works.ActivityChange, userID string, _ []*activities.Activity) error {
+		// Newly logged activities are added to goals as they are checked
+		if change != works.ActivitiesDeleted && change != works.ActivitiesRestored {
+			return nil
+		}
+		_, err := goalService.RecalculateTx(ctx, tx, userID)
+		return err
+	})
+	privacyService := privacy.NewPrivacyService(pool, activityRepo, userRepo, goalRepo, workRepo, timerRepo)
+
+	if *exportUserData != "" {
+		data, err := privacyService.Export(ctx, *exportUserData)
+		if err != nil {
+			logger.Error("Unable to export user data", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+
+		path := fmt.Sprintf("botsu-data-%s.json", *exportUserData)
+		contents, err := json.MarshalIndent(data, "", "  ")
+		if err == nil {
+			err = os.WriteFile(path, contents, 0o600)
+		}
+		if err != nil {
+			logger.Error("Unable to write user data", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Wrote user data to %s\n", path)
+		return
+	}
+
+	if *deleteUserData != "" {
+		report, err := privacyService.Delete(ctx, *deleteUserData)
+		if err != nil {
+			logger.Error("Unable to delete user data", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+
+		fmt.Printf(
+			"Deleted %d activities, %d goals, %d works, %d timers and %d guild memberships (settings deleted: %t)\n",
+			report.Activities,
+			report.Goals,
+			report.Works,
+			report.Timers,
+			report.GuildMemberships,
+			report.Settings,
+		)
+		return
+	}
+
+	if *backfillTotals {
+		logger.Info("Rebuilding daily totals")
+		err := activityRepo.RebuildDailyTotals(ctx, func(done, total int) {
+			if done%100 == 0 || done == total {
+				logger.Info("Rebuilding daily totals", slog.Int("done", done), slog.Int("total", total))
+			}
+		})
+		if err != nil {
+			logger.Error("Unable to rebuild daily totals", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+
+		logger.Info("Daily totals rebuilt")
+
+		logger.Info("Scoring activities")
+		if err = scoringService.ScoreAll(ctx); err != nil {
+			logger.Error("Unable to score activities", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+
+		logger.Info("Activities scored")
+		return
+	}
+
+	for _, path := range config.ChartFonts {
+		err := chart.LoadFallbackFont(path)
+		if err != nil {
+			logger.Error("Unable to load chart font", slog.String("path", path), slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// Activity names in charts are often Japanese, which would otherwise be
+	// drawn as empty boxes. Only the bot draws charts, so the operator flags
+	// above run without fonts.
+	if !chart.CanDraw("日本語、ひらがな、カタカナ") {
+		logger.Error("No chart font can draw Japanese text, add a CJK font such as Noto Sans CJK to chart_fonts")
+		os.Exit(1)
+	}
+
+	if config.TrashRetention > 0 {
+		logger.Debug("Starting trash purge ticker", slog.Duration("retention", config.TrashRetention))
+		purgeTrash := func() {
+			purged, err := activityRepo.PurgeDeletedBefore(ctx, time.Now().Add(-config.TrashRetention))
+			if err != nil {
+				logger.Error("Unable to purge deleted activities", slog.String("err", err.Error()))
+				return
+			}
+
+			if purged > 0 {
+				logger.Info("Purged deleted activities", slog.Int64("count", purged))
+			}
+		}
+
+		go func() {
+			purgeTicker := time.NewTicker(time.Hour)
+			defer purgeTicker.Stop()
+
+			purgeTrash()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-purgeTicker.C:
+					purgeTrash()
+				}
+			}
+		}()
+	}
 
 	bot := bot.NewBot(ctx, bot.Options{
 		Logger:        logger.WithGroup("bot"),
@@ -206,16 +349,39 @@ func main() {
 	})
 	// bot.SetNoPanic(config.NoPanic)
 
-	bot.AddCommand(commands.LogCommandData, commands.NewLogCommand(activityRepo, userRepo, guildRepo, mediaSearcher, goalService, timeService))
+	bot.AddCommand(commands.LogCommandData, commands.NewLogCommand(activityRepo, userRepo, guildRepo, mediaSearcher, goalService, timeService, workService))
 	bot.AddCommand(commands.ConfigCommandData, commands.NewConfigCommand(userRepo, activityRepo))
-	bot.AddCommand(commands.HistoryCommandData, commands.NewHistoryCommand(activityRepo))
-	bot.AddCommand(commands.LeaderboardCommandData, commands.NewLeaderboardCommand(activityRepo, userRepo, guildRepo))
-	bot.AddCommand(commands.UndoCommandData, commands.NewUndoCommand(activityRepo))
-	bot.AddCommand(commands.ChartCommandData, commands.NewChartCommand(activityRepo, userRepo, guildRepo))
+	bot.AddTask("send-daily-goal-reminders", time.Minute, commands.NewDailyGoalReminder(userRepo, activityRepo, timeService, logger.WithGroup("daily_goals")).SendReminders)
+	bot.AddCommand(commands.HistoryCommandData, commands.NewHistoryCommand(activityRepo, timeService))
+	bot.AddCommand(commands.LeaderboardCommandData, commands.NewLeaderboardCommand(activityRepo, userRepo, guildRepo, timeService, scoringService))
+	bot.AddCommand(commands.UndoCommandData, commands.NewUndoCommand(activityRepo, workService))
+	bot.AddCommand(commands.ChartCommandData, commands.NewChartCommand(activityRepo, userRepo, guildRepo, timeService, scoringService))
 	bot.AddCommand(commands.GuildConfigCommandData, commands.NewGuildConfigCommand(guildRepo))
-	bot.AddCommand(commands.ExportCommandData, commands.NewExportCommand(activityRepo))
-	bot.AddCommand(commands.ImportCommandData, commands.NewImportCommand(activityRepo))
-	bot.AddCommand(commands.GoalCommandData, commands.NewGoalCommand(goalService))
+	bot.AddCommand(commands.ExportCommandData, commands.NewExportCommand(activityRepo, userRepo, timeService))
+	bot.AddCommand(commands.ImportCommandData, commands.NewImportCommand(activityRepo, workService, timeService))
+
+	goalCommand := commands.NewGoalCommand(goalService, userRepo, timeService, logger.WithGroup("goals"))
+	bot.AddCommand(commands.GoalCommandData, goalCommand)
+	bot.AddTask("send-goal-reminders", time.Minute, goalCommand.SendReminders)
+
+	goalRolloverWorker := commands.NewGoalRolloverWorker(goalService, logger.WithGroup("goal_rollover"))
+	goalRolloverWorker.OnPeriodClosed(goalCommand.SendSummary)
+	bot.AddTask("roll-over-goals", time.Minute, goalRolloverWorker.Run)
+
+	guildGoalCommand := commands.NewGuildGoalCommand(goalService, guildRepo, timeService, logger.WithGroup("guild_goals"))
+	bot.AddCommand(commands.GuildGoalCommandData, guildGoalCommand)
+	bot.AddTask("refresh-guild-goal-messages", time.Minute, guildGoalCommand.RefreshMessages)
+	bot.AddTask("announce-reached-guild-goals", time.Minute, guildGoalCommand.AnnounceReached)
+
+	bot.AddCommand(commands.TrashCommandData, commands.NewTrashCommand(activityRepo, workService, config.TrashRetention))
+	bot.AddCommand(commands.PrivacyCommandData, commands.NewPrivacyCommand(privacyService))
+	bot.AddCommand(commands.ScoringCommandData, commands.NewScoringCommand(scoringService))
+	bot.AddCommand(commands.WorksCommandData, commands.NewWorksCommand(workRepo, timeService))
+
+	timerCommand := commands.NewTimerCommand(timerRepo, workService, goalService, config.TimerMaxDuration, logger.WithGroup("timers"))
+	bot.AddCommand(commands.TimerCommandData, timerCommand)
+	bot.AddComponentHandler("timer", timerCommand)
+	bot.AddTask("stop-expired-timers", time.Minute, timerCommand.StopExpired)
 	logger.Info("Starting bot")
 
 	intents := discordgo.IntentsNone
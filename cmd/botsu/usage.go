@@ -24,6 +24,9 @@ func init() {
 		fmt.Fprintln(flag.CommandLine.Output(), "  BOTSU_VNDB_DUMP_PATH: Path to vndb dump")
 		fmt.Fprintln(flag.CommandLine.Output(), "  BOTSU_USE_MEMBERS_INTENT: Whether to use the members intent")
 		fmt.Fprintln(flag.CommandLine.Output(), "  BOTSU_NO_PANIC: Whether to recover from panics caused by command handlers")
+		fmt.Fprintln(flag.CommandLine.Output(), "  BOTSU_TRASH_RETENTION: How long deleted activities are kept before being purged (0 keeps them forever)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  BOTSU_TIMER_MAX_DURATION: How long a timer can run before it is stopped automatically (default 12h)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  BOTSU_CHART_FONTS: Paths of fallback chart fonts, separated as in PATH (one must draw Japanese text)")
 
 		fmt.Fprintln(flag.CommandLine.Output(), "\nConfig file:")
 		printTOMLStructure(
@@ -48,3 +48,30 @@ func (a *Activity) SetMeta(key string, value any) {
 
 	kv[key] = value
 }
+
+// MetaNumber returns a numeric meta value. Numbers set with SetMeta keep
+// their Go type while those read from the database are float64, so both are
+// accepted.
+func (a *Activity) MetaNumber(key string) (float64, bool) {
+	kv, ok := a.Meta.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+
+	switch v := kv[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,257 @@
+package activities
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Activities logged this close to an existing activity of the same type and
+// name, with a duration at most nearDuplicateDuration apart, are likely the
+// same activity logged twice.
+const (
+	nearDuplicateWindow   = 10 * time.Minute
+	nearDuplicateDuration = time.Minute
+)
+
+// ImportRow is an activity to be imported. Line is its position in the
+// imported file and Err is set if it could not be read.
+type ImportRow struct {
+	Line     int
+	Activity *Activity
+	Err      error
+}
+
+// ImportPlan sorts the rows of an import by whether they should be imported.
+type ImportPlan struct {
+	New []ImportRow
+	// Rows with the same date, duration, name and type as an existing
+	// activity or an earlier row
+	Duplicates []ImportRow
+	// Rows similar to an existing activity or an earlier row, see
+	// nearDuplicateWindow
+	NearDuplicates []ImportRow
+	Invalid        []ImportRow
+}
+
+type duplicateKey struct {
+	date        int64
+	duration    time.Duration
+	name        string
+	primaryType string
+}
+
+func keyOf(a *Activity) duplicateKey {
+	return duplicateKey{
+		// Dates are stored with microsecond precision
+		date:        a.Date.Truncate(time.Microsecond).UnixMicro(),
+		duration:    a.Duration,
+		name:        a.Name,
+		primaryType: a.PrimaryType,
+	}
+}
+
+func isNearDuplicate(a, b *Activity) bool {
+	if a.PrimaryType != b.PrimaryType {
+		return false
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(a.Name), strings.TrimSpace(b.Name)) {
+		return false
+	}
+
+	return absDuration(a.Date.Sub(b.Date)) <= nearDuplicateWindow &&
+		absDuration(a.Duration-b.Duration) <= nearDuplicateDuration
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// PlanImport validates rows and compares them to the existing activities of
+// the user, which should include at least those within nearDuplicateWindow
+// of the imported dates (see ImportRange).
+func PlanImport(rows []ImportRow, existing []*Activity) *ImportPlan {
+	return planImport(rows, existing, ValidateExternalActivity)
+}
+
+// PlanOfflineImport is PlanImport for activities which don't belong to a user
+// yet, such as when planning an import offline.
+func PlanOfflineImport(rows []ImportRow, existing []*Activity) *ImportPlan {
+	return planImport(rows, existing, ValidateOfflineActivity)
+}
+
+func planImport(rows []ImportRow, existing []*Activity, validate func(a *Activity) error) *ImportPlan {
+	plan := &ImportPlan{}
+
+	seen := make(map[duplicateKey]struct{}, len(existing)+len(rows))
+	for _, a := range existing {
+		seen[keyOf(a)] = struct{}{}
+	}
+
+	// Candidates for near duplicates, by type and lowercase name
+	similar := make(map[string][]*Activity)
+	similarKey := func(a *Activity) string {
+		return a.PrimaryType + "\x00" + strings.ToLower(strings.TrimSpace(a.Name))
+	}
+
+	for _, a := range existing {
+		similar[similarKey(a)] = append(similar[similarKey(a)], a)
+	}
+
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = validate(row.Activity)
+		}
+
+		if row.Err != nil {
+			plan.Invalid = append(plan.Invalid, row)
+			continue
+		}
+
+		a := row.Activity
+		key := keyOf(a)
+
+		if _, ok := seen[key]; ok {
+			plan.Duplicates = append(plan.Duplicates, row)
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		near := false
+		for _, b := range similar[similarKey(a)] {
+			if isNearDuplicate(a, b) {
+				near = true
+				break
+			}
+		}
+
+		similar[similarKey(a)] = append(similar[similarKey(a)], a)
+
+		if near {
+			plan.NearDuplicates = append(plan.NearDuplicates, row)
+		} else {
+			plan.New = append(plan.New, row)
+		}
+	}
+
+	return plan
+}
+
+// ImportRange returns the range of dates existing activities are needed for
+// to plan an import of rows. It returns false if no row has an activity.
+func ImportRange(rows []ImportRow) (start, end time.Time, ok bool) {
+	for _, row := range rows {
+		if row.Activity == nil {
+			continue
+		}
+
+		if !ok || row.Activity.Date.Before(start) {
+			start = row.Activity.Date
+		}
+
+		if !ok || row.Activity.Date.After(end) {
+			end = row.Activity.Date
+		}
+
+		ok = true
+	}
+
+	return start.Add(-nearDuplicateWindow), end.Add(nearDuplicateWindow), ok
+}
+
+// Activities returns the activities to import. Duplicates are only included
+// if includeDuplicates is set, and near duplicates unless skipNear is set.
+func (p *ImportPlan) Activities(includeDuplicates, skipNear bool) []*Activity {
+	var as []*Activity
+
+	add := func(rows []ImportRow) {
+		for _, row := range rows {
+			as = append(as, row.Activity)
+		}
+	}
+
+	add(p.New)
+	if !skipNear {
+		add(p.NearDuplicates)
+	}
+	if includeDuplicates {
+		add(p.Duplicates)
+	}
+
+	return as
+}
+
+// Summary counts the rows of each kind, e.g. "3 new, 2 duplicates, 0 near
+// duplicates, 1 invalid".
+func (p *ImportPlan) Summary() string {
+	return fmt.Sprintf(
+		"%d new, %d duplicates, %d near duplicates, %d invalid",
+		len(p.New),
+		len(p.Duplicates),
+		len(p.NearDuplicates),
+		len(p.Invalid),
+	)
+}
+
+// Meta keys identifying the work an activity is of.
+var workIDMetaKeys = []string{"anidb_id", "vndb_id"}
+
+func metaString(a *Activity, key string) (string, bool) {
+	kv, ok := a.Meta.(map[string]interface{})
+	if !ok || kv[key] == nil {
+		return "", false
+	}
+
+	// Numbers read from the database are float64 but set as integers, both
+	// of which format the same when whole
+	return fmt.Sprint(kv[key]), true
+}
+
+func equalOptional(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// overlaps reports whether two activities, ending at their dates, were done
+// at the same time, allowing for a gap of window.
+func overlaps(a, b *Activity, window time.Duration) bool {
+	aStart, bStart := a.Date.Add(-a.Duration), b.Date.Add(-b.Duration)
+	return !aStart.After(b.Date.Add(window)) && !bStart.After(a.Date.Add(window))
+}
+
+// FindLikelyDuplicate returns the first of existing that a is likely a
+// duplicate of, or nil. Activities are likely duplicates if they are of the
+// same anime or visual novel and overlap, or are identical and logged
+// within window of each other.
+func FindLikelyDuplicate(a *Activity, existing []*Activity, window time.Duration) *Activity {
+	for _, e := range existing {
+		for _, key := range workIDMetaKeys {
+			id, ok := metaString(a, key)
+			if !ok {
+				continue
+			}
+
+			if existingID, ok := metaString(e, key); ok && id == existingID && overlaps(a, e, window) {
+				return e
+			}
+		}
+
+		identical := a.Name == e.Name &&
+			a.PrimaryType == e.PrimaryType &&
+			a.Duration == e.Duration &&
+			equalOptional(a.MediaType, e.MediaType)
+
+		if identical && absDuration(a.Date.Sub(e.Date)) <= window {
+			return e
+		}
+	}
+
+	return nil
+}
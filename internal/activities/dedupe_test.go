@@ -0,0 +1,108 @@
+package activities_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/activities"
+)
+
+func importActivity(name string, date time.Time, duration time.Duration) *activities.Activity {
+	a := activities.NewActivity()
+	a.UserID = "123456789012345678"
+	a.Name = name
+	a.PrimaryType = activities.ActivityImmersionTypeListening
+	a.Date = date
+	a.Duration = duration
+	return a
+}
+
+func TestPlanImport(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	existing := []*activities.Activity{
+		importActivity("Frieren", date, 24*time.Minute),
+	}
+
+	invalid := importActivity("Painting", date, time.Hour)
+	invalid.PrimaryType = "painting"
+
+	rows := []activities.ImportRow{
+		// Same as existing, with a date in another location and more precision
+		{Line: 1, Activity: importActivity("Frieren", date.In(time.FixedZone("JST", 9*3600)).Add(time.Nanosecond), 24*time.Minute)},
+		{Line: 2, Activity: importActivity("frieren", date.Add(5*time.Minute), 24*time.Minute+30*time.Second)},
+		{Line: 3, Activity: importActivity("Frieren", date.Add(time.Hour), 24*time.Minute)},
+		{Line: 4, Activity: importActivity("Frieren", date.Add(time.Hour), 24*time.Minute)},
+		{Line: 5, Activity: invalid},
+		{Line: 6, Err: assert.AnError},
+	}
+
+	plan := activities.PlanImport(rows, existing)
+
+	lines := func(rows []activities.ImportRow) (ls []int) {
+		for _, row := range rows {
+			ls = append(ls, row.Line)
+		}
+		return
+	}
+
+	assert.Equal(t, []int{3}, lines(plan.New))
+	assert.Equal(t, []int{1, 4}, lines(plan.Duplicates))
+	assert.Equal(t, []int{2}, lines(plan.NearDuplicates))
+	assert.Equal(t, []int{5, 6}, lines(plan.Invalid))
+	assert.ErrorIs(t, plan.Invalid[0].Err, activities.ErrInvalidPrimaryType)
+
+	assert.Len(t, plan.Activities(false, false), 2)
+	assert.Len(t, plan.Activities(false, true), 1)
+	assert.Len(t, plan.Activities(true, false), 4)
+	assert.Equal(t, "1 new, 2 duplicates, 1 near duplicates, 2 invalid", plan.Summary())
+
+	start, end, ok := activities.ImportRange(rows)
+	assert.True(t, ok)
+	assert.True(t, start.Before(date))
+	assert.True(t, end.After(date.Add(time.Hour)))
+}
+
+func TestPlanOfflineImport(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	unowned := importActivity("Frieren", date, 24*time.Minute)
+	unowned.UserID = ""
+
+	rows := []activities.ImportRow{{Line: 1, Activity: unowned}}
+
+	// Activities planned offline don't belong to a user yet
+	assert.Len(t, activities.PlanOfflineImport(rows, nil).New, 1)
+
+	plan := activities.PlanImport(rows, nil)
+	if assert.Len(t, plan.Invalid, 1) {
+		assert.ErrorIs(t, plan.Invalid[0].Err, activities.ErrInvalidUserID)
+	}
+}
+
+func TestFindLikelyDuplicate(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	anime := importActivity("Frieren", date, 24*time.Minute)
+	anime.SetMeta("anidb_id", float64(17617))
+
+	manual := importActivity("Podcast", date.Add(-2*time.Hour), time.Hour)
+	existing := []*activities.Activity{anime, manual}
+
+	// Overlapping episode of the same anime, with the ID set as an integer
+	sameWork := importActivity("Sousou no Frieren", date.Add(10*time.Minute), 24*time.Minute)
+	sameWork.SetMeta("anidb_id", 17617)
+	assert.Same(t, anime, activities.FindLikelyDuplicate(sameWork, existing, 5*time.Minute))
+
+	nextEpisode := importActivity("Frieren", date.Add(time.Hour), 24*time.Minute)
+	nextEpisode.SetMeta("anidb_id", 17617)
+	assert.Nil(t, activities.FindLikelyDuplicate(nextEpisode, existing, 5*time.Minute))
+
+	sameManual := importActivity("Podcast", date.Add(-2*time.Hour+3*time.Minute), time.Hour)
+	assert.Same(t, manual, activities.FindLikelyDuplicate(sameManual, existing, 5*time.Minute))
+	assert.Nil(t, activities.FindLikelyDuplicate(sameManual, existing, time.Minute))
+
+	otherManual := importActivity("Podcast", date.Add(-2*time.Hour), 30*time.Minute)
+	assert.Nil(t, activities.FindLikelyDuplicate(otherManual, existing, 5*time.Minute))
+}
@@ -0,0 +1,85 @@
+package activities
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ActivityFilter narrows down queries over a user's activities.
+// Zero values are ignored.
+type ActivityFilter struct {
+	PrimaryType *string
+	MediaType   *string
+	Start       *time.Time
+	End         *time.Time
+	MinDuration time.Duration
+	// Case-insensitive substring match on the activity name
+	NameSearch string
+}
+
+func (f *ActivityFilter) IsEmpty() bool {
+	return f == nil || (f.PrimaryType == nil &&
+		f.MediaType == nil &&
+		f.Start == nil &&
+		f.End == nil &&
+		f.MinDuration == 0 &&
+		f.NameSearch == "")
+}
+
+// whereClause returns SQL conditions (each prefixed with AND) for the filter
+// along with the arguments they reference. Argument placeholders start
+// after the given number of existing arguments.
+func (f *ActivityFilter) whereClause(table string, argOffset int) (string, []interface{}) {
+	if f.IsEmpty() {
+		return "", nil
+	}
+
+	var (
+		sb   strings.Builder
+		args []interface{}
+	)
+
+	column := func(name string) string {
+		if table == "" {
+			return name
+		}
+		return table + "." + name
+	}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		fmt.Fprintf(&sb, "\n\t\tAND "+condition, argOffset+len(args))
+	}
+
+	if f.PrimaryType != nil {
+		add(column("primary_type")+" = $%d", *f.PrimaryType)
+	}
+
+	if f.MediaType != nil {
+		add(column("media_type")+" = $%d", *f.MediaType)
+	}
+
+	if f.Start != nil {
+		add(column("date")+" >= $%d", *f.Start)
+	}
+
+	if f.End != nil {
+		add(column("date")+" <= $%d", *f.End)
+	}
+
+	if f.MinDuration > 0 {
+		add(column("duration")+" >= $%d", f.MinDuration)
+	}
+
+	if f.NameSearch != "" {
+		// Uses the trigram index on activities.name
+		add(column("name")+" ILIKE '%%' || $%d || '%%'", escapeLikePattern(f.NameSearch))
+	}
+
+	return sb.String(), args
+}
+
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -2,6 +2,7 @@ package activities
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -13,6 +14,8 @@ import (
 type MemberStats struct {
 	UserID        string
 	TotalDuration time.Duration
+	// Only set when ranking by points
+	TotalPoints float64
 }
 
 type UserActivityPage struct {
@@ -39,8 +42,18 @@ func NewActivityRepository(pool *pgxpool.Pool) *ActivityRepository {
 	return &ActivityRepository{pool: pool}
 }
 
-func (r *ActivityRepository) Create(ctx context.Context, activity *Activity) error {
-	err := r.pool.QueryRow(
+// queryRower is either a pool or a transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// querier is either a pool or a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func insertActivity(ctx context.Context, q queryRower, activity *Activity) error {
+	return q.QueryRow(
 		ctx,
 		`INSERT INTO activities (user_id, guild_id, name, primary_type, media_type, duration, date, meta)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -54,11 +67,36 @@ func (r *ActivityRepository) Create(ctx context.Context, activity *Activity) err
 		activity.Date,
 		activity.Meta).
 		Scan(&activity.ID)
+}
+
+func (r *ActivityRepository) Create(ctx context.Context, activity *Activity) error {
+	return insertActivity(ctx, r.pool, activity)
+}
 
-	return err
+// CreateTx creates an activity as part of a larger transaction.
+func (r *ActivityRepository) CreateTx(ctx context.Context, tx pgx.Tx, activity *Activity) error {
+	return insertActivity(ctx, tx, activity)
 }
 
-func (r *ActivityRepository) ImportMany(ctx context.Context, as []*Activity) error {
+// CreateManyTx creates activities as part of a larger transaction, setting
+// their IDs.
+func (r *ActivityRepository) CreateManyTx(ctx context.Context, tx pgx.Tx, as []*Activity) error {
+	for _, activity := range as {
+		if err := insertActivity(ctx, tx, activity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportManyTx creates imported activities as part of a larger transaction,
+// setting their IDs and import times.
+func (r *ActivityRepository) ImportManyTx(ctx context.Context, tx pgx.Tx, as []*Activity) error {
+	if len(as) == 0 {
+		return nil
+	}
+
 	columnNames := []string{
 		"user_id",
 		"guild_id",
@@ -73,7 +111,8 @@ func (r *ActivityRepository) ImportMany(ctx context.Context, as []*Activity) err
 		"imported_at",
 	}
 
-	now := time.Now().UTC()
+	// Postgres stores microseconds, so the import can be found by its time
+	now := time.Now().UTC().Truncate(time.Microsecond)
 	rows := make([][]interface{}, len(as))
 
 	for i, a := range as {
@@ -92,25 +131,66 @@ func (r *ActivityRepository) ImportMany(ctx context.Context, as []*Activity) err
 		}
 	}
 
-	_, err := r.pool.CopyFrom(ctx, pgx.Identifier{"activities"}, columnNames, pgx.CopyFromRows(rows))
-	return err
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"activities"}, columnNames, pgx.CopyFromRows(rows))
+	if err != nil {
+		return err
+	}
+
+	// Copied rows are given IDs in order, and an import belongs to one user
+	idRows, err := tx.Query(ctx, `
+		SELECT id
+		FROM activities
+		WHERE user_id = $1
+		AND imported_at = $2
+		ORDER BY id
+	`, as[0].UserID, now)
+	if err != nil {
+		return err
+	}
+
+	ids, err := pgx.CollectRows(idRows, pgx.RowTo[uint64])
+	if err != nil {
+		return err
+	} else if len(ids) != len(as) {
+		return fmt.Errorf("imported %d activities, found %d", len(as), len(ids))
+	}
+
+	for i, a := range as {
+		a.ID = ids[i]
+		a.ImportedAt = &now
+	}
+
+	return nil
 }
 
-func (r *ActivityRepository) UndoImportByUserIDAndTimestamp(
+// Columns returned by the statements deleting and restoring activities. The
+// date is returned as is rather than in the user's timezone.
+const returningActivityColumns = `RETURNING id, user_id, guild_id, name, primary_type, media_type,
+	duration, date, created_at, deleted_at, imported_at, meta`
+
+// UndoImportByUserIDAndTimestampTx deletes the activities of an import as
+// part of a larger transaction, returning them.
+func (r *ActivityRepository) UndoImportByUserIDAndTimestampTx(
 	ctx context.Context,
+	tx pgx.Tx,
 	userID string,
 	timestamp time.Time,
-) (int64, error) {
-	query := `
+) ([]*Activity, error) {
+	rows, err := tx.Query(ctx, `
 		UPDATE activities
 		SET deleted_at = NOW() AT TIME ZONE 'UTC'
 		WHERE user_id = $1
 		AND imported_at = $2 AT TIME ZONE 'UTC'
 		AND deleted_at IS NULL
-	`
+		`+returningActivityColumns,
+		userID,
+		timestamp,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	tag, err := r.pool.Exec(ctx, query, userID, timestamp)
-	return tag.RowsAffected(), err
+	return scanActivities(rows)
 }
 
 func (r *ActivityRepository) GetRecentImportsByUserID(
@@ -154,16 +234,15 @@ func (r *ActivityRepository) GetTotalYouTubeWatchTimeByUserID(
 ) (time.Duration, error) {
 	query := `
 		SELECT COALESCE(SUM(duration), 0)
-		FROM activities
+		FROM daily_user_totals
 		WHERE user_id = $1
 		AND media_type = 'video'
-		AND meta->>'platform' = 'youtube'
-		AND deleted_at IS NULL
-		AND date >= $2
-		AND date <= $3
+		AND video_platform = 'youtube'
+		AND local_date >= $2::date
+		AND local_date <= $3::date
 	`
 
-	row := r.pool.QueryRow(ctx, query, userID, start, end)
+	row := r.pool.QueryRow(ctx, query, userID, localDate(start), localDate(end))
 	var total time.Duration
 	err := row.Scan(&total)
 	return total, err
@@ -217,20 +296,19 @@ func (r *ActivityRepository) GetTotalByUserIDGroupByVideoChannel(
 	query := `
 		SELECT
 			COALESCE(SUM(duration), 0) AS total_duration,
-			meta->>'channel_id' AS channel_id
-		FROM activities
+			video_channel_id
+		FROM daily_user_totals
 		WHERE user_id = $1
 		AND media_type = 'video'
-		AND meta->>'platform' = 'youtube'
-		AND meta->>'channel_id' IS NOT NULL
-		AND date >= $2
-		AND date <= $3
-		AND deleted_at IS NULL
-		GROUP BY meta->>'channel_id'
+		AND video_platform = 'youtube'
+		AND video_channel_id IS NOT NULL
+		AND local_date >= $2::date
+		AND local_date <= $3::date
+		GROUP BY video_channel_id
 		ORDER BY total_duration DESC
 		LIMIT $4
 	`
-	rows, err := r.pool.Query(ctx, query, userID, start, end, limit)
+	rows, err := r.pool.Query(ctx, query, userID, localDate(start), localDate(end), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -267,37 +345,33 @@ func (r *ActivityRepository) GetTotalByUserIDGroupByVideoChannel(
 	return channels, nil
 }
 
+// Returns map of month (YYYY-MM) to total duration, filling in missing months
+// with 0
 func (r *ActivityRepository) GetTotalByUserIDGroupedByMonth(
 	ctx context.Context,
-	userID, guildID string,
+	userID string,
 	start, end time.Time,
 ) (orderedmap.Map[time.Duration], error) {
 	query := `
 		SELECT
 			to_char(date_series.month, 'YYYY-MM') AS month,
-			COALESCE(SUM(duration), 0) AS total_duration
+			COALESCE(SUM(t.duration), 0) AS total_duration
 		FROM (
 			SELECT
 				generate_series(
+					date_trunc('month', $2::date),
 					$3::date,
-					$4::date,
 					interval '1 month'
 				) AS month
 		) AS date_series
-		LEFT JOIN users u ON u.id = $1
-		LEFT JOIN guilds g ON g.id = $2
-		LEFT JOIN activities
-			ON date_series.month = date_trunc(
-				'month',
-				activities.date at time zone COALESCE(u.timezone, g.timezone, 'UTC')
-			)
-			AND activities.user_id = $1
-			AND activities.deleted_at IS NULL
+		LEFT JOIN daily_user_totals t
+			ON date_series.month = date_trunc('month', t.local_date)
+			AND t.user_id = $1
 		GROUP BY month
 		ORDER BY month ASC
 	`
 
-	rows, err := r.pool.Query(ctx, query, userID, guildID, start, end)
+	rows, err := r.pool.Query(ctx, query, userID, localDate(start), localDate(end))
 	if err != nil {
 		return nil, err
 	}
@@ -324,36 +398,29 @@ func (r *ActivityRepository) GetTotalByUserIDGroupedByMonth(
 // filling in missing days with 0 (string formatted according to user's timezone)
 func (r *ActivityRepository) GetTotalByUserIDGroupedByDay(
 	ctx context.Context,
-	userID, guildID string,
+	userID string,
 	start, end time.Time,
 ) (orderedmap.Map[time.Duration], error) {
-	// day should be truncated to a string `YYYY-MM-DD` in the user's timezone
 	query := `
 		SELECT
 			to_char(date_series.day, 'YYYY-MM-DD') AS day,
-			COALESCE(SUM(duration), 0) AS total_duration
+			COALESCE(SUM(t.duration), 0) AS total_duration
 		FROM (
 			SELECT
 				generate_series(
+					$2::date,
 					$3::date,
-					$4::date,
 					interval '1 day'
 				) AS day
 		) AS date_series
-		LEFT JOIN users u ON u.id = $1
-		LEFT JOIN guilds g ON g.id = $2
-		LEFT JOIN activities
-			ON date_series.day = date_trunc(
-				'day',
-				activities.date at time zone COALESCE(u.timezone, g.timezone, 'UTC')
-			)
-			AND activities.user_id = $1
-			AND activities.deleted_at IS NULL
+		LEFT JOIN daily_user_totals t
+			ON t.local_date = date_series.day
+			AND t.user_id = $1
 		GROUP BY day
 		ORDER BY day ASC
 	`
 
-	rows, err := r.pool.Query(ctx, query, userID, guildID, start, end)
+	rows, err := r.pool.Query(ctx, query, userID, localDate(start), localDate(end))
 	if err != nil {
 		return nil, err
 	}
@@ -515,11 +582,76 @@ func (r *ActivityRepository) GetAllByUserID(ctx context.Context, userID, guildID
 	return activities, nil
 }
 
+// StreamByUserID calls fn for each non-deleted activity of a user matching
+// the filter, oldest first, without loading them all into memory. Iteration
+// stops at the first error returned by fn.
+func (r *ActivityRepository) StreamByUserID(
+	ctx context.Context,
+	userID string,
+	filter *ActivityFilter,
+	fn func(a *Activity) error,
+) error {
+	conditions, filterArgs := filter.whereClause("", 1)
+	query := `
+		SELECT id,
+			   user_id,
+			   guild_id,
+			   name,
+			   primary_type,
+			   media_type,
+			   duration,
+			   date,
+			   created_at,
+			   deleted_at,
+			   imported_at,
+			   meta
+		FROM activities
+		WHERE user_id = $1
+		AND deleted_at IS NULL` + conditions + `
+		ORDER BY date ASC, id ASC
+	`
+
+	args := append([]interface{}{userID}, filterArgs...)
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var activity Activity
+		if err := rows.Scan(
+			&activity.ID,
+			&activity.UserID,
+			&activity.GuildID,
+			&activity.Name,
+			&activity.PrimaryType,
+			&activity.MediaType,
+			&activity.Duration,
+			&activity.Date,
+			&activity.CreatedAt,
+			&activity.DeletedAt,
+			&activity.ImportedAt,
+			&activity.Meta,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(&activity); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (r *ActivityRepository) PageByUserID(
 	ctx context.Context,
 	userID, guildID string,
 	limit, offset int,
+	filter *ActivityFilter,
 ) (*UserActivityPage, error) {
+	conditions, filterArgs := filter.whereClause("activities", 4)
 	query := `
 		SELECT activities.id,
 			   user_id,
@@ -539,13 +671,14 @@ func (r *ActivityRepository) PageByUserID(
 		LEFT JOIN users u ON activities.user_id = u.id
 		LEFT JOIN guilds g ON g.id = $2
 		WHERE activities.user_id = $1
-		AND deleted_at IS NULL
+		AND deleted_at IS NULL` + conditions + `
 		ORDER BY date DESC
 		LIMIT $3
 		OFFSET $4
 	`
 
-	rows, err := r.pool.Query(ctx, query, userID, guildID, limit, offset)
+	args := append([]interface{}{userID, guildID, limit, offset}, filterArgs...)
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -580,29 +713,220 @@ func (r *ActivityRepository) PageByUserID(
 	return page, nil
 }
 
-func (r *ActivityRepository) DeleteByID(ctx context.Context, id uint64) error {
-	_, err := r.pool.Exec(ctx, `
+// Returns the most recently deleted activities of a user, newest deletion first
+func (r *ActivityRepository) GetRecentlyDeletedByUserID(
+	ctx context.Context,
+	userID, guildID string,
+	limit int,
+) ([]*Activity, error) {
+	query := `
+		SELECT activities.id,
+			   user_id,
+			   guild_id,
+			   name,
+			   primary_type,
+			   media_type,
+			   duration,
+			   date at time zone COALESCE(u.timezone, g.timezone, 'UTC'),
+			   created_at,
+			   deleted_at,
+			   imported_at,
+			   meta
+		FROM activities
+		LEFT JOIN users u ON activities.user_id = u.id
+		LEFT JOIN guilds g ON g.id = $2
+		WHERE activities.user_id = $1
+		AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, date DESC
+		LIMIT $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, guildID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActivities(rows)
+}
+
+func (r *ActivityRepository) GetRecentlyDeletedImportsByUserID(
+	ctx context.Context,
+	userID string,
+	limit int,
+) ([]ImportInfo, error) {
+	query := `
+		SELECT imported_at, COUNT(*) as count
+		FROM activities
+		WHERE user_id = $1
+		AND imported_at IS NOT NULL
+		AND deleted_at IS NOT NULL
+		GROUP BY imported_at
+		ORDER BY MAX(deleted_at) DESC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imports []ImportInfo
+
+	for rows.Next() {
+		importInfo := ImportInfo{}
+		if err = rows.Scan(&importInfo.Timestamp, &importInfo.Count); err != nil {
+			return nil, err
+		}
+
+		imports = append(imports, importInfo)
+	}
+
+	return imports, rows.Err()
+}
+
+// Returns all non-deleted activities of a user with a date in the given range
+func (r *ActivityRepository) GetByUserIDInRange(
+	ctx context.Context,
+	userID string,
+	start, end time.Time,
+) ([]*Activity, error) {
+	return getByUserIDInRange(ctx, r.pool, userID, start, end)
+}
+
+// GetByUserIDInRangeTx is GetByUserIDInRange as part of a larger
+// transaction, seeing the activities it changed.
+func (r *ActivityRepository) GetByUserIDInRangeTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID string,
+	start, end time.Time,
+) ([]*Activity, error) {
+	return getByUserIDInRange(ctx, tx, userID, start, end)
+}
+
+func getByUserIDInRange(
+	ctx context.Context,
+	q querier,
+	userID string,
+	start, end time.Time,
+) ([]*Activity, error) {
+	query := `
+		SELECT id,
+			   user_id,
+			   guild_id,
+			   name,
+			   primary_type,
+			   media_type,
+			   duration,
+			   date,
+			   created_at,
+			   deleted_at,
+			   imported_at,
+			   meta
+		FROM activities
+		WHERE user_id = $1
+		AND deleted_at IS NULL
+		AND date >= $2
+		AND date <= $3
+		ORDER BY date ASC
+	`
+
+	rows, err := q.Query(ctx, query, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActivities(rows)
+}
+
+// RestoreByIDTx restores a deleted activity of the user as part of a larger
+// transaction, returning it if it was found.
+func (r *ActivityRepository) RestoreByIDTx(ctx context.Context, tx pgx.Tx, id uint64, userID string) ([]*Activity, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE activities
+		SET deleted_at = NULL
+		WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NOT NULL
+		`+returningActivityColumns,
+		id,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActivities(rows)
+}
+
+// RestoreImportByUserIDAndTimestampTx restores the deleted activities of an
+// import as part of a larger transaction, returning them.
+func (r *ActivityRepository) RestoreImportByUserIDAndTimestampTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID string,
+	timestamp time.Time,
+) ([]*Activity, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE activities
+		SET deleted_at = NULL
+		WHERE user_id = $1
+		AND imported_at = $2 AT TIME ZONE 'UTC'
+		AND deleted_at IS NOT NULL
+		`+returningActivityColumns,
+		userID,
+		timestamp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActivities(rows)
+}
+
+// Permanently removes activities that were soft-deleted before the given time
+func (r *ActivityRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM activities
+		WHERE deleted_at IS NOT NULL
+		AND deleted_at < $1 AT TIME ZONE 'UTC'
+	`, before)
+	return tag.RowsAffected(), err
+}
+
+// DeleteByIDTx deletes an activity as part of a larger transaction,
+// returning it unless it was already deleted.
+func (r *ActivityRepository) DeleteByIDTx(ctx context.Context, tx pgx.Tx, id uint64) ([]*Activity, error) {
+	rows, err := tx.Query(ctx, `
 		UPDATE activities
 		SET deleted_at = NOW() AT TIME ZONE 'UTC'
 		WHERE id = $1
-	`, id)
-	return err
+		AND deleted_at IS NULL
+		`+returningActivityColumns,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActivities(rows)
 }
 
+// GetTopMembers ranks the members of a guild by the duration of activities
+// logged between the dates of start and end, in each member's local time.
 func (r *ActivityRepository) GetTopMembers(ctx context.Context, guildID string, limit int, start, end time.Time) ([]*MemberStats, error) {
 	members := make([]*MemberStats, 0)
 	rows, err := r.pool.Query(ctx, `
-		SELECT m.user_id, COALESCE(SUM(a.duration), 0) AS total_duration
+		SELECT m.user_id, COALESCE(SUM(t.duration), 0) AS total_duration
 		FROM guild_members m
-		LEFT JOIN activities a ON m.user_id = a.user_id
+		JOIN daily_user_totals t ON m.user_id = t.user_id
 		WHERE m.guild_id = $1
-		AND a.date >= $2
-		AND a.date <= $3
-		AND a.deleted_at IS NULL
+		AND t.local_date >= $2::date
+		AND t.local_date <= $3::date
 		GROUP BY m.user_id
 		ORDER BY total_duration DESC
 		LIMIT $4
-	`, guildID, start, end, limit)
+	`, guildID, localDate(start), localDate(end), limit)
 
 	if err != nil {
 		return nil, err
@@ -644,7 +968,9 @@ func (r *ActivityRepository) GetAvgSpeedByMediaTypeAndUserID(ctx context.Context
 	return avg, err
 }
 
-func (r *ActivityRepository) GetTotalWatchTimeOfVideoByUserID(ctx context.Context, userID, videoPlatform, videoID string) (total time.Duration, err error) {
+// GetTotalWatchTimeOfVideoByUserID sums the time a user logged watching a
+// video in activities dated since, or ever if since is zero.
+func (r *ActivityRepository) GetTotalWatchTimeOfVideoByUserID(ctx context.Context, userID, videoPlatform, videoID string, since time.Time) (total time.Duration, err error) {
 	query := `
 		SELECT COALESCE(SUM(duration), 0)
 		FROM activities
@@ -652,10 +978,74 @@ func (r *ActivityRepository) GetTotalWatchTimeOfVideoByUserID(ctx context.Contex
 		AND media_type = 'video'
 		AND meta->>'platform' = $2
 		AND meta->>'video_id' = $3
+		AND date >= $4
 		AND deleted_at IS NULL
 	`
 
-	row := r.pool.QueryRow(ctx, query, userID, videoPlatform, videoID)
+	row := r.pool.QueryRow(ctx, query, userID, videoPlatform, videoID, since)
 	err = row.Scan(&total)
 	return
 }
+
+// RebuildDailyTotals recomputes the daily totals of every user from their
+// activities, calling progress after each user. The totals are kept current
+// by triggers, so this is only needed for activities logged before they were
+// added.
+func (r *ActivityRepository) RebuildDailyTotals(ctx context.Context, progress func(done, total int)) error {
+	// Most users never configure anything, so have no row in users
+	rows, err := r.pool.Query(ctx, `SELECT DISTINCT user_id FROM activities`)
+	if err != nil {
+		return err
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for i, userID := range userIDs {
+		if _, err := r.pool.Exec(ctx, `SELECT rebuild_daily_user_totals($1)`, userID); err != nil {
+			return err
+		}
+
+		if progress != nil {
+			progress(i+1, len(userIDs))
+		}
+	}
+
+	return nil
+}
+
+// localDate formats the date of t in its own location, for comparing against
+// the local dates of daily totals.
+func localDate(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+func scanActivities(rows pgx.Rows) ([]*Activity, error) {
+	defer rows.Close()
+
+	activities := make([]*Activity, 0)
+	for rows.Next() {
+		var activity Activity
+		if err := rows.Scan(
+			&activity.ID,
+			&activity.UserID,
+			&activity.GuildID,
+			&activity.Name,
+			&activity.PrimaryType,
+			&activity.MediaType,
+			&activity.Duration,
+			&activity.Date,
+			&activity.CreatedAt,
+			&activity.DeletedAt,
+			&activity.ImportedAt,
+			&activity.Meta,
+		); err != nil {
+			return nil, err
+		}
+		activities = append(activities, &activity)
+	}
+
+	return activities, rows.Err()
+}
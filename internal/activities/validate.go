@@ -25,7 +25,24 @@ func isSnowflakeValid(str string) bool {
 	return err == nil
 }
 
+// ValidateExternalActivity validates an activity from outside of the bot,
+// such as an imported one, which must belong to a user.
 func ValidateExternalActivity(a *Activity) error {
+	if err := ValidateOfflineActivity(a); err != nil {
+		return err
+	}
+
+	if !isSnowflakeValid(a.UserID) {
+		return ErrInvalidUserID
+	}
+
+	return nil
+}
+
+// ValidateOfflineActivity validates an activity which doesn't belong to a
+// user yet, such as one converted offline to be imported later. Everything
+// but the user ID is checked.
+func ValidateOfflineActivity(a *Activity) error {
 	validMediaType := []string{
 		ActivityMediaTypeAnime,
 		ActivityMediaTypeBook,
@@ -50,9 +67,5 @@ func ValidateExternalActivity(a *Activity) error {
 		return ErrInvalidGuildID
 	}
 
-	if !isSnowflakeValid(a.UserID) {
-		return ErrInvalidUserID
-	}
-
 	return nil
 }
@@ -1,4 +1,4 @@
-package main
+package aib
 
 import (
 	"context"
@@ -9,7 +9,8 @@ import (
 	"github.com/xoltia/botsu/internal/videos"
 )
 
-type aibActivity struct {
+// Activity is an activity as stored by AnotherImmersionBot.
+type Activity struct {
 	ID              string
 	UserID          string
 	Name            string
@@ -23,7 +24,8 @@ type aibActivity struct {
 	Tags            []string
 }
 
-var mediaTypeTags = map[string]string{
+// MediaTypeTags maps AnotherImmersionBot tags to media types.
+var MediaTypeTags = map[string]string{
 	"anime": activities.ActivityMediaTypeAnime,
 	"vn":    activities.ActivityMediaTypeVisualNovel,
 	"manga": activities.ActivityMediaTypeManga,
@@ -31,18 +33,21 @@ var mediaTypeTags = map[string]string{
 	"video": activities.ActivityMediaTypeVideo,
 }
 
-func (activity *aibActivity) asNewFormatActivity() (a *activities.Activity) {
+// Convert returns the activity in Botsu's format. The activity was created
+// when it was logged, as AnotherImmersionBot does not keep both.
+func (activity *Activity) Convert() (a *activities.Activity) {
 	a = &activities.Activity{
 		UserID:      activity.UserID,
 		Name:        activity.Name,
 		PrimaryType: activity.Type,
 		Date:        time.UnixMilli(int64(activity.Date)),
-		Duration:    time.Duration(activity.Duration * float32(time.Minute)),
+		Duration:    time.Duration(float64(activity.Duration) * float64(time.Minute)),
 		Meta:        make(map[string]interface{}),
 	}
+	a.CreatedAt = a.Date
 
 	for _, tag := range activity.Tags {
-		if mediaType, ok := mediaTypeTags[tag]; ok {
+		if mediaType, ok := MediaTypeTags[tag]; ok {
 			a.MediaType = &mediaType
 			break
 		}
@@ -63,7 +68,9 @@ func (activity *aibActivity) asNewFormatActivity() (a *activities.Activity) {
 	return
 }
 
-func populateVideoMetadata(ctx context.Context, a *activities.Activity, vidURL string) (err error) {
+// PopulateVideoMetadata replaces the meta of a with the metadata of the video
+// at vidURL.
+func PopulateVideoMetadata(ctx context.Context, a *activities.Activity, vidURL string) (err error) {
 	var u *url.URL
 	u, err = url.Parse(vidURL)
 	if err != nil {
@@ -0,0 +1,222 @@
+// Package aib reads activities from AnotherImmersionBot's SQLite database and
+// converts them to Botsu's format.
+package aib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"sync"
+
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/xoltia/botsu/internal/activities"
+)
+
+var (
+	// ErrNotAIB is returned when a database doesn't have the tables of an
+	// AnotherImmersionBot database.
+	ErrNotAIB = errors.New("not an AnotherImmersionBot database")
+	// ErrTooManyActivities is returned when a database has more activities
+	// than are allowed to be read.
+	ErrTooManyActivities = errors.New("too many activities")
+)
+
+// Open opens the AnotherImmersionBot database at path. The database is
+// opened read-only and without trusting its schema, since it may have been
+// uploaded by anyone.
+func Open(path string) (*sql.DB, error) {
+	dsn := &url.URL{
+		Scheme:   "file",
+		Path:     path,
+		RawQuery: "mode=ro&_pragma=trusted_schema(OFF)",
+	}
+	return sql.Open("sqlite", dsn.String())
+}
+
+// checkTables checks that the tables activities are read from are tables,
+// rather than views or missing.
+func checkTables(ctx context.Context, db *sql.DB) error {
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM sqlite_master
+		WHERE type = 'table'
+		AND name IN ('activities', 'tags', 'tags_to_activities')
+	`).Scan(&n)
+	if err != nil {
+		return err
+	}
+
+	if n != 3 {
+		return ErrNotAIB
+	}
+
+	return nil
+}
+
+// ReadActivities reads the activities of a user, or of all users if userID
+// is empty, along with their tags. If limit is positive and there are more
+// activities than limit, ErrTooManyActivities is returned.
+func ReadActivities(ctx context.Context, db *sql.DB, userID string, limit int) ([]*Activity, error) {
+	if err := checkTables(ctx, db); err != nil {
+		return nil, err
+	}
+
+	// One more than the limit is read to tell when it is exceeded
+	queryLimit := -1
+	if limit > 0 {
+		queryLimit = limit + 1
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, name, type, url, date, duration, raw_duration, raw_duration_unit, speed
+		FROM activities
+		WHERE ? = '' OR user_id = ?
+		ORDER BY date ASC
+		LIMIT ?
+	`, userID, userID, queryLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var as []*Activity
+	byID := make(map[string]*Activity)
+
+	for rows.Next() {
+		var activity Activity
+
+		err = rows.Scan(
+			&activity.ID,
+			&activity.UserID,
+			&activity.Name,
+			&activity.Type,
+			&activity.URL,
+			&activity.Date,
+			&activity.Duration,
+			&activity.RawDuration,
+			&activity.RawDurationUnit,
+			&activity.Speed,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if limit > 0 && len(as) == limit {
+			return nil, ErrTooManyActivities
+		}
+
+		as = append(as, &activity)
+		byID[activity.ID] = &activity
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Relations are in tags_to_activities (tag_id, activity_id) and tags (id, name)
+	tagRows, err := db.QueryContext(ctx, `
+		SELECT tags_to_activities.activity_id, tags.name
+		FROM tags_to_activities
+		INNER JOIN tags ON tags_to_activities.tag_id = tags.id
+		INNER JOIN activities ON tags_to_activities.activity_id = activities.id
+		WHERE ? = '' OR activities.user_id = ?
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var activityID, tag string
+
+		if err = tagRows.Scan(&activityID, &tag); err != nil {
+			return nil, err
+		}
+
+		if activity, ok := byID[activityID]; ok {
+			activity.Tags = append(activity.Tags, tag)
+		}
+	}
+
+	return as, tagRows.Err()
+}
+
+type Options struct {
+	// Replace the meta of videos with their metadata, which requires a
+	// request per video
+	VideoMetadata bool
+	// Maximum number of concurrent video metadata requests
+	MaxConcurrency int
+	// Called after each activity is converted
+	Progress func(done, total int)
+	// Called when the metadata of a video can not be fetched, in which case
+	// the activity keeps its converted meta
+	VideoError func(a *Activity, err error)
+}
+
+// ConvertAll converts activities, fetching video metadata if enabled. The
+// converted activities are in the same order as as.
+func ConvertAll(ctx context.Context, as []*Activity, opts Options) ([]*activities.Activity, error) {
+	converted := make([]*activities.Activity, len(as))
+
+	var (
+		mu   sync.Mutex
+		done int
+		wg   sync.WaitGroup
+	)
+
+	progress := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, len(as))
+		}
+	}
+
+	sem := make(chan struct{}, max(opts.MaxConcurrency, 1))
+
+	for i, activity := range as {
+		a := activity.Convert()
+		converted[i] = a
+
+		isVideo := a.MediaType != nil && *a.MediaType == activities.ActivityMediaTypeVideo
+		if !opts.VideoMetadata || !isVideo || activity.URL == nil {
+			progress()
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(activity *Activity) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := PopulateVideoMetadata(ctx, a, *activity.URL)
+			if err != nil && opts.VideoError != nil {
+				mu.Lock()
+				opts.VideoError(activity, err)
+				mu.Unlock()
+			}
+
+			progress()
+		}(activity)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,121 @@
+package aib_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/aib"
+)
+
+const testSchema = `
+CREATE TABLE activities (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	url TEXT,
+	date INTEGER NOT NULL,
+	duration REAL NOT NULL,
+	raw_duration INTEGER,
+	raw_duration_unit TEXT,
+	speed REAL
+);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE tags_to_activities (tag_id INTEGER NOT NULL, activity_id TEXT NOT NULL);
+
+INSERT INTO activities VALUES
+	('a', '1', 'Frieren', 'listening', NULL, 1714564800000, 72, 3, 'episode', NULL),
+	('b', '1', 'Stream', 'listening', 'https://example.com/v', 1714568400000, 30.5, NULL, NULL, 1.5),
+	('c', '2', 'Other user', 'reading', NULL, 1714564800000, 10, NULL, NULL, NULL);
+INSERT INTO tags VALUES (1, 'anime'), (2, 'video'), (3, 'other');
+INSERT INTO tags_to_activities VALUES (3, 'a'), (1, 'a'), (2, 'b'), (1, 'c');
+`
+
+// openTestDB creates a database with schema and opens it as Open would.
+func openTestDB(t *testing.T, schema string) *sql.DB {
+	path := filepath.Join(t.TempDir(), "bot_data.db")
+
+	setup, err := sql.Open("sqlite", path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer setup.Close()
+
+	_, err = setup.Exec(schema)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db, err := aib.Open(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestReadAndConvert(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, testSchema)
+
+	_, err := db.ExecContext(ctx, `DELETE FROM activities`)
+	assert.Error(t, err, "database should be read-only")
+
+	as, err := aib.ReadActivities(ctx, db, "1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, as, 2)
+	assert.ElementsMatch(t, []string{"other", "anime"}, as[0].Tags)
+
+	all, err := aib.ReadActivities(ctx, db, "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	_, err = aib.ReadActivities(ctx, db, "", 2)
+	assert.ErrorIs(t, err, aib.ErrTooManyActivities)
+
+	limited, err := aib.ReadActivities(ctx, db, "", 3)
+	assert.NoError(t, err)
+	assert.Len(t, limited, 3)
+
+	var progress []int
+	converted, err := aib.ConvertAll(ctx, as, aib.Options{
+		Progress: func(done, total int) {
+			assert.Equal(t, 2, total)
+			progress = append(progress, done)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, progress)
+
+	a := converted[0]
+	assert.Equal(t, "1", a.UserID)
+	assert.Equal(t, activities.ActivityMediaTypeAnime, *a.MediaType)
+	assert.Equal(t, 72*time.Minute, a.Duration)
+	assert.True(t, a.Date.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, a.Date, a.CreatedAt)
+	assert.Equal(t, uint64(3), a.Meta.(map[string]interface{})["episodes"])
+
+	a = converted[1]
+	assert.Equal(t, activities.ActivityMediaTypeVideo, *a.MediaType)
+	assert.Equal(t, 30*time.Minute+30*time.Second, a.Duration)
+	assert.Equal(t, "https://example.com/v", a.Meta.(map[string]interface{})["url"])
+}
+
+func TestReadActivitiesFromView(t *testing.T) {
+	db := openTestDB(t, `
+		CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE tags_to_activities (tag_id INTEGER NOT NULL, activity_id TEXT NOT NULL);
+		CREATE VIEW activities AS
+			SELECT 'a' AS id, '1' AS user_id, 'name' AS name, 'reading' AS type, NULL AS url,
+				0 AS date, 1 AS duration, NULL AS raw_duration, NULL AS raw_duration_unit, NULL AS speed;
+	`)
+
+	_, err := aib.ReadActivities(context.Background(), db, "1", 0)
+	assert.ErrorIs(t, err, aib.ErrNotAIB)
+}
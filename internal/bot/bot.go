@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/xoltia/botsu/pkg/discordutil"
@@ -23,6 +24,8 @@ type Bot struct {
 	session                  *discordgo.Session
 	createdCommands          []*discordgo.ApplicationCommand
 	commands                 CommandCollection
+	components               ComponentCollection
+	tasks                    []task
 	guildRepo                memberTracker
 	noPanic                  bool
 	destroyOnClose           bool
@@ -44,6 +47,7 @@ func NewBot(ctx context.Context, opts Options) *Bot {
 	bot := &Bot{
 		logger:         opts.Logger,
 		commands:       make(CommandCollection),
+		components:     make(ComponentCollection),
 		guildRepo:      opts.MemberTracker,
 		noPanic:        opts.NoPanic,
 		destroyOnClose: opts.DestroyOnClose,
@@ -64,7 +68,18 @@ func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.Interaction
 		slog.String("guild", i.Interaction.GuildID),
 		slog.String("type", i.Type.String()),
 	)
-	if i.Type != discordgo.InteractionApplicationCommand && i.Type != discordgo.InteractionApplicationCommandAutocomplete {
+	var handlerAttr slog.Attr
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		handlerAttr = slog.String("command", i.ApplicationCommandData().Name)
+	case discordgo.InteractionMessageComponent:
+		// Components without a registered handler are left to collectors
+		if _, ok := b.components.Get(i.MessageComponentData().CustomID); !ok {
+			return
+		}
+		handlerAttr = slog.String("custom_id", i.MessageComponentData().CustomID)
+	default:
 		return
 	}
 
@@ -74,7 +89,7 @@ func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.Interaction
 		With(slog.String("user", discordutil.GetInteractionUser(i).String())).
 		With(slog.String("guild", i.Interaction.GuildID)).
 		With(slog.String("type", i.Type.String())).
-		With(slog.String("command", i.ApplicationCommandData().Name)).
+		With(handlerAttr).
 		WithGroup("handler")
 
 	ctx := NewInteractionContext(subLogger, b, s, i, b.botContext)
@@ -96,11 +111,17 @@ func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.Interaction
 	b.wg.Add(1)
 	defer b.wg.Done()
 
-	err := b.commands.Handle(ctx)
+	var err error
+	if ctx.IsComponent() {
+		err = b.components.Handle(ctx)
+	} else {
+		err = b.commands.Handle(ctx)
+	}
+
 	if err != nil {
 		ctx.Logger.Error("Failed to handle interaction", slog.String("err", err.Error()))
 
-		if ctx.IsCommand() {
+		if ctx.IsCommand() || ctx.IsComponent() {
 			_, err = ctx.RespondOrFollowup(unexpectedErrorMessage, false)
 			if err != nil {
 				ctx.Logger.Error("Failed to send error message", slog.String("err", err.Error()))
@@ -150,6 +171,24 @@ func (b *Bot) AddCommand(data *discordgo.ApplicationCommand, cmd CommandHandler)
 	b.commands.Add(data, cmd)
 }
 
+// AddComponentHandler registers a handler for all message components whose
+// custom ID has the given prefix. See ComponentCollection.
+func (b *Bot) AddComponentHandler(prefix string, handler ComponentHandler) {
+	b.logger.Debug("Adding component handler", slog.String("prefix", prefix))
+	b.components.Add(prefix, handler)
+}
+
+// AddTask registers a function to be run every interval once the bot has
+// logged in. Must be called before Login.
+func (b *Bot) AddTask(name string, interval time.Duration, run TaskFunc) {
+	b.logger.Debug("Adding task", slog.String("task", name), slog.Duration("interval", interval))
+	b.tasks = append(b.tasks, task{name: name, interval: interval, run: run})
+}
+
+func (b *Bot) Session() *discordgo.Session {
+	return b.session
+}
+
 func (b *Bot) Login(token string, intent discordgo.Intent) error {
 	s, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -181,6 +220,11 @@ func (b *Bot) Login(token string, intent discordgo.Intent) error {
 		return err
 	}
 
+	for _, t := range b.tasks {
+		b.wg.Add(1)
+		go b.runTask(t)
+	}
+
 	return nil
 }
 
@@ -199,7 +243,7 @@ func (b *Bot) Close() {
 	b.globalComponentCollector.Close()
 	// Stop accepting command interactions
 	b.removeInteractionHandler()
-	// Cancel bot context (parent context of all interaction contexts)
+	// Cancel bot context (parent context of all interaction contexts and tasks)
 	b.cancelBotContext()
 	// Wait for already running commands to finish
 	b.wg.Wait()
@@ -2,16 +2,9 @@ package commands
 
 import (
 	"bytes"
-	_ "embed"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"image/color"
-	"io"
 	"math"
-	"net/http"
-	"net/url"
-	"text/template"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -20,9 +13,12 @@ import (
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
 	"github.com/xoltia/botsu/internal/guilds"
+	"github.com/xoltia/botsu/internal/scoring"
 	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/pkg/chart"
 	"github.com/xoltia/botsu/pkg/discordutil"
 	"github.com/xoltia/botsu/pkg/orderedmap"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
 var ChartCommandData = &discordgo.ApplicationCommand{
@@ -35,16 +31,39 @@ var ChartCommandData = &discordgo.ApplicationCommand{
 			Description: "View a chart of your daily activity duration",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "start",
-					Description: "The start date of the chart",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "start",
+					Description:  "The start date of the chart, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "end",
-					Description: "The end date of the chart",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "end",
+					Description:  "The end date of the chart, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "points",
+			Description: "View a chart of the points you earned in this server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "start",
+					Description:  "The start date of the chart, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "end",
+					Description:  "The end date of the chart, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -70,16 +89,18 @@ var ChartCommandData = &discordgo.ApplicationCommand{
 					},
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "start",
-					Description: "The start date of the chart",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "start",
+					Description:  "The start date of the chart, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "end",
-					Description: "The end date of the chart",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "end",
+					Description:  "The end date of the chart, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -90,96 +111,27 @@ type ChartCommand struct {
 	ar *activities.ActivityRepository
 	ur *users.UserRepository
 	gr *guilds.GuildRepository
+	ts *users.UserTimeService
+	sc *scoring.ScoringService
 }
 
-func NewChartCommand(ar *activities.ActivityRepository, ur *users.UserRepository, gr *guilds.GuildRepository) *ChartCommand {
-	return &ChartCommand{ar: ar, ur: ur, gr: gr}
+func NewChartCommand(
+	ar *activities.ActivityRepository,
+	ur *users.UserRepository,
+	gr *guilds.GuildRepository,
+	ts *users.UserTimeService,
+	sc *scoring.ScoringService,
+) *ChartCommand {
+	return &ChartCommand{ar: ar, ur: ur, gr: gr, ts: ts, sc: sc}
 }
 
-var quickChartURL = url.URL{
-	Scheme: "https",
-	Host:   "quickchart.io",
-	Path:   "/chart",
-}
-
-//go:embed chart_body.json.tmpl
-var barBodyTemplateFile string
-
-//go:embed chart_body_channel_pie.json.tmpl
-var channelPieBodyTemplateFile string
-
-var barBodyTemplate = template.Must(template.New("body").Parse(barBodyTemplateFile))
-var channelPieBodyTemplate = template.Must(template.New("body").Parse(channelPieBodyTemplateFile))
-
-type barRequestBody struct {
-	Values         string
-	Labels         string
-	Color          string
-	SecondaryColor string
-	Horizontal     int
-	ShowHorizontal bool
-}
-
-type pieRequestBody struct {
-	Values string
-	Labels string
-}
-
-func colorAsHex(c color.Color) string {
-	r, g, b, _ := c.RGBA()
-	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
-}
-
-func getQuickChartBarBody(xValues []string, yValues []float64, yBar int) (*bytes.Buffer, error) {
-	buffer := bytes.Buffer{}
-
-	values, _ := json.Marshal(yValues)
-	labels, _ := json.Marshal(xValues)
-
-	err := barBodyTemplate.Execute(&buffer, barRequestBody{
-		Values:         string(values),
-		Labels:         string(labels),
-		Color:          fmt.Sprintf("\"%s\"", colorAsHex(discordutil.ColorSecondary)),
-		SecondaryColor: fmt.Sprintf("\"%s\"", colorAsHex(discordutil.ColorPrimary)),
-		Horizontal:     yBar,
-		ShowHorizontal: yBar != 0,
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	compactBuffer := bytes.Buffer{}
-
-	if err = json.Compact(&compactBuffer, buffer.Bytes()); err != nil {
-		return nil, err
-	}
-
-	return &compactBuffer, nil
-}
-
-func getQuickChartChannelPieBody(labels []string, values []float64) (*bytes.Buffer, error) {
-	buffer := bytes.Buffer{}
-
-	valuesJSON, _ := json.Marshal(values)
-	labelsJSON, _ := json.Marshal(labels)
-
-	err := channelPieBodyTemplate.Execute(&buffer, pieRequestBody{
-		Values: string(valuesJSON),
-		Labels: string(labelsJSON),
-	})
-
-	if err != nil {
+// renderChart draws a chart as a PNG in the bot's colors.
+func renderChart(ch chart.Chart, width, height int) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	if err := chart.WritePNG(&buf, ch, chart.DiscordTheme, width, height); err != nil {
 		return nil, err
 	}
-
-	compactBuffer := bytes.Buffer{}
-
-	if err = json.Compact(&compactBuffer, buffer.Bytes()); err != nil {
-		return nil, err
-	}
-
-	return &compactBuffer, nil
+	return &buf, nil
 }
 
 func (c *ChartCommand) handleYoutubeChannel(ctx *bot.InteractionContext, user *users.User, start, end carbon.Carbon, chartType string) error {
@@ -228,38 +180,21 @@ func (c *ChartCommand) handleYoutubeChannel(ctx *bot.InteractionContext, user *u
 		values[i] = math.Round(v)
 	}
 
-	var reqBody *bytes.Buffer
+	var img *bytes.Buffer
 
 	if chartType != "pie" {
-		reqBody, err = getQuickChartBarBody(keys, values, 0)
+		img, err = renderChart(&chart.BarChart{
+			Labels: keys,
+			Series: []chart.Series{{Values: values}},
+		}, 500, 300)
 	} else {
-		reqBody, err = getQuickChartChannelPieBody(keys, values)
-	}
-
-	if err != nil {
-		return err
+		img, err = renderChart(&chart.PieChart{Labels: keys, Values: values}, 600, 500)
 	}
 
-	req := http.Request{
-		Method: http.MethodPost,
-		URL:    &quickChartURL,
-		Header: http.Header{
-			"Content-Type": []string{"application/json"},
-		},
-		Body: io.NopCloser(reqBody),
-	}
-
-	resp, err := http.DefaultClient.Do(&req)
 	if err != nil {
 		return err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to generate chart")
-	}
-
 	description := fmt.Sprintf(
 		"Here are your top channels from <t:%d> to <t:%d>. You logged a total of **%.0f minutes**. Here is a breakdown of your time:",
 		start.Timestamp(),
@@ -286,14 +221,21 @@ func (c *ChartCommand) handleYoutubeChannel(ctx *bot.InteractionContext, user *u
 		Files: []*discordgo.File{
 			{
 				Name:        "chart.png",
-				ContentType: resp.Header.Get("Content-Type"),
-				Reader:      resp.Body,
+				ContentType: "image/png",
+				Reader:      img,
 			},
 		},
 	})
 }
 
 func (c *ChartCommand) Handle(ctx *bot.InteractionContext) error {
+	if ctx.IsAutocomplete() {
+		if focused := focusedSubcommandOption(ctx); isDateOption(focused) {
+			return respondDateAutocomplete(ctx, c.ts, focused)
+		}
+		return nil
+	}
+
 	userID := discordutil.GetInteractionUser(ctx.Interaction()).ID
 	guildID := ctx.Interaction().GuildID
 	user, err := c.ur.FindByID(ctx.ResponseContext(), userID)
@@ -320,8 +262,9 @@ func (c *ChartCommand) Handle(ctx *bot.InteractionContext) error {
 		}
 	}
 
-	start := carbon.Now(timezone).SubDays(6).StartOfDay()
-	end := carbon.Now(timezone).EndOfDay()
+	now := carbon.Now(timezone)
+	start := now.SubDays(6).StartOfDay()
+	end := now.EndOfDay()
 
 	if len(ctx.Options()) == 0 {
 		return bot.ErrInvalidOptions
@@ -333,22 +276,23 @@ func (c *ChartCommand) Handle(ctx *bot.InteractionContext) error {
 	customTimeframe := startInput != nil || endInput != nil
 
 	if startInput != nil {
-		start = carbon.Parse(*startInput, timezone)
-		if !start.IsValid() {
+		t, err := timeparse.ParseDate(*startInput, now.ToStdTime())
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
 				Content: "Invalid start date.",
 			})
 		}
+		start = carbon.CreateFromStdTime(t)
 	}
 
 	if endInput != nil {
-		end = carbon.Parse(*endInput, timezone)
-
-		if !end.IsValid() {
+		t, err := timeparse.ParseDateEnd(*endInput, now.ToStdTime())
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
 				Content: "Invalid end date.",
 			})
 		}
+		end = carbon.CreateFromStdTime(t)
 	}
 
 	if end.Lt(start) {
@@ -371,85 +315,117 @@ func (c *ChartCommand) Handle(ctx *bot.InteractionContext) error {
 
 	useMonthGrouping := deltaMonths > 3
 
-	var dailyDurations orderedmap.Map[time.Duration]
+	var (
+		keys   []string
+		values []float64
+		unit   = "minutes"
+		goal   = user.DailyGoal
+	)
 
-	if useMonthGrouping {
-		dailyDurations, err = c.ar.GetTotalByUserIDGroupedByMonth(
+	if subcommand.Name == "points" {
+		if guildID == "" {
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Content: "Points can only be viewed in a server.",
+			})
+		}
+
+		dailyPoints, err := c.sc.GetTotalByUserIDGrouped(
 			ctx.ResponseContext(),
 			user.ID,
-			ctx.Interaction().GuildID,
+			guildID,
 			start.ToStdTime(),
 			end.ToStdTime(),
+			useMonthGrouping,
 		)
+		if err != nil {
+			return err
+		}
+
+		keys = dailyPoints.Keys()
+		values = make([]float64, 0, dailyPoints.Len())
+		for _, k := range keys {
+			v, _ := dailyPoints.Get(k)
+			values = append(values, v)
+		}
+
+		unit = "points"
+		goal = 0
 	} else {
-		dailyDurations, err = c.ar.GetTotalByUserIDGroupedByDay(
-			ctx.ResponseContext(),
-			user.ID,
-			ctx.Interaction().GuildID,
-			start.ToStdTime(),
-			end.ToStdTime(),
-		)
-	}
+		var dailyDurations orderedmap.Map[time.Duration]
+
+		if useMonthGrouping {
+			dailyDurations, err = c.ar.GetTotalByUserIDGroupedByMonth(
+				ctx.ResponseContext(),
+				user.ID,
+				start.ToStdTime(),
+				end.ToStdTime(),
+			)
+		} else {
+			dailyDurations, err = c.ar.GetTotalByUserIDGroupedByDay(
+				ctx.ResponseContext(),
+				user.ID,
+				start.ToStdTime(),
+				end.ToStdTime(),
+			)
+		}
 
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+
+		keys = dailyDurations.Keys()
+		values = make([]float64, 0, dailyDurations.Len())
+		for _, k := range keys {
+			v, _ := dailyDurations.Get(k)
+			values = append(values, v.Minutes())
+		}
 	}
 
-	totalMinutes := 0.0
-	highestMinutes := 0.0
+	total := 0.0
+	highest := 0.0
 	highestDay := "N/A"
 
-	values := make([]float64, 0, dailyDurations.Len())
-
-	for _, k := range dailyDurations.Keys() {
-		v, _ := dailyDurations.Get(k)
-		totalMinutes += v.Minutes()
-		if v.Minutes() > highestMinutes {
-			highestMinutes = v.Minutes()
-			highestDay = k
+	for i, v := range values {
+		total += v
+		if v > highest {
+			highest = v
+			highestDay = keys[i]
 		}
-		values = append(values, v.Minutes())
 	}
 
-	avgMinutes := totalMinutes / float64(dailyDurations.Len())
-	goal := user.DailyGoal
+	average := total / float64(len(values))
 
 	if useMonthGrouping {
 		goal = 0
 	}
 
-	reqBody, err := getQuickChartBarBody(dailyDurations.Keys(), values, goal)
+	img, err := renderChart(&chart.BarChart{
+		Labels:    keys,
+		Series:    []chart.Series{{Values: values}},
+		Reference: float64(goal),
+	}, 500, 300)
 	if err != nil {
 		return err
 	}
 
-	req := http.Request{
-		Method: http.MethodPost,
-		URL:    &quickChartURL,
-		Header: http.Header{
-			"Content-Type": []string{"application/json"},
-		},
-		Body: io.NopCloser(reqBody),
-	}
-
-	resp, err := http.DefaultClient.Do(&req)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to generate chart")
-	}
-
 	embed := discordutil.NewEmbedBuilder().
 		SetTitle("Activity History").
 		SetColor(discordutil.ColorPrimary).
 		SetImage("attachment://chart.png").
-		AddField("Total", fmt.Sprintf("%.0f minutes", math.Round(totalMinutes)), true).
-		AddField("Average", fmt.Sprintf("%.0f minutes", math.Round(avgMinutes)), true).
-		AddField("Highest", fmt.Sprintf("%.0f minutes (%s)", math.Round(highestMinutes), highestDay), true)
+		AddField("Total", fmt.Sprintf("%.0f %s", math.Round(total), unit), true).
+		AddField("Average", fmt.Sprintf("%.0f %s", math.Round(average), unit), true).
+		AddField("Highest", fmt.Sprintf("%.0f %s (%s)", math.Round(highest), unit, highestDay), true)
+
+	if goal != 0 {
+		met := 0
+		for _, v := range values {
+			if v >= float64(goal) {
+				met++
+			}
+		}
+
+		embed.AddField("Daily Goal", fmt.Sprintf("Met on %d of %d days (%d minutes)", met, len(values), goal), true)
+	}
 
 	if customTimeframe {
 		embed.SetDescription(fmt.Sprintf("Here is your activity from <t:%d> to <t:%d>", start.Timestamp(), end.Timestamp()))
@@ -460,8 +436,8 @@ func (c *ChartCommand) Handle(ctx *bot.InteractionContext) error {
 		Files: []*discordgo.File{
 			{
 				Name:        "chart.png",
-				ContentType: resp.Header.Get("Content-Type"),
-				Reader:      resp.Body,
+				ContentType: "image/png",
+				Reader:      img,
 			},
 		},
 	})
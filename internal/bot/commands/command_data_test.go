@@ -0,0 +1,106 @@
+package commands_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/bot/commands"
+)
+
+// Every command registered by the bot. Discord rejects all of them if any
+// one is invalid, so the bot fails to start.
+var commandData = []*discordgo.ApplicationCommand{
+	commands.ChartCommandData,
+	commands.ConfigCommandData,
+	commands.ExportCommandData,
+	commands.GoalCommandData,
+	commands.GuildConfigCommandData,
+	commands.GuildGoalCommandData,
+	commands.HistoryCommandData,
+	commands.ImportCommandData,
+	commands.LeaderboardCommandData,
+	commands.LogCommandData,
+	commands.PrivacyCommandData,
+	commands.ScoringCommandData,
+	commands.TimerCommandData,
+	commands.TrashCommandData,
+	commands.UndoCommandData,
+	commands.WorksCommandData,
+}
+
+// Limits of chat input commands, see
+// https://discord.com/developers/docs/interactions/application-commands
+var commandNamePattern = regexp.MustCompile(`^[-_\p{Ll}\p{Lo}\p{N}]{1,32}$`)
+
+const (
+	maxCommandDescription = 100
+	maxCommandOptions     = 25
+	maxCommandChoices     = 25
+	maxCommandChoice      = 100
+	maxCommandCharacters  = 4000
+)
+
+func checkCommandText(t *testing.T, path, name, description string) int {
+	assert.Regexp(t, commandNamePattern, name, "%s: invalid name", path)
+
+	length := utf8.RuneCountInString(description)
+	assert.True(t, length >= 1 && length <= maxCommandDescription, "%s: description is %d characters", path, length)
+
+	return utf8.RuneCountInString(name) + length
+}
+
+func checkCommandOptions(t *testing.T, path string, options []*discordgo.ApplicationCommandOption) (characters int) {
+	assert.LessOrEqual(t, len(options), maxCommandOptions, "%s: too many options", path)
+
+	names := make(map[string]bool)
+	required := true
+
+	for _, o := range options {
+		optionPath := path + " " + o.Name
+		characters += checkCommandText(t, optionPath, o.Name, o.Description)
+
+		assert.False(t, names[o.Name], "%s: duplicate option", optionPath)
+		names[o.Name] = true
+
+		// Required options must come before optional ones
+		assert.False(t, o.Required && !required, "%s: required option after optional ones", optionPath)
+		required = required && o.Required
+
+		assert.LessOrEqual(t, len(o.Choices), maxCommandChoices, "%s: too many choices", optionPath)
+		for _, c := range o.Choices {
+			length := utf8.RuneCountInString(c.Name)
+			assert.True(t, length >= 1 && length <= maxCommandChoice, "%s: choice %q is %d characters", optionPath, c.Name, length)
+			characters += length
+
+			if s, ok := c.Value.(string); ok {
+				assert.LessOrEqual(t, utf8.RuneCountInString(s), maxCommandChoice, "%s: choice value %q is too long", optionPath, s)
+				characters += utf8.RuneCountInString(s)
+			} else {
+				characters += len(fmt.Sprint(c.Value))
+			}
+		}
+
+		characters += checkCommandOptions(t, optionPath, o.Options)
+	}
+
+	return
+}
+
+func TestCommandData(t *testing.T) {
+	names := make(map[string]bool)
+
+	for _, c := range commandData {
+		path := "/" + c.Name
+
+		assert.False(t, names[c.Name], "%s: duplicate command", path)
+		names[c.Name] = true
+
+		characters := checkCommandText(t, path, c.Name, c.Description)
+		characters += checkCommandOptions(t, path, c.Options)
+		assert.LessOrEqual(t, characters, maxCommandCharacters, "%s: too many characters", path)
+	}
+}
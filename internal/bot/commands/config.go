@@ -11,7 +11,7 @@ import (
 	"github.com/xoltia/botsu/internal/bot"
 	"github.com/xoltia/botsu/internal/users"
 	"github.com/xoltia/botsu/pkg/discordutil"
-	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
 var ConfigCommandData = &discordgo.ApplicationCommand{
@@ -48,12 +48,28 @@ var ConfigCommandData = &discordgo.ApplicationCommand{
 		},
 		{
 			Name:         "daily-goal",
-			Description:  "Set your daily immersion goal (minutes)",
-			Type:         discordgo.ApplicationCommandOptionInteger,
-			MinValue:     ref.New(0.0),
-			MaxValue:     1440,
+			Description:  "Set your daily immersion goal, e.g. 45m or 1.5h (minutes if no unit)",
+			Type:         discordgo.ApplicationCommandOptionString,
 			Required:     false,
-			Autocomplete: false,
+			Autocomplete: true,
+		},
+		{
+			Name:        "daily-goal-reminder",
+			Description: "Set how long before midnight to remind you by DM of an unmet daily goal, e.g. 2h (or off)",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    false,
+		},
+		{
+			Name:        "goal-reminders",
+			Description: "Set how long before goals are due to remind you by DM, e.g. 1d, 6h (or off)",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    false,
+		},
+		{
+			Name:        "quiet-hours",
+			Description: "Set the hours in your timezone to not send reminders, e.g. 22-8 (or off)",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    false,
 		},
 	},
 }
@@ -147,17 +163,114 @@ func (c *ConfigCommand) Handle(ctx *bot.InteractionContext) error {
 
 		embedBuilder.SetDescription("Your manga reading speed has been updated.")
 	case "daily-goal":
-		dailyGoal, err := discordutil.GetRequiredUintOption(options, "daily-goal")
+		dailyGoalString, err := discordutil.GetRequiredStringOption(options, "daily-goal")
 		if err != nil {
 			return err
 		}
 
-		err = c.userRepository.SetDailyGoal(ctx.Context(), discordutil.GetInteractionUser(i).ID, int(dailyGoal))
+		dailyGoal, err := timeparse.ParseDuration(dailyGoalString)
+		if err != nil || dailyGoal > 24*time.Hour {
+			embedBuilder.SetDescription("Invalid daily goal. Try something like `45m` or `1.5h`, up to 24 hours.")
+
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			})
+		}
+
+		err = c.userRepository.SetDailyGoal(ctx.Context(), discordutil.GetInteractionUser(i).ID, int(dailyGoal.Minutes()))
 		if err != nil {
 			return err
 		}
 
 		embedBuilder.SetDescription("Your daily goal has been updated.")
+	case "daily-goal-reminder":
+		input, err := discordutil.GetRequiredStringOption(options, "daily-goal-reminder")
+		if err != nil {
+			return err
+		}
+
+		reminder, err := parseDailyGoalReminder(input)
+		if err != nil {
+			embedBuilder.SetDescription("Invalid daily goal reminder. Try something like `2h` before midnight, up to 24 hours, or `off`.")
+
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			})
+		}
+
+		err = c.userRepository.SetDailyGoalReminder(ctx.Context(), discordutil.GetInteractionUser(i).ID, reminder)
+		if err != nil {
+			return err
+		}
+
+		if reminder == nil {
+			embedBuilder.SetDescription("Daily goal reminders have been turned off.")
+		} else {
+			embedBuilder.SetDescription(fmt.Sprintf(
+				"You will be reminded %s before midnight if you haven't met your daily goal.",
+				reminder.String(),
+			))
+		}
+	case "goal-reminders":
+		input, err := discordutil.GetRequiredStringOption(options, "goal-reminders")
+		if err != nil {
+			return err
+		}
+
+		reminders, err := parseGoalReminders(input)
+		if err != nil {
+			embedBuilder.SetDescription(fmt.Sprintf(
+				"Invalid goal reminders. Try something like `1d, 6h`, up to %d reminders of at most 7 days, or `off`.",
+				maxGoalReminders,
+			))
+
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			})
+		}
+
+		err = c.userRepository.SetGoalReminders(ctx.Context(), discordutil.GetInteractionUser(i).ID, reminders)
+		if err != nil {
+			return err
+		}
+
+		if len(reminders) == 0 {
+			embedBuilder.SetDescription("Goal reminders have been turned off.")
+		} else {
+			embedBuilder.SetDescription(fmt.Sprintf(
+				"You will be reminded of unfinished goals %s before they are due.",
+				formatGoalReminders(reminders),
+			))
+		}
+	case "quiet-hours":
+		input, err := discordutil.GetRequiredStringOption(options, "quiet-hours")
+		if err != nil {
+			return err
+		}
+
+		start, end, err := parseQuietHours(input)
+		if err != nil {
+			embedBuilder.SetDescription("Invalid quiet hours. Try something like `22-8` for 10 PM to 8 AM, or `off`.")
+
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			})
+		}
+
+		err = c.userRepository.SetQuietHours(ctx.Context(), discordutil.GetInteractionUser(i).ID, start, end)
+		if err != nil {
+			return err
+		}
+
+		if start == nil {
+			embedBuilder.SetDescription("Your quiet hours have been removed.")
+		} else {
+			embedBuilder.SetDescription(fmt.Sprintf("No reminders will be sent from %02d:00 to %02d:00.", *start, *end))
+		}
 	default:
 		return fmt.Errorf("unexpected option: %s", options[0].Name)
 	}
@@ -177,6 +290,10 @@ func (c *ConfigCommand) handleAutocomplete(ctx *bot.InteractionContext) error {
 		return nil
 	}
 
+	if isDurationOption(focusedOption) {
+		return respondDurationAutocomplete(ctx, focusedOption)
+	}
+
 	if focusedOption.Name == "timezone" {
 		const maxResults = 25
 		timezone := focusedOption.StringValue()
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+// Most days counted towards a daily goal streak.
+const maxDailyGoalStreak = 365
+
+// parseDailyGoalReminder parses how long before midnight to remind users of
+// their daily goal. "off" disables the reminder, returning nil.
+func parseDailyGoalReminder(input string) (*time.Duration, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "off") {
+		return nil, nil
+	}
+
+	d, err := timeparse.ParseDuration(input)
+	if err != nil {
+		return nil, err
+	} else if d <= 0 || d > 24*time.Hour {
+		return nil, fmt.Errorf("invalid daily goal reminder: %s", input)
+	}
+
+	return &d, nil
+}
+
+type dailyGoalProgress struct {
+	Goal   time.Duration
+	Today  time.Duration
+	Streak int
+}
+
+func (p *dailyGoalProgress) Met() bool {
+	return p.Today >= p.Goal
+}
+
+func (p *dailyGoalProgress) String() string {
+	s := fmt.Sprintf("%.0f/%.0f min today", p.Today.Minutes(), p.Goal.Minutes())
+	if p.Met() {
+		s += " ✅"
+	}
+
+	if p.Streak == 1 {
+		s += " • 🔥 1 day streak"
+	} else if p.Streak > 1 {
+		s += fmt.Sprintf(" • 🔥 %d day streak", p.Streak)
+	}
+
+	return s
+}
+
+// getDailyGoalProgress returns the progress of a user towards their daily
+// goal today, or nil if they have no daily goal. Days are in the user's
+// timezone, falling back to that of guildID as the daily totals do.
+func getDailyGoalProgress(
+	ctx context.Context,
+	ur *users.UserRepository,
+	ar *activities.ActivityRepository,
+	ts *users.UserTimeService,
+	userID, guildID string,
+) (*dailyGoalProgress, error) {
+	user, err := ur.FindByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if user.DailyGoal <= 0 {
+		return nil, nil
+	}
+
+	now, err := ts.GetTime(ctx, userID, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	dailyDurations, err := ar.GetTotalByUserIDGroupedByDay(ctx, userID, now.AddDate(0, 0, -maxDailyGoalStreak), now)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]time.Duration, 0, dailyDurations.Len())
+	for _, k := range dailyDurations.Keys() {
+		v, _ := dailyDurations.Get(k)
+		totals = append(totals, v)
+	}
+
+	progress := &dailyGoalProgress{
+		Goal:   user.DailyGoalDuration(),
+		Streak: user.DailyGoalStreak(totals),
+	}
+
+	if len(totals) > 0 {
+		progress.Today = totals[len(totals)-1]
+	}
+
+	return progress, nil
+}
+
+// addDailyGoalField shows the user's progress towards their daily goal on
+// the embed of logged activities. Failures are logged rather than failing
+// the command, since the activities have already been logged.
+func (c *LogCommand) addDailyGoalField(cmd *bot.InteractionContext, embed *discordutil.EmbedBuilder) {
+	progress, err := getDailyGoalProgress(cmd.Context(), c.userRepo, c.activityRepo, c.timeService, cmd.User().ID, cmd.Interaction().GuildID)
+	if err != nil {
+		cmd.Logger.Error("Failed to get daily goal progress", slog.String("err", err.Error()))
+		return
+	}
+
+	if progress != nil {
+		embed.AddField("Daily Goal", progress.String(), false)
+	}
+}
+
+// DailyGoalReminder reminds users who haven't met their daily goal some time
+// before local midnight.
+type DailyGoalReminder struct {
+	users       *users.UserRepository
+	activities  *activities.ActivityRepository
+	timeService *users.UserTimeService
+	logger      *slog.Logger
+}
+
+func NewDailyGoalReminder(
+	ur *users.UserRepository,
+	ar *activities.ActivityRepository,
+	ts *users.UserTimeService,
+	logger *slog.Logger,
+) *DailyGoalReminder {
+	return &DailyGoalReminder{users: ur, activities: ar, timeService: ts, logger: logger}
+}
+
+// SendReminders sends the day's reminders which are due. Meant to be run as
+// a bot task.
+func (r *DailyGoalReminder) SendReminders(ctx context.Context, s *discordgo.Session) error {
+	userIDs, err := r.users.FindDailyGoalReminderIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		// A failing user shouldn't keep others from being reminded
+		if err := r.sendReminder(ctx, s, userID); err != nil {
+			r.logger.Error("Failed to send daily goal reminder", slog.String("user", userID), slog.String("err", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// sendReminder reminds a user who hasn't met their daily goal if their
+// reminder is due.
+func (r *DailyGoalReminder) sendReminder(ctx context.Context, s *discordgo.Session, userID string) error {
+	user, err := r.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now, err := r.timeService.GetTime(ctx, userID, "")
+	if err != nil {
+		return err
+	}
+
+	if !user.DailyGoalReminderDue(now) {
+		return nil
+	}
+
+	claimed, err := r.users.ClaimDailyGoalReminder(ctx, userID, now)
+	if err != nil {
+		return err
+	} else if !claimed {
+		return nil
+	}
+
+	progress, err := getDailyGoalProgress(ctx, r.users, r.activities, r.timeService, userID, "")
+	if err != nil {
+		return err
+	} else if progress == nil || progress.Met() {
+		return nil
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+
+	description := fmt.Sprintf(
+		"You have %s left to reach your daily goal before the day ends <t:%d:R>.",
+		(progress.Goal - progress.Today).Round(time.Minute),
+		midnight.Unix(),
+	)
+	if progress.Streak > 0 {
+		description += fmt.Sprintf(" Keep your %d day streak going!", progress.Streak)
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Daily goal reminder").
+		SetColor(discordutil.ColorWarning).
+		SetDescription(description).
+		AddField("Progress", progress.String(), false).
+		SetFooter("Change this reminder with /config daily-goal-reminder", "").
+		SetTimestamp(now)
+
+	sendGoalDM(ctx, s, r.logger, userID, embed.MessageEmbed)
+	return nil
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+const dateOptionDescription = "e.g. yesterday 21:00, -2h, last fri or 5/1"
+
+// parseUserDate parses a date option relative to the current time in the
+// user's timezone. See timeparse.ParseDate for the accepted formats.
+func parseUserDate(ctx context.Context, ts *users.UserTimeService, userID, guildID, input string) (time.Time, error) {
+	now, err := ts.GetTime(ctx, userID, guildID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return timeparse.ParseDate(input, now)
+}
+
+// parseUserDateEnd is like parseUserDate, but dates without a time resolve
+// to the end of the day.
+func parseUserDateEnd(ctx context.Context, ts *users.UserTimeService, userID, guildID, input string) (time.Time, error) {
+	now, err := ts.GetTime(ctx, userID, guildID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return timeparse.ParseDateEnd(input, now)
+}
+
+// isDateOption reports whether the focused autocomplete option is one of the
+// date options handled by respondDateAutocomplete.
+func isDateOption(option *discordgo.ApplicationCommandInteractionDataOption) bool {
+	if option == nil {
+		return false
+	}
+
+	switch option.Name {
+	case "date", "start", "end":
+		return true
+	default:
+		return false
+	}
+}
+
+// respondDateAutocomplete previews the date the focused option resolves to.
+// The choice value is the resolved absolute date so that relative inputs
+// don't drift between autocompleting and submitting.
+func respondDateAutocomplete(ctx *bot.InteractionContext, ts *users.UserTimeService, option *discordgo.ApplicationCommandInteractionDataOption) error {
+	input := option.StringValue()
+	if input == "" {
+		input = "now"
+	}
+
+	var (
+		t   time.Time
+		err error
+	)
+
+	if option.Name == "end" {
+		t, err = parseUserDateEnd(ctx.ResponseContext(), ts, ctx.User().ID, ctx.Interaction().GuildID, input)
+	} else {
+		t, err = parseUserDate(ctx.ResponseContext(), ts, ctx.User().ID, ctx.Interaction().GuildID, input)
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 1)
+
+	if err == nil {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s)", t.Format("Mon, 02 Jan 2006 15:04"), t.Location()),
+			Value: t.Format(time.DateTime),
+		})
+	} else if !errors.Is(err, timeparse.ErrInvalidDate) {
+		return err
+	}
+
+	return ctx.Respond(discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
+		Choices: choices,
+	})
+}
+
+// focusedSubcommandOption returns the focused option of the interaction's
+// subcommand, or of the command itself if it has no subcommands.
+func focusedSubcommandOption(ctx *bot.InteractionContext) *discordgo.ApplicationCommandInteractionDataOption {
+	options := ctx.Options()
+	if len(options) > 0 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		options = options[0].Options
+	}
+
+	return discordutil.GetFocusedOption(options)
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+const (
+	durationOptionDescription = "e.g. 90, 1h30m, 1.5h or 1:30:00 (minutes if no unit)"
+	invalidDurationMessage    = "Invalid duration provided. Try something like `90`, `1h30m` or `1:30:00`."
+)
+
+// isDurationOption reports whether the focused autocomplete option is one of
+// the duration options handled by respondDurationAutocomplete.
+func isDurationOption(option *discordgo.ApplicationCommandInteractionDataOption) bool {
+	if option == nil {
+		return false
+	}
+
+	switch option.Name {
+	case "duration", "episode-duration", "min-duration", "target", "daily-goal":
+		return true
+	default:
+		return false
+	}
+}
+
+// respondDurationAutocomplete previews the duration the focused option
+// resolves to. Invalid input gets no choices, which still allows submitting
+// syntax handled elsewhere such as video duration ranges.
+func respondDurationAutocomplete(ctx *bot.InteractionContext, option *discordgo.ApplicationCommandInteractionDataOption) error {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 1)
+
+	if d, err := timeparse.ParseDuration(option.StringValue()); err == nil {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s minutes)", d, strconv.FormatFloat(d.Minutes(), 'f', -1, 64)),
+			Value: d.String(),
+		})
+	}
+
+	return ctx.Respond(discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
+		Choices: choices,
+	})
+}
+
+// parseDurationOption parses an optional duration option, where an empty
+// string is a zero duration.
+func parseDurationOption(input string) (time.Duration, error) {
+	if input == "" {
+		return 0, nil
+	}
+
+	return timeparse.ParseDuration(input)
+}
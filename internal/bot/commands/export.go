@@ -2,84 +2,248 @@ package commands
 
 import (
 	"bytes"
-	"compress/gzip"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"sync"
+	"log/slog"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/users"
+	activitiesPub "github.com/xoltia/botsu/pkg/activities"
 	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
 var ExportCommandData = &discordgo.ApplicationCommand{
 	Name:        "export",
-	Description: "Export your activities to a JSONL file.",
+	Description: "Export your activities to a file.",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "format",
+			Description: "The file format (default: jsonl.gz, which can be imported with /import)",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "JSONL (gzip)", Value: activitiesPub.FormatCompressedJSONL},
+				{Name: "JSON", Value: activitiesPub.FormatJSON},
+				{Name: "CSV", Value: activitiesPub.FormatCSV},
+				{Name: "iCalendar", Value: activitiesPub.FormatICS},
+			},
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         "start",
+			Description:  "Only export activities after this date, " + dateOptionDescription,
+			Required:     false,
+			Autocomplete: true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         "end",
+			Description:  "Only export activities before this date, " + dateOptionDescription,
+			Required:     false,
+			Autocomplete: true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "media-type",
+			Description: "Only export activities of this media type",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Anime", Value: activities.ActivityMediaTypeAnime},
+				{Name: "Book", Value: activities.ActivityMediaTypeBook},
+				{Name: "Manga", Value: activities.ActivityMediaTypeManga},
+				{Name: "Video", Value: activities.ActivityMediaTypeVideo},
+				{Name: "Visual Novel", Value: activities.ActivityMediaTypeVisualNovel},
+			},
+		},
+	},
 }
 
+const exportInterval = 24 * time.Hour
+
+// Files are split before reaching Discord's attachment size limit, leaving
+// room for output the writers have buffered but not yet written.
+const maxExportFileSize = 8 << 20
+
 type ExportCommand struct {
-	r         *activities.ActivityRepository
-	history   map[string]time.Time
-	historyMu sync.Mutex
+	r  *activities.ActivityRepository
+	u  *users.UserRepository
+	ts *users.UserTimeService
 }
 
-func NewExportCommand(r *activities.ActivityRepository) *ExportCommand {
-	return &ExportCommand{r: r, history: make(map[string]time.Time)}
+func NewExportCommand(r *activities.ActivityRepository, u *users.UserRepository, ts *users.UserTimeService) *ExportCommand {
+	return &ExportCommand{r: r, u: u, ts: ts}
+}
+
+type exportOptions struct {
+	Format    string  `discordopt:"format"`
+	Start     string  `discordopt:"start"`
+	End       string  `discordopt:"end"`
+	MediaType *string `discordopt:"media-type"`
 }
 
 func (c *ExportCommand) Handle(ctx *bot.InteractionContext) error {
-	userID := discordutil.GetInteractionUser(ctx.Interaction()).ID
+	if ctx.IsAutocomplete() {
+		if focused := focusedSubcommandOption(ctx); isDateOption(focused) {
+			return respondDateAutocomplete(ctx, c.ts, focused)
+		}
+		return nil
+	}
+
+	options := exportOptions{Format: activitiesPub.FormatCompressedJSONL}
+	if err := discordutil.UnmarshalOptions(ctx.Options(), &options); err != nil {
+		return err
+	}
 
-	c.historyMu.Lock()
+	userID := ctx.User().ID
+	guildID := ctx.Interaction().GuildID
+	filter := &activities.ActivityFilter{MediaType: options.MediaType}
+
+	if options.Start != "" {
+		start, err := parseUserDate(ctx.ResponseContext(), c.ts, userID, guildID, options.Start)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Content: "Invalid start date.",
+			})
+		} else if err != nil {
+			return err
+		}
+		filter.Start = &start
+	}
 
-	t, ok := c.history[userID]
+	if options.End != "" {
+		end, err := parseUserDateEnd(ctx.ResponseContext(), c.ts, userID, guildID, options.End)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
+			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+				Content: "Invalid end date.",
+			})
+		} else if err != nil {
+			return err
+		}
+		filter.End = &end
+	}
 
-	if ok && time.Since(t) < time.Hour*24 {
-		c.historyMu.Unlock()
+	ok, exportedAt, err := c.u.ClaimExport(ctx.ResponseContext(), userID, exportInterval)
+	if err != nil {
+		return err
+	}
 
+	if !ok {
 		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
-			Content: "You can only export your activities once per day.",
+			Content: fmt.Sprintf(
+				"You can only export your activities once per day. You can export again <t:%d:R>.",
+				exportedAt.Add(exportInterval).Unix(),
+			),
 		})
 	}
 
-	c.history[userID] = time.Now()
-	c.historyMu.Unlock()
+	// The claim is only kept once the export has been delivered, so that
+	// failed and empty exports don't count towards the limit
+	delivered := false
+	defer func() {
+		if delivered {
+			return
+		}
 
-	activities, err := c.r.GetAllByUserID(
-		ctx.Context(),
-		discordutil.GetInteractionUser(ctx.Interaction()).ID,
-		ctx.Interaction().GuildID)
+		if err := c.u.ReleaseExport(ctx.Context(), userID, exportedAt); err != nil {
+			ctx.Logger.Error("Failed to release export claim", slog.String("err", err.Error()))
+		}
+	}()
 
+	if err := ctx.DeferResponse(); err != nil {
+		return err
+	}
+
+	file := &exportFile{
+		format:   options.Format,
+		baseName: fmt.Sprintf("activities-%s-%s", userID, time.Now().Format(time.RFC3339)),
+		send: func(f *discordgo.File) error {
+			_, err := ctx.Followup(&discordgo.WebhookParams{
+				Files: []*discordgo.File{f},
+			}, false)
+			return err
+		},
+	}
+
+	if err := file.reset(); err != nil {
+		return err
+	}
+
+	err = c.r.StreamByUserID(ctx.Context(), userID, filter, file.write)
 	if err != nil {
 		return err
 	}
 
-	buffer := new(bytes.Buffer)
-	compressed := gzip.NewWriter(buffer)
-	jsonEncoder := json.NewEncoder(compressed)
+	if file.total == 0 {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: "You have no activities to export.",
+		}, false)
+		return err
+	}
+
+	if err = file.flush(true); err != nil {
+		return err
+	}
+
+	delivered = true
+	return nil
+}
+
+// exportFile writes activities to one or more files, sending each file once
+// it reaches maxExportFileSize. Every file is complete on its own.
+type exportFile struct {
+	format   string
+	baseName string
+	send     func(f *discordgo.File) error
+
+	buffer *bytes.Buffer
+	writer activitiesPub.Writer
+	parts  int
+	total  int
+}
 
-	for _, activity := range activities {
-		err = jsonEncoder.Encode(activity)
-		if err != nil {
+func (f *exportFile) reset() error {
+	f.buffer = new(bytes.Buffer)
+
+	var err error
+	f.writer, err = activitiesPub.NewWriter(f.format, f.buffer)
+	return err
+}
+
+func (f *exportFile) write(a *activities.Activity) error {
+	// Only split once it's known that more activities follow
+	if f.buffer.Len() >= maxExportFileSize {
+		if err := f.flush(false); err != nil {
 			return err
 		}
+
+		if err := f.reset(); err != nil {
+			return err
+		}
+	}
+
+	f.total++
+	return f.writer.Write(a)
+}
+
+func (f *exportFile) flush(last bool) error {
+	if err := f.writer.Close(); err != nil {
+		return err
 	}
 
-	// flush stream
-	compressed.Close()
+	f.parts++
 
-	filename := fmt.Sprintf("activities-%s-%s.jsonl.gz", userID, time.Now().Format(time.RFC3339))
+	name := f.baseName
+	if !last || f.parts > 1 {
+		name = fmt.Sprintf("%s-part%d", name, f.parts)
+	}
 
-	// send file
-	return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
-		Files: []*discordgo.File{
-			{
-				Name:        filename,
-				ContentType: "application/gzip",
-				Reader:      buffer,
-			},
-		},
+	return f.send(&discordgo.File{
+		Name:        name + "." + f.format,
+		ContentType: activitiesPub.ContentType(f.format),
+		Reader:      f.buffer,
 	})
 }
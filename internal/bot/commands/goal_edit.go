@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/pkg/discordutil"
+)
+
+// Value of the activity-type and media-type options of /goal edit which
+// removes the filter.
+const goalFilterAny = "any"
+
+// goalEditOptions returns the options of a goal which can be edited, none of
+// them required.
+func goalEditOptions() []*discordgo.ApplicationCommandOption {
+	options := append(goalRequiredOptions(), goalFilterOptions()...)
+
+	for _, option := range options {
+		option.Required = false
+
+		switch option.Name {
+		case "activity-type", "media-type":
+			option.Choices = append(option.Choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  "Any",
+				Value: goalFilterAny,
+			})
+		case "youtube-channels":
+			option.Description = "The YouTube channels to track (comma separated handles or IDs), or none for any."
+		case "work":
+			option.Description = "The anime or visual novel to track, e.g. anidb:69 or vndb:v17, or none for any."
+		case "title":
+			option.Description = "Only track activities whose name contains this text, or none for any."
+		case "tag":
+			option.Description = "Only track anime with this tag or videos with this hashtag, or none for any."
+		}
+	}
+
+	return options
+}
+
+// respondGoalIDAutocomplete suggests goals by name, matching the input
+// against their names and IDs.
+func respondGoalIDAutocomplete(cmd *bot.InteractionContext, focused *discordgo.ApplicationCommandInteractionDataOption, gs []*goals.Goal) error {
+	input := strings.ToLower(strings.TrimSpace(fmt.Sprint(focused.Value)))
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, min(len(gs), 25))
+	for _, g := range gs {
+		if len(choices) == 25 {
+			break
+		}
+
+		id := strconv.FormatInt(g.ID, 10)
+		if input != "" && !strings.Contains(strings.ToLower(g.Name), input) && !strings.HasPrefix(id, input) {
+			continue
+		}
+
+		name := fmt.Sprintf("%s (%d)", g.Name, g.ID)
+		if g.Paused() {
+			name += " (paused)"
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateLongString(name, 100),
+			Value: g.ID,
+		})
+	}
+
+	return cmd.Respond(discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
+		Choices: choices,
+	})
+}
+
+// parseGoalFilter reads a filter option of /goal edit. The filter is removed
+// if set to any, or none for options without choices.
+func parseGoalFilter(options []*discordgo.ApplicationCommandInteractionDataOption, name string, current *string) *string {
+	v := discordutil.GetStringOption(options, name)
+	if v == nil {
+		return current
+	} else if *v == goalFilterAny || isNoneOption(*v) {
+		return nil
+	}
+	return v
+}
+
+func isNoneOption(v string) bool {
+	return strings.EqualFold(strings.TrimSpace(v), "none")
+}
+
+func (c *GoalCommand) handleEdit(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	id, err := discordutil.GetRequiredIntOption(subcommand.Options, "id")
+	if err != nil {
+		return err
+	}
+
+	goal, err := c.findUserGoal(cmd, id)
+	if err != nil {
+		return err
+	} else if goal == nil {
+		return respondGoalNotFound(cmd, id)
+	}
+
+	options := subcommand.Options
+	name := discordutil.GetStringOptionOrDefault(options, "name", goal.Name)
+	unit := discordutil.GetStringOptionOrDefault(options, "unit", goal.Unit)
+	target := goal.Target
+	problem := ""
+
+	if targetString := discordutil.GetStringOption(options, "target"); targetString != nil {
+		if target, err = goals.ParseAmount(unit, *targetString); err != nil {
+			problem = invalidTargetProblem(unit)
+		}
+	} else if unit != goal.Unit {
+		problem = "A new target is required when changing the unit of a goal."
+	}
+
+	var schedule *goals.Schedule
+	if scheduleString := discordutil.GetStringOption(options, "schedule"); problem == "" && scheduleString != nil {
+		now, err := c.timeService.GetTime(cmd.ResponseContext(), cmd.User().ID, "")
+		if err != nil {
+			return err
+		}
+
+		var parsed goals.Schedule
+		parsed, problem = parseGoalSchedule(*scheduleString, now)
+		schedule = &parsed
+	}
+
+	work := discordutil.GetStringOption(options, "work")
+	if problem == "" && work != nil && !isNoneOption(*work) {
+		parsed, err := goals.ParseWork(*work)
+		if err != nil {
+			problem = invalidWorkProblem
+		}
+		work = &parsed
+	}
+
+	if problem != "" {
+		return cmd.Respond(
+			discordgo.InteractionResponseChannelMessageWithSource,
+			&discordgo.InteractionResponseData{
+				Content: problem,
+			},
+		)
+	}
+
+	if err := cmd.DeferResponse(); err != nil {
+		return err
+	}
+
+	var ytHandles, ytIDs []string
+	ytChannels := discordutil.GetStringOption(options, "youtube-channels")
+
+	if ytChannels != nil && !isNoneOption(*ytChannels) {
+		ytHandles, ytIDs, problem, err = resolveYoutubeChannels(cmd, *ytChannels)
+		if err != nil {
+			return err
+		} else if problem != "" {
+			_, err = cmd.Followup(&discordgo.WebhookParams{
+				Content: problem,
+			}, false)
+			return err
+		}
+	}
+
+	goal, changes, err := c.goals.Update(cmd.Context(), id, func(g *goals.Goal, now time.Time) (err error) {
+		g.Name = name
+		g.Unit = unit
+		g.Target = target
+		g.ActivityType = parseGoalFilter(options, "activity-type", g.ActivityType)
+		g.MediaType = parseGoalFilter(options, "media-type", g.MediaType)
+		g.NameContains = parseGoalFilter(options, "title", g.NameContains)
+		g.Tag = parseGoalFilter(options, "tag", g.Tag)
+
+		if work != nil {
+			g.Work = nil
+			if !isNoneOption(*work) {
+				g.Work = work
+			}
+		}
+
+		if ytChannels != nil {
+			g.YoutubeChannels, g.YoutubeChannelIDs = ytHandles, ytIDs
+		}
+
+		// The current period ends on the new schedule
+		if schedule != nil && !schedule.Equal(g.Schedule()) {
+			err = g.SetSchedule(*schedule, now)
+		}
+
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	content := "Nothing to change."
+	if len(changes) > 0 {
+		content = fmt.Sprintf("Goal **%s** updated:\n- %s", goal.Name, strings.Join(changes, "\n- "))
+	}
+
+	_, err = cmd.Followup(&discordgo.WebhookParams{
+		Content: content,
+	}, false)
+	return err
+}
+
+func (c *GoalCommand) handlePause(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption, pause bool) error {
+	id, err := discordutil.GetRequiredIntOption(subcommand.Options, "id")
+	if err != nil {
+		return err
+	}
+
+	goal, err := c.findUserGoal(cmd, id)
+	if err != nil {
+		return err
+	} else if goal == nil {
+		return respondGoalNotFound(cmd, id)
+	}
+
+	if pause {
+		goal, _, err = c.goals.Update(cmd.ResponseContext(), id, func(g *goals.Goal, now time.Time) error {
+			if g.Ended() {
+				return goals.ErrEnded
+			}
+			return g.Pause(now)
+		})
+	} else {
+		goal, err = c.goals.Resume(cmd.ResponseContext(), id)
+	}
+
+	var content string
+	switch {
+	case errors.Is(err, goals.ErrPaused):
+		content = "This goal is already paused."
+	case errors.Is(err, goals.ErrNotPaused):
+		content = "This goal isn't paused."
+	case errors.Is(err, goals.ErrEnded):
+		content = "This goal has ended. Give it a new schedule with `/goal edit` to restart it."
+	case err != nil:
+		return fmt.Errorf("failed to update goal: %w", err)
+	case pause:
+		content = fmt.Sprintf("Goal **%s** paused. Activities won't count towards it until you resume it with `/goal resume`.", goal.Name)
+	default:
+		content = fmt.Sprintf("Goal **%s** resumed. The current period ends <t:%d:R>.", goal.Name, goal.DueAt.Unix())
+	}
+
+	return cmd.Respond(
+		discordgo.InteractionResponseChannelMessageWithSource,
+		&discordgo.InteractionResponseData{
+			Content: content,
+		},
+	)
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/pkg/chart"
+	"github.com/xoltia/botsu/pkg/discordutil"
+)
+
+const (
+	maxGoalHistoryPeriods = 25
+	maxGoalHistoryChanges = 5
+)
+
+func (c *GoalCommand) handleHistory(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	id, err := discordutil.GetRequiredIntOption(subcommand.Options, "id")
+	if err != nil {
+		return err
+	}
+
+	limit := discordutil.GetIntOptionOrDefault(subcommand.Options, "periods", 10)
+	limit = min(max(limit, 1), maxGoalHistoryPeriods)
+
+	goal, err := c.findUserGoal(cmd, id)
+	if err != nil {
+		return err
+	} else if goal == nil {
+		return respondGoalNotFound(cmd, id)
+	}
+
+	if err := cmd.DeferResponse(); err != nil {
+		return err
+	}
+
+	// Close any periods which ended since the user last logged
+	if _, err := c.goals.CheckAll(cmd.Context(), goal.UserID); err != nil {
+		return err
+	}
+
+	periods, err := c.goals.FindPeriods(cmd.Context(), goal, int(limit))
+	if err != nil {
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle(fmt.Sprintf("History of %s (%d)", goal.Name, goal.ID)).
+		SetColor(discordutil.ColorPrimary)
+
+	changes, err := c.goals.FindChanges(cmd.Context(), goal.ID, maxGoalHistoryChanges)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		var sb strings.Builder
+		for _, change := range changes {
+			fmt.Fprintf(&sb, "<t:%d:d>: %s\n", change.ChangedAt.Unix(), strings.ReplaceAll(change.Description, "\n", "; "))
+		}
+		embed.AddField("Recent changes", sb.String(), false)
+	}
+
+	if len(periods) == 0 {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{
+				embed.SetDescription("No periods of this goal have closed yet.").MessageEmbed,
+			},
+		}, false)
+		return err
+	}
+
+	stats, err := c.goals.GetPeriodStats(cmd.Context(), goal.ID)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, p := range periods {
+		result := "❌"
+		if p.Completed() {
+			result = "✅"
+		}
+
+		fmt.Fprintf(
+			&sb,
+			"%s <t:%d:d> to <t:%d:d>: %s / %s (%.0f%%)\n",
+			result,
+			p.Start.Unix(),
+			p.End.Unix(),
+			goals.FormatAmount(goal.Unit, p.Achieved),
+			goals.FormatAmount(goal.Unit, p.Target),
+			p.Achieved/p.Target*100,
+		)
+	}
+
+	embed.
+		SetDescription(sb.String()).
+		AddField("Completion rate", fmt.Sprintf("%d / %d periods (%.0f%%)", stats.Completed, stats.Periods, stats.CompletionRate()), true).
+		AddField("Longest streak", fmt.Sprintf("%d periods", stats.LongestStreak), true).
+		AddField("Current streak", fmt.Sprintf("%d periods", stats.CurrentStreak), true)
+
+	if !discordutil.GetBoolOptionOrDefault(subcommand.Options, "chart", false) {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+		}, false)
+		return err
+	}
+
+	// Periods are charted as a percentage of their target, which may differ
+	// between periods, from oldest to newest
+	labels := make([]string, len(periods))
+	values := make([]float64, len(periods))
+	for i, p := range periods {
+		labels[i] = p.End.Format("2006-01-02")
+		values[i] = p.Achieved / p.Target * 100
+	}
+	slices.Reverse(labels)
+	slices.Reverse(values)
+
+	img, err := renderChart(&chart.BarChart{
+		Labels:    labels,
+		Series:    []chart.Series{{Values: values}},
+		Reference: 100,
+	}, 500, 300)
+	if err != nil {
+		return err
+	}
+
+	embed.SetImage("attachment://chart.png")
+
+	_, err = cmd.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+		Files: []*discordgo.File{
+			{
+				Name:        "chart.png",
+				ContentType: "image/png",
+				Reader:      img,
+			},
+		},
+	}, false)
+	return err
+}
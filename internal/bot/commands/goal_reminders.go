@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+const (
+	maxGoalReminders    = 5
+	maxGoalReminderLead = 7 * 24 * time.Hour
+)
+
+// parseGoalReminders parses a comma separated list of how long before goals
+// are due to send reminders, e.g. "1d, 6h". "off" disables reminders.
+func parseGoalReminders(input string) ([]time.Duration, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "off") {
+		return []time.Duration{}, nil
+	}
+
+	parts := strings.Split(input, ",")
+	if len(parts) > maxGoalReminders {
+		return nil, fmt.Errorf("at most %d reminders can be set", maxGoalReminders)
+	}
+
+	reminders := make([]time.Duration, 0, len(parts))
+	for _, part := range parts {
+		d, err := timeparse.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 || d > maxGoalReminderLead {
+			return nil, fmt.Errorf("invalid reminder: %s", strings.TrimSpace(part))
+		}
+
+		reminders = append(reminders, d)
+	}
+
+	return reminders, nil
+}
+
+// parseQuietHours parses a range of local hours such as "22-8". "off" removes
+// quiet hours, returning nil hours.
+func parseQuietHours(input string) (start, end *int, err error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "off") {
+		return nil, nil, nil
+	}
+
+	startString, endString, ok := strings.Cut(input, "-")
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid quiet hours: %s", input)
+	}
+
+	hours := [2]int{}
+	for i, s := range [2]string{startString, endString} {
+		hours[i], err = strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || hours[i] < 0 || hours[i] > 23 {
+			return nil, nil, fmt.Errorf("invalid quiet hours: %s", input)
+		}
+	}
+
+	if hours[0] == hours[1] {
+		return nil, nil, fmt.Errorf("quiet hours must start and end at different hours")
+	}
+
+	return &hours[0], &hours[1], nil
+}
+
+func formatGoalReminders(reminders []time.Duration) string {
+	parts := make([]string, len(reminders))
+	for i, r := range reminders {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sendGoalDM sends an embed to a user by DM. Users can have DMs closed, so
+// failures are only logged.
+func sendGoalDM(ctx context.Context, s *discordgo.Session, logger *slog.Logger, userID string, embed *discordgo.MessageEmbed) {
+	channel, err := s.UserChannelCreate(userID, discordgo.WithContext(ctx))
+	if err != nil {
+		logger.Warn("Unable to create DM channel for goal", slog.String("user", userID), slog.String("err", err.Error()))
+		return
+	}
+
+	_, err = s.ChannelMessageSendEmbed(channel.ID, embed, discordgo.WithContext(ctx))
+	if err != nil {
+		logger.Warn("Unable to send goal DM", slog.String("user", userID), slog.String("err", err.Error()))
+	}
+}
+
+// SendReminders reminds users of unfinished goals which are nearly due, at
+// the times before each goal is due that they chose, outside of their quiet
+// hours.
+func (c *GoalCommand) SendReminders(ctx context.Context, s *discordgo.Session) error {
+	remindable, err := c.goals.FindRemindable(ctx, time.Now(), maxGoalReminderLead)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range remindable {
+		// A failing goal, such as one of a deleted user, shouldn't keep
+		// others from being reminded
+		if err := c.sendReminder(ctx, s, g); err != nil {
+			c.logger.Error(
+				"Failed to send goal reminder",
+				slog.Int64("goal_id", g.ID),
+				slog.String("user", g.UserID),
+				slog.String("err", err.Error()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// sendReminder reminds the user of a goal if one of their reminders is due.
+func (c *GoalCommand) sendReminder(ctx context.Context, s *discordgo.Session, g *goals.Goal) error {
+	user, err := c.users.FindByID(ctx, g.UserID)
+	if err != nil {
+		return err
+	}
+
+	now, err := c.timeService.GetTime(ctx, g.UserID, "")
+	if err != nil {
+		return err
+	}
+
+	if user.IsQuietHour(now) || !g.ReminderDue(user.GoalReminders, now) {
+		return nil
+	}
+
+	claimed, err := c.goals.ClaimReminder(ctx, g, now)
+	if err != nil {
+		return err
+	} else if !claimed {
+		return nil
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Goal reminder").
+		SetColor(discordutil.ColorWarning).
+		SetDescription(fmt.Sprintf(
+			"**%s** is due <t:%d:R>: %s left, %s remaining.",
+			g.Name,
+			g.DueAt.Unix(),
+			g.DueAt.Sub(now).Round(time.Minute),
+			goals.FormatAmount(g.Unit, g.Target-g.Current),
+		)).
+		AddField("Progress", fmt.Sprintf(
+			"%s / %s **(%.2f%%)**",
+			goals.FormatAmount(g.Unit, g.Current),
+			goals.FormatAmount(g.Unit, g.Target),
+			g.Percentage(),
+		), false).
+		SetFooter(fmt.Sprintf("Goal ID: %d • Change reminders with /config goal-reminders", g.ID), "").
+		SetTimestamp(now)
+
+	sendGoalDM(ctx, s, c.logger, g.UserID, embed.MessageEmbed)
+	return nil
+}
+
+// formatNextReset describes when the current period of a goal ends, or when
+// a one-off goal ended.
+func formatNextReset(g *goals.Goal) string {
+	if g.Ended() {
+		return fmt.Sprintf("Ended <t:%d:R>", g.DueAt.Unix())
+	}
+	return fmt.Sprintf("Next Reset: <t:%d:R>", g.DueAt.Unix())
+}
+
+// formatNextPeriod describes the period following one which just closed.
+func formatNextPeriod(g *goals.Goal) string {
+	if g.Ended() {
+		return "None, this was the goal's only period."
+	}
+	return fmt.Sprintf("Due <t:%d:R>", g.DueAt.Unix())
+}
+
+// SendSummary sends the user a summary of a closed period of a goal with
+// summaries enabled. Periods of guild goals are announced in the goal's
+// channel instead. Meant to be a handler of the rollover worker.
+func (c *GoalCommand) SendSummary(ctx context.Context, s *discordgo.Session, p *goals.Period) error {
+	if !p.Goal.Summary {
+		return nil
+	}
+
+	if p.Goal.GuildID != nil {
+		return announceGuildGoalPeriod(ctx, s, c.logger, c.goals, p)
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle(fmt.Sprintf("Goal period closed: %s", p.Goal.Name)).
+		AddField("Period", fmt.Sprintf("<t:%d:f> to <t:%d:f>", p.Start.Unix(), p.End.Unix()), false).
+		AddField("Result", fmt.Sprintf(
+			"%s / %s **(%.2f%%)**",
+			goals.FormatAmount(p.Goal.Unit, p.Achieved),
+			goals.FormatAmount(p.Goal.Unit, p.Target),
+			p.Achieved/p.Target*100,
+		), false).
+		AddField("Next period", formatNextPeriod(p.Goal), false).
+		SetFooter(fmt.Sprintf("Goal ID: %d", p.Goal.ID), "").
+		SetTimestamp(p.End)
+
+	if p.Completed() {
+		embed.SetColor(discordutil.ColorSuccess).SetDescription("You completed this goal!")
+	} else {
+		embed.SetColor(discordutil.ColorDanger).SetDescription("You didn't reach this goal.")
+	}
+
+	sendGoalDM(ctx, s, c.logger, p.Goal.UserID, embed.MessageEmbed)
+	return nil
+}
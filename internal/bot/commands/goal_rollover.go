@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/goals"
+)
+
+// Most goals closed, or closed periods handled, by one batch of the rollover
+// worker.
+const goalRolloverBatchSize = 100
+
+// PeriodClosedHandler is called once with each closed period of a goal,
+// once the period has been recorded.
+type PeriodClosedHandler func(ctx context.Context, s *discordgo.Session, p *goals.Period) error
+
+// GoalRolloverWorker closes goal periods as they end, whether or not their
+// users log anything, and passes every recorded period on to its handlers,
+// including those closed when goals are checked or resumed.
+type GoalRolloverWorker struct {
+	goals    *goals.GoalService
+	handlers []PeriodClosedHandler
+	logger   *slog.Logger
+}
+
+func NewGoalRolloverWorker(goals *goals.GoalService, logger *slog.Logger) *GoalRolloverWorker {
+	return &GoalRolloverWorker{goals: goals, logger: logger}
+}
+
+// OnPeriodClosed adds a handler of closed periods. Handlers must be added
+// before the worker is run.
+func (w *GoalRolloverWorker) OnPeriodClosed(h PeriodClosedHandler) {
+	w.handlers = append(w.handlers, h)
+}
+
+// Run closes the periods of every due goal, then handles every period which
+// hasn't been yet. Meant to be run as a bot task. Goals and periods are
+// claimed before they are closed or handled, so several instances of the
+// bot may run the worker at once.
+func (w *GoalRolloverWorker) Run(ctx context.Context, s *discordgo.Session) error {
+	for {
+		n, err := w.goals.CloseDue(ctx, goalRolloverBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if n < goalRolloverBatchSize {
+			break
+		}
+	}
+
+	for {
+		periods, err := w.goals.ClaimClosedPeriods(ctx, goalRolloverBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range periods {
+			for _, h := range w.handlers {
+				// A failing handler shouldn't keep the others from seeing
+				// the period, since it won't be claimed again
+				if err := h(ctx, s, p); err != nil {
+					w.logger.Error(
+						"Failed to handle closed goal period",
+						slog.Int64("goal_id", p.Goal.ID),
+						slog.String("err", err.Error()),
+					)
+				}
+			}
+		}
+
+		if len(periods) < goalRolloverBatchSize {
+			return nil
+		}
+	}
+}
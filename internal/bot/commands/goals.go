@@ -8,15 +8,25 @@ import (
 	"strings"
 	"time"
 
-	"github.com/adhocore/gronx"
 	"github.com/bwmarrin/discordgo"
 	"github.com/jackc/pgx/v5"
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
 	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/internal/videos/ytchannel"
 	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
 )
 
+var goalIDOption = &discordgo.ApplicationCommandOption{
+	Type:         discordgo.ApplicationCommandOptionInteger,
+	Name:         "id",
+	Description:  "The ID of the goal.",
+	Required:     true,
+	Autocomplete: true,
+}
+
 var GoalCommandData = &discordgo.ApplicationCommand{
 	Name:        "goal",
 	Description: "Manage your goals.",
@@ -25,110 +35,240 @@ var GoalCommandData = &discordgo.ApplicationCommand{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "create",
 			Description: "Create a new goal.",
+			Options: append(
+				append(goalRequiredOptions(), goalFilterOptions()...),
+				&discordgo.ApplicationCommandOption{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "summary",
+					Description: "Whether to send you a summary by DM when each period closes (default: false).",
+					Required:    false,
+				},
+			),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List your goals.",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "delete",
+			Description: "Delete a goal.",
+			Options: []*discordgo.ApplicationCommandOption{
+				goalIDOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "edit",
+			Description: "Edit a goal, starting from its current period.",
+			Options:     append([]*discordgo.ApplicationCommandOption{goalIDOption}, goalEditOptions()...),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "pause",
+			Description: "Pause a goal, so that its periods don't close until it is resumed.",
+			Options: []*discordgo.ApplicationCommandOption{
+				goalIDOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "resume",
+			Description: "Resume a paused goal.",
+			Options: []*discordgo.ApplicationCommandOption{
+				goalIDOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "history",
+			Description: "View the results of a goal's past periods.",
 			Options: []*discordgo.ApplicationCommandOption{
+				goalIDOption,
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "The name of the goal.",
-					Required:    true,
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "periods",
+					Description: "The number of periods to show (default: 10).",
+					Required:    false,
+					MinValue:    ref.New(1.0),
+					MaxValue:    maxGoalHistoryPeriods,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "target",
-					Description: "The target duration of the goal.",
-					Required:    true,
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "chart",
+					Description: "Whether to chart the periods (default: false).",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// goalRequiredOptions returns the options required to create a user or guild
+// goal.
+func goalRequiredOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "name",
+			Description: "The name of the goal.",
+			Required:    true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         "target",
+			Description:  "The target of the goal, e.g. 2h or 1.5h for durations (minutes if no unit), or 100k characters.",
+			Required:     true,
+			Autocomplete: true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         "schedule",
+			Description:  "When the goal resets, e.g. daily, weekly on monday, every 3 days, 2025-12-31 or a cron.",
+			Required:     true,
+			Autocomplete: true,
+		},
+	}
+}
+
+// goalFilterOptions returns the options for what a goal is measured in and
+// which activities count towards it.
+func goalFilterOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "unit",
+			Description: "What the goal is measured in (default: duration).",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{
+					Name:  "Duration",
+					Value: goals.UnitDuration,
 				},
 				{
-					Type:         discordgo.ApplicationCommandOptionString,
-					Name:         "cron",
-					Description:  "The cron expression for the goal.",
-					Required:     true,
-					Autocomplete: true,
+					Name:  "Characters",
+					Value: goals.UnitCharacters,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "activity-type",
-					Description: "The type of activity to track.",
-					Required:    false,
-					Choices: []*discordgo.ApplicationCommandOptionChoice{
-						{
-							Name:  "Listening",
-							Value: activities.ActivityImmersionTypeListening,
-						},
-						{
-							Name:  "Reading",
-							Value: activities.ActivityImmersionTypeReading,
-						},
-					},
+					Name:  "Pages",
+					Value: goals.UnitPages,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "media-type",
-					Description: "The type of media to track.",
-					Required:    false,
-					Choices: []*discordgo.ApplicationCommandOptionChoice{
-						{
-							Name:  "Visual Novel",
-							Value: activities.ActivityMediaTypeVisualNovel,
-						},
-						{
-							Name:  "Book",
-							Value: activities.ActivityMediaTypeBook,
-						},
-						{
-							Name:  "Manga",
-							Value: activities.ActivityMediaTypeManga,
-						},
-						{
-							Name:  "Anime",
-							Value: activities.ActivityMediaTypeAnime,
-						},
-						{
-							Name:  "Video",
-							Value: activities.ActivityMediaTypeVideo,
-						},
-					},
+					Name:  "Episodes",
+					Value: goals.UnitEpisodes,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "youtube-channels",
-					Description: "The YouTube channels to track (comma separated, e.g. @HakuiKoyori,@ui_shig,@MinatoAqua).",
-					Required:    false,
+					Name:  "Activities",
+					Value: goals.UnitActivities,
+				},
+				{
+					Name:  "Points",
+					Value: goals.UnitPoints,
 				},
 			},
 		},
 		{
-			Type:        discordgo.ApplicationCommandOptionSubCommand,
-			Name:        "list",
-			Description: "List your goals.",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "activity-type",
+			Description: "The type of activity to track.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{
+					Name:  "Listening",
+					Value: activities.ActivityImmersionTypeListening,
+				},
+				{
+					Name:  "Reading",
+					Value: activities.ActivityImmersionTypeReading,
+				},
+			},
 		},
 		{
-			Type:        discordgo.ApplicationCommandOptionSubCommand,
-			Name:        "delete",
-			Description: "Delete a goal.",
-			Options: []*discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "media-type",
+			Description: "The type of media to track.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "id",
-					Description: "The ID of the goal.",
-					Required:    true,
+					Name:  "Visual Novel",
+					Value: activities.ActivityMediaTypeVisualNovel,
+				},
+				{
+					Name:  "Book",
+					Value: activities.ActivityMediaTypeBook,
+				},
+				{
+					Name:  "Manga",
+					Value: activities.ActivityMediaTypeManga,
+				},
+				{
+					Name:  "Anime",
+					Value: activities.ActivityMediaTypeAnime,
+				},
+				{
+					Name:  "Video",
+					Value: activities.ActivityMediaTypeVideo,
 				},
 			},
 		},
-	},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "youtube-channels",
+			Description: "The YouTube channels to track (comma separated handles or IDs, e.g. @HakuiKoyori,@ui_shig).",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "work",
+			Description: "The anime or visual novel to track, e.g. anidb:69, vndb:v17 or a link to it.",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "title",
+			Description: "Only track activities whose name contains this text.",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "tag",
+			Description: "Only track anime with this tag or videos with this hashtag.",
+			Required:    false,
+		},
+	}
 }
 
 type GoalCommand struct {
-	goals *goals.GoalService
+	goals       *goals.GoalService
+	users       *users.UserRepository
+	timeService *users.UserTimeService
+	logger      *slog.Logger
 }
 
-func NewGoalCommand(goals *goals.GoalService) *GoalCommand {
-	return &GoalCommand{goals: goals}
+func NewGoalCommand(goals *goals.GoalService, users *users.UserRepository, ts *users.UserTimeService, logger *slog.Logger) *GoalCommand {
+	return &GoalCommand{goals: goals, users: users, timeService: ts, logger: logger}
 }
 
 func (c *GoalCommand) Handle(cmd *bot.InteractionContext) error {
 	if cmd.IsAutocomplete() {
-		return c.handleAutocomplete(cmd)
+		focused := focusedSubcommandOption(cmd)
+		if focused != nil && focused.Name == "target" {
+			return respondTargetAutocomplete(cmd, focused)
+		} else if focused != nil && focused.Name == "id" {
+			userGoals, err := c.goals.FindByUserID(cmd.ResponseContext(), cmd.User().ID)
+			if err != nil {
+				return err
+			}
+			return respondGoalIDAutocomplete(cmd, focused, userGoals)
+		} else if focused != nil && focused.Name == "schedule" {
+			now, err := c.timeService.GetTime(cmd.ResponseContext(), cmd.User().ID, "")
+			if err != nil {
+				return err
+			}
+			return respondScheduleAutocomplete(cmd, focused, now)
+		}
+		return nil
 	}
 
 	if len(cmd.Options()) == 0 {
@@ -144,11 +284,45 @@ func (c *GoalCommand) Handle(cmd *bot.InteractionContext) error {
 		return c.handleList(cmd, subcommand)
 	case "delete":
 		return c.handleDelete(cmd, subcommand)
+	case "edit":
+		return c.handleEdit(cmd, subcommand)
+	case "pause":
+		return c.handlePause(cmd, subcommand, true)
+	case "resume":
+		return c.handlePause(cmd, subcommand, false)
+	case "history":
+		return c.handleHistory(cmd, subcommand)
 	default:
 		return bot.ErrInvalidOptions
 	}
 }
 
+// findUserGoal finds a goal of the user running the command, or returns nil
+// if they have no goal with the ID.
+func (c *GoalCommand) findUserGoal(cmd *bot.InteractionContext, id int64) (*goals.Goal, error) {
+	goal, err := c.goals.FindByID(cmd.ResponseContext(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error finding goal: %w", err)
+	}
+
+	if goal.UserID != cmd.User().ID || goal.GuildID != nil {
+		return nil, nil
+	}
+
+	return goal, nil
+}
+
+func respondGoalNotFound(cmd *bot.InteractionContext, id int64) error {
+	return cmd.Respond(
+		discordgo.InteractionResponseChannelMessageWithSource,
+		&discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("No goal found with ID: %d", id),
+		},
+	)
+}
+
 func (c *GoalCommand) handleDelete(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
 	id, err := discordutil.GetRequiredIntOption(subcommand.Options, "id")
 	if err != nil {
@@ -157,27 +331,11 @@ func (c *GoalCommand) handleDelete(cmd *bot.InteractionContext, subcommand *disc
 
 	cmd.Logger.Debug("Finding goal for deletion", slog.Int64("goal_id", id))
 
-	goal, err := c.goals.FindByID(cmd.ResponseContext(), id)
+	goal, err := c.findUserGoal(cmd, id)
 	if err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return fmt.Errorf("error finding goal: %w", err)
-		}
-
-		return cmd.Respond(
-			discordgo.InteractionResponseChannelMessageWithSource,
-			&discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("No goal found with ID: %d", id),
-			},
-		)
-	}
-
-	if goal.UserID != cmd.User().ID {
-		return cmd.Respond(
-			discordgo.InteractionResponseChannelMessageWithSource,
-			&discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("No goal found with ID: %d", id),
-			},
-		)
+		return err
+	} else if goal == nil {
+		return respondGoalNotFound(cmd, id)
 	}
 
 	cmd.Logger.Debug("Deleting goal", slog.Int64("goal_id", id))
@@ -196,12 +354,12 @@ func (c *GoalCommand) handleDelete(cmd *bot.InteractionContext, subcommand *disc
 }
 
 func (c *GoalCommand) handleList(cmd *bot.InteractionContext, _ *discordgo.ApplicationCommandInteractionDataOption) error {
-	goals, err := c.goals.CheckAll(cmd.ResponseContext(), cmd.User().ID)
+	userGoals, err := c.goals.CheckAll(cmd.ResponseContext(), cmd.User().ID)
 	if err != nil {
 		return fmt.Errorf("failed to find goals: %w", err)
 	}
 
-	if len(goals) == 0 {
+	if len(userGoals) == 0 {
 		return cmd.Respond(
 			discordgo.InteractionResponseChannelMessageWithSource,
 			&discordgo.InteractionResponseData{
@@ -210,6 +368,11 @@ func (c *GoalCommand) handleList(cmd *bot.InteractionContext, _ *discordgo.Appli
 		)
 	}
 
+	location, err := c.timeService.GetTimeLocation(cmd.ResponseContext(), cmd.User().ID, "")
+	if err != nil {
+		return err
+	}
+
 	if err := cmd.DeferResponse(); err != nil {
 		return err
 	}
@@ -219,21 +382,30 @@ func (c *GoalCommand) handleList(cmd *bot.InteractionContext, _ *discordgo.Appli
 		SetColor(discordutil.ColorPrimary).
 		SetTimestamp(time.Now())
 
-	for _, goal := range goals {
+	for _, goal := range userGoals {
+		title := fmt.Sprintf("%s (%d)", goal.Name, goal.ID)
+		progress := fmt.Sprintf(
+			"Progress: %s / %s **(%.2f%%)**\nSchedule: %s",
+			goals.FormatAmount(goal.Unit, goal.Current),
+			goals.FormatAmount(goal.Unit, goal.Target),
+			goal.Percentage(),
+			describeGoalSchedule(goal, location),
+		)
+
+		if goal.Ended() {
+			embed.AddField(title, fmt.Sprintf("%s\nEnded <t:%d:R>", progress, goal.DueAt.Unix()), false)
+			continue
+		} else if goal.Paused() {
+			embed.AddField(title, fmt.Sprintf("%s\nPaused <t:%d:R>", progress, goal.PausedAt.Unix()), false)
+			continue
+		}
+
 		nextDueDate, err := c.goals.NextCron(cmd.Context(), goal)
 		if err != nil {
 			return fmt.Errorf("failed to calculate next due date: %w", err)
 		}
 
-		title := fmt.Sprintf("%s (%d)", goal.Name, goal.ID)
-
-		embed.AddField(title, fmt.Sprintf(
-			"Progress: %s / %s **(%.2f%%)**\nNext Reset: <t:%d>",
-			goal.Current,
-			goal.Target,
-			goal.Current.Seconds()/goal.Target.Seconds()*100,
-			nextDueDate.Unix(),
-		), false)
+		embed.AddField(title, fmt.Sprintf("%s\nNext Reset: <t:%d>", progress, nextDueDate.Unix()), false)
 	}
 
 	pages := embed.SplitOnFields(2)
@@ -319,109 +491,242 @@ func (c *GoalCommand) handleList(cmd *bot.InteractionContext, _ *discordgo.Appli
 	return nil
 }
 
-func (c *GoalCommand) handleCreate(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
-	name, err := discordutil.GetRequiredStringOption(subcommand.Options, "name")
-	if err != nil {
-		return err
+func invalidTargetProblem(unit string) string {
+	if unit != goals.UnitDuration {
+		return fmt.Sprintf("Invalid target provided. Try a number of %s like `500` or `100k`.", unit)
 	}
+	return "Invalid target provided. Try something like `30m`, `2h` or `1.5h`."
+}
+
+const invalidWorkProblem = "Invalid work provided. Try an AniDB or VNDB ID like `anidb:69` or `vndb:v17`, or a link to the work."
 
-	target, err := discordutil.GetRequiredIntOption(subcommand.Options, "target")
+const invalidScheduleProblem = "Invalid schedule provided. Try `daily`, `weekly on monday`, `monthly`, `every 3 days`, a date like `2025-12-31` " +
+	"or a cron expression (see https://crontab.guru/)."
+
+const deadlinePassedProblem = "That date has already passed. Try a date in the future."
+
+// parseGoalSchedule reads the schedule option of a goal in the timezone of
+// now. Invalid schedules are described by problem for the user.
+func parseGoalSchedule(input string, now time.Time) (schedule goals.Schedule, problem string) {
+	schedule, err := goals.ParseSchedule(input, now)
+	if errors.Is(err, goals.ErrDeadlinePassed) {
+		return schedule, deadlinePassedProblem
+	} else if err != nil {
+		return schedule, invalidScheduleProblem
+	}
+	return schedule, ""
+}
+
+// describeGoalSchedule describes the schedule of a goal along with the
+// timezone it is kept in.
+func describeGoalSchedule(g *goals.Goal, location *time.Location) string {
+	return fmt.Sprintf("%s (%s)", g.Schedule().Describe(location), location)
+}
+
+// parseGoalOptions reads a goal from the options shared by user and guild
+// goals, scheduling it from now in the goal's timezone. Invalid options are
+// described by problem for the user. YouTube channels are left to be resolved
+// by resolveYoutubeChannels.
+func parseGoalOptions(options []*discordgo.ApplicationCommandInteractionDataOption, now time.Time) (goal *goals.Goal, problem string, err error) {
+	name, err := discordutil.GetRequiredStringOption(options, "name")
 	if err != nil {
-		return err
+		return
 	}
 
-	cron, err := discordutil.GetRequiredStringOption(subcommand.Options, "cron")
+	targetString, err := discordutil.GetRequiredStringOption(options, "target")
 	if err != nil {
-		return err
+		return
 	}
 
-	gron := gronx.New()
+	unit := discordutil.GetStringOptionOrDefault(options, "unit", goals.UnitDuration)
 
-	if !gron.IsValid(cron) {
-		return cmd.Respond(
-			discordgo.InteractionResponseChannelMessageWithSource,
-			&discordgo.InteractionResponseData{
-				Content: "Invalid cron provided. See https://crontab.guru/ for help on creating a valid cron expression.",
-			},
-		)
+	target, err := goals.ParseAmount(unit, targetString)
+	if err != nil {
+		return nil, invalidTargetProblem(unit), nil
 	}
 
-	activityType := discordutil.GetStringOption(subcommand.Options, "activity-type")
-	mediaType := discordutil.GetStringOption(subcommand.Options, "media-type")
-	ytChannels := discordutil.GetStringOption(subcommand.Options, "youtube-channels")
-
-	goal := &goals.Goal{}
+	scheduleString, err := discordutil.GetRequiredStringOption(options, "schedule")
+	if err != nil {
+		return
+	}
 
-	if activityType != nil {
-		goal.ActivityType = activityType
+	schedule, problem := parseGoalSchedule(scheduleString, now)
+	if problem != "" {
+		return nil, problem, nil
 	}
 
-	if mediaType != nil {
-		goal.MediaType = mediaType
+	goal = &goals.Goal{
+		ActivityType: discordutil.GetStringOption(options, "activity-type"),
+		MediaType:    discordutil.GetStringOption(options, "media-type"),
+		NameContains: discordutil.GetStringOption(options, "title"),
+		Tag:          discordutil.GetStringOption(options, "tag"),
 	}
 
-	if ytChannels != nil {
-		goal.YoutubeChannels = strings.Split(*ytChannels, ",")
+	if work := discordutil.GetStringOption(options, "work"); work != nil {
+		parsed, err := goals.ParseWork(*work)
+		if err != nil {
+			return nil, invalidWorkProblem, nil
+		}
+		goal.Work = &parsed
 	}
 
 	goal.Name = name
-	goal.Target = time.Duration(target) * time.Minute
-	goal.Cron = cron
-	goal.UserID = cmd.User().ID
-	goal.DueAt, err = c.goals.NextCron(cmd.ResponseContext(), goal)
+	goal.Unit = unit
+	goal.Target = target
+
+	if err = goal.SetSchedule(schedule, now); err != nil {
+		return nil, "", fmt.Errorf("failed to calculate due date: %w", err)
+	}
+
+	return goal, "", nil
+}
+
+func (c *GoalCommand) handleCreate(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	now, err := c.timeService.GetTime(cmd.ResponseContext(), cmd.User().ID, "")
+	if err != nil {
+		return err
+	}
 
+	goal, problem, err := parseGoalOptions(subcommand.Options, now)
 	if err != nil {
-		return fmt.Errorf("failed to calculate due date: %w", err)
+		return err
+	}
+
+	if ytChannels := discordutil.GetStringOption(subcommand.Options, "youtube-channels"); ytChannels != nil && problem == "" {
+		goal.YoutubeChannels, goal.YoutubeChannelIDs, problem, err = resolveYoutubeChannels(cmd, *ytChannels)
+		if err != nil {
+			return err
+		}
+	}
+
+	if problem != "" {
+		_, err = cmd.RespondOrFollowup(&discordgo.WebhookParams{
+			Content: problem,
+		}, false)
+		return err
 	}
 
+	goal.Summary = discordutil.GetBoolOptionOrDefault(subcommand.Options, "summary", false)
+	goal.UserID = cmd.User().ID
+
 	cmd.Logger.Debug("Creating goal", slog.Any("goal", goal))
 
-	if err := c.goals.Create(cmd.ResponseContext(), goal); err != nil {
+	if err := c.goals.Create(cmd.Context(), goal); err != nil {
 		return fmt.Errorf("failed to create goal: %w", err)
 	}
 
-	return cmd.Respond(
-		discordgo.InteractionResponseChannelMessageWithSource,
-		&discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("Goal **%s** created!", goal.Name),
-		},
-	)
+	_, err = cmd.RespondOrFollowup(&discordgo.WebhookParams{
+		Content: fmt.Sprintf(
+			"Goal **%s** created with a target of %s! Schedule: %s.",
+			goal.Name,
+			goals.FormatAmount(goal.Unit, goal.Target),
+			describeGoalSchedule(goal, now.Location()),
+		),
+	}, false)
+	return err
 }
 
-func (c *GoalCommand) handleAutocomplete(cmd *bot.InteractionContext) error {
-	choices := [...]*discordgo.ApplicationCommandOptionChoice{
-		{
-			Name:  "Daily",
-			Value: "@daily",
-		},
-		{
-			Name:  "Weekly",
-			Value: "@weekly",
-		},
-		{
-			Name:  "Monthly",
-			Value: "@monthly",
-		},
-		{
-			Name:  "Yearly",
-			Value: "@yearly",
-		},
+// resolveYoutubeChannels looks up the handles and IDs of a comma separated
+// list of YouTube channel handles or IDs, deferring the response while they
+// are looked up. Channels which can't be found are described by problem.
+func resolveYoutubeChannels(cmd *bot.InteractionContext, input string) (handles, ids []string, problem string, err error) {
+	if !cmd.Responded() {
+		if err = cmd.DeferResponse(); err != nil {
+			return
+		}
 	}
 
-	//filteredChoices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(choices))
-	//input := discordutil.GetFocusedOption(cmd.Options())
-	//
-	//if input != nil && input.Type == discordgo.ApplicationCommandOptionString && len(input.Value.(string)) > 0 {
-	//	for _, choice := range choices {
-	//		if strings.Contains(choice.Value.(string), input.Value.(string)) {
-	//			filteredChoices = append(filteredChoices, choice)
-	//		}
-	//	}
-	//} else {
-	//	filteredChoices = choices[:]
-	//}
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		channel, err := ytchannel.GetYoutubeChannel(cmd.Context(), part)
+		if err != nil {
+			cmd.Logger.Debug("Unable to find YouTube channel", slog.String("channel", part), slog.String("err", err.Error()))
+			problem = fmt.Sprintf("Couldn't find the YouTube channel `%s`. Try its handle, like `@HakuiKoyori`, or its ID.", part)
+			return nil, nil, problem, nil
+		}
+
+		handles = append(handles, channel.Handle)
+		ids = append(ids, channel.ID)
+	}
+
+	return
+}
+
+// respondTargetAutocomplete previews the target in the unit chosen so far.
+func respondTargetAutocomplete(cmd *bot.InteractionContext, focused *discordgo.ApplicationCommandInteractionDataOption) error {
+	unit := discordutil.GetStringOptionOrDefault(cmd.Options()[0].Options, "unit", goals.UnitDuration)
+	if unit == goals.UnitDuration {
+		return respondDurationAutocomplete(cmd, focused)
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 1)
+
+	if v, err := goals.ParseAmount(unit, focused.StringValue()); err == nil {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  goals.FormatAmount(unit, v),
+			Value: focused.StringValue(),
+		})
+	}
+
+	return cmd.Respond(discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
+		Choices: choices,
+	})
+}
+
+// Schedules suggested by respondScheduleAutocomplete, along with a deadline.
+var schedulePresetInputs = [...]string{
+	"daily",
+	"weekly on monday",
+	"weekly on tuesday",
+	"weekly on wednesday",
+	"weekly on thursday",
+	"weekly on friday",
+	"weekly on saturday",
+	"weekly on sunday",
+	"monthly",
+	"yearly",
+	"every 2 days",
+	"every 3 days",
+}
+
+// respondScheduleAutocomplete suggests schedule presets matching the input,
+// describing the input itself if it is already a valid schedule.
+func respondScheduleAutocomplete(cmd *bot.InteractionContext, focused *discordgo.ApplicationCommandInteractionDataOption, now time.Time) error {
+	input := strings.TrimSpace(focused.StringValue())
+
+	inputs := make([]string, 0, len(schedulePresetInputs)+2)
+	inputs = append(inputs, input)
+	inputs = append(inputs, schedulePresetInputs[:]...)
+	// A deadline at the end of the month, as an example of dates
+	inputs = append(inputs, time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).Format(time.DateOnly))
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(inputs))
+	for i, v := range inputs {
+		if v == "" || len(v) > 100 {
+			continue
+		}
+
+		// Presets are filtered by the input, which is suggested as is
+		if i > 0 && (v == input || !strings.Contains(v, strings.ToLower(input))) {
+			continue
+		}
+
+		schedule, err := goals.ParseSchedule(v, now)
+		if err != nil {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateLongString(schedule.Describe(now.Location()), 100),
+			Value: v,
+		})
+	}
 
 	return cmd.Respond(discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
-		Choices: choices[:],
+		Choices: choices,
 	})
 }
@@ -1,13 +1,16 @@
 package commands
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/xoltia/botsu/internal/bot"
 	"github.com/xoltia/botsu/internal/guilds"
 	"github.com/xoltia/botsu/pkg/discordutil"
 	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
 var GuildConfigCommandData = &discordgo.ApplicationCommand{
@@ -23,6 +26,12 @@ var GuildConfigCommandData = &discordgo.ApplicationCommand{
 			Required:     false,
 			Autocomplete: true,
 		},
+		{
+			Name:        "duplicate-window",
+			Description: "Set how close together activities must be to warn about duplicates, or 0 to disable",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    false,
+		},
 	},
 }
 
@@ -70,6 +79,40 @@ func (c *GuildConfigCommand) Handle(ctx *bot.InteractionContext) error {
 		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
 			Content: "Timezone set!",
 		})
+	case "duplicate-window":
+		input, err := discordutil.GetRequiredStringOption(options, "duplicate-window")
+		if err != nil {
+			return err
+		}
+
+		var window time.Duration
+		if strings.TrimSpace(input) != "0" {
+			window, err = timeparse.ParseDuration(input)
+			if err != nil || window < 0 {
+				return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+					Content: invalidDurationMessage,
+				})
+			}
+		}
+
+		_, err = c.r.FindOrCreate(ctx.Context(), i.GuildID)
+		if err != nil {
+			return err
+		}
+
+		err = c.r.SetGuildDuplicateWindow(ctx.Context(), i.GuildID, &window)
+		if err != nil {
+			return err
+		}
+
+		content := fmt.Sprintf("Duplicate window set to %s!", window)
+		if window == 0 {
+			content = "Duplicate warnings disabled!"
+		}
+
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: content,
+		})
 	}
 	return nil
 }
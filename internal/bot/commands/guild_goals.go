@@ -0,0 +1,426 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/internal/guilds"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+// Number of members shown on guild goal progress messages and announcements.
+const guildGoalTopContributors = 5
+
+var GuildGoalCommandData = &discordgo.ApplicationCommand{
+	Name:                     "guild-goal",
+	Description:              "Manage goals the whole server works towards together.",
+	DMPermission:             ref.New(false),
+	DefaultMemberPermissions: ref.New(int64(discordgo.PermissionManageServer)),
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "create",
+			Description: "Create a new server goal.",
+			Options: append(
+				append(goalRequiredOptions(), &discordgo.ApplicationCommandOption{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "The channel to show the goal's progress and announcements in.",
+					Required:     true,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+				}),
+				goalFilterOptions()...,
+			),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List the server's goals.",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "delete",
+			Description: "Delete a server goal.",
+			Options: []*discordgo.ApplicationCommandOption{
+				goalIDOption,
+			},
+		},
+	},
+}
+
+type GuildGoalCommand struct {
+	goals       *goals.GoalService
+	guilds      *guilds.GuildRepository
+	timeService *users.UserTimeService
+	logger      *slog.Logger
+}
+
+func NewGuildGoalCommand(goals *goals.GoalService, guilds *guilds.GuildRepository, ts *users.UserTimeService, logger *slog.Logger) *GuildGoalCommand {
+	return &GuildGoalCommand{goals: goals, guilds: guilds, timeService: ts, logger: logger}
+}
+
+// now returns the current time in the timezone of the guild the command is
+// run in, which its goals are kept in.
+func (c *GuildGoalCommand) now(cmd *bot.InteractionContext) (time.Time, error) {
+	location, err := c.timeService.GetGuildTimeLocation(cmd.ResponseContext(), cmd.Interaction().GuildID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(location), nil
+}
+
+func (c *GuildGoalCommand) Handle(cmd *bot.InteractionContext) error {
+	if cmd.IsAutocomplete() {
+		focused := focusedSubcommandOption(cmd)
+		if focused != nil && focused.Name == "target" {
+			return respondTargetAutocomplete(cmd, focused)
+		} else if focused != nil && focused.Name == "id" {
+			guildGoals, err := c.goals.FindByGuildID(cmd.ResponseContext(), cmd.Interaction().GuildID)
+			if err != nil {
+				return err
+			}
+			return respondGoalIDAutocomplete(cmd, focused, guildGoals)
+		} else if focused != nil && focused.Name == "schedule" {
+			now, err := c.now(cmd)
+			if err != nil {
+				return err
+			}
+			return respondScheduleAutocomplete(cmd, focused, now)
+		}
+		return nil
+	}
+
+	if len(cmd.Options()) == 0 {
+		return bot.ErrInvalidOptions
+	}
+
+	subcommand := cmd.Options()[0]
+
+	switch subcommand.Name {
+	case "create":
+		return c.handleCreate(cmd, subcommand)
+	case "list":
+		return c.handleList(cmd, subcommand)
+	case "delete":
+		return c.handleDelete(cmd, subcommand)
+	default:
+		return bot.ErrInvalidOptions
+	}
+}
+
+func (c *GuildGoalCommand) handleCreate(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	now, err := c.now(cmd)
+	if err != nil {
+		return err
+	}
+
+	goal, problem, err := parseGoalOptions(subcommand.Options, now)
+	if err != nil {
+		return err
+	}
+
+	if ytChannels := discordutil.GetStringOption(subcommand.Options, "youtube-channels"); ytChannels != nil && problem == "" {
+		goal.YoutubeChannels, goal.YoutubeChannelIDs, problem, err = resolveYoutubeChannels(cmd, *ytChannels)
+		if err != nil {
+			return err
+		}
+	}
+
+	if problem != "" {
+		_, err = cmd.RespondOrFollowup(&discordgo.WebhookParams{
+			Content: problem,
+		}, false)
+		return err
+	}
+
+	channel, err := discordutil.GetRequiredChannelOption(subcommand.Options, "channel", nil)
+	if err != nil {
+		return err
+	}
+
+	guildID := cmd.Interaction().GuildID
+
+	if _, err = c.guilds.FindOrCreate(cmd.Context(), guildID); err != nil {
+		return fmt.Errorf("failed to find guild: %w", err)
+	}
+
+	goal.UserID = cmd.User().ID
+	goal.GuildID = &guildID
+	goal.ChannelID = &channel.ID
+	// Closing periods are announced in the channel even if nobody logs
+	goal.Summary = true
+
+	cmd.Logger.Debug("Creating guild goal", slog.Any("goal", goal))
+
+	if err := c.goals.Create(cmd.Context(), goal); err != nil {
+		return fmt.Errorf("failed to create goal: %w", err)
+	}
+
+	_, err = cmd.RespondOrFollowup(&discordgo.WebhookParams{
+		Content: fmt.Sprintf(
+			"Server goal **%s** created with a target of %s! Schedule: %s. Progress will be shown in <#%s>.",
+			goal.Name,
+			goals.FormatAmount(goal.Unit, goal.Target),
+			describeGoalSchedule(goal, now.Location()),
+			channel.ID,
+		),
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	refreshGuildGoalMessage(cmd.Context(), cmd.Session(), cmd.Logger, c.goals, goal)
+	return nil
+}
+
+func (c *GuildGoalCommand) handleList(cmd *bot.InteractionContext, _ *discordgo.ApplicationCommandInteractionDataOption) error {
+	guildGoals, err := c.goals.FindByGuildID(cmd.ResponseContext(), cmd.Interaction().GuildID)
+	if err != nil {
+		return fmt.Errorf("failed to find goals: %w", err)
+	}
+
+	if len(guildGoals) == 0 {
+		return cmd.Respond(
+			discordgo.InteractionResponseChannelMessageWithSource,
+			&discordgo.InteractionResponseData{
+				Content: "This server has no goals! Try setting one with: `/guild-goal create`",
+			},
+		)
+	}
+
+	now, err := c.now(cmd)
+	if err != nil {
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Server Goals").
+		SetColor(discordutil.ColorPrimary).
+		SetTimestamp(now)
+
+	for _, goal := range guildGoals {
+		value := fmt.Sprintf(
+			"Progress: %s / %s **(%.2f%%)**\nSchedule: %s\n%s\nChannel: <#%s>",
+			goals.FormatAmount(goal.Unit, goal.Current),
+			goals.FormatAmount(goal.Unit, goal.Target),
+			goal.Percentage(),
+			describeGoalSchedule(goal, now.Location()),
+			formatNextReset(goal),
+			*goal.ChannelID,
+		)
+
+		if goal.MessageID != nil {
+			value += fmt.Sprintf(
+				" ([progress](https://discord.com/channels/%s/%s/%s))",
+				*goal.GuildID,
+				*goal.ChannelID,
+				*goal.MessageID,
+			)
+		}
+
+		embed.AddField(fmt.Sprintf("%s (%d)", goal.Name, goal.ID), value, false)
+	}
+
+	pages := embed.SplitOnFields(25)
+
+	return cmd.Respond(
+		discordgo.InteractionResponseChannelMessageWithSource,
+		&discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{pages[0].MessageEmbed},
+		},
+	)
+}
+
+func (c *GuildGoalCommand) handleDelete(cmd *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	id, err := discordutil.GetRequiredIntOption(subcommand.Options, "id")
+	if err != nil {
+		return err
+	}
+
+	goal, err := c.goals.FindByID(cmd.ResponseContext(), id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (goal.GuildID == nil || *goal.GuildID != cmd.Interaction().GuildID)) {
+		return respondGoalNotFound(cmd, id)
+	} else if err != nil {
+		return fmt.Errorf("error finding goal: %w", err)
+	}
+
+	cmd.Logger.Debug("Deleting guild goal", slog.Int64("goal_id", id))
+
+	if err = c.goals.DeleteByID(cmd.ResponseContext(), id); err != nil {
+		return err
+	}
+
+	if goal.MessageID != nil {
+		err = cmd.Session().ChannelMessageDelete(*goal.ChannelID, *goal.MessageID, discordgo.WithContext(cmd.ResponseContext()))
+		if err != nil {
+			cmd.Logger.Warn("Unable to delete guild goal message", slog.String("err", err.Error()))
+		}
+	}
+
+	return cmd.Respond(
+		discordgo.InteractionResponseChannelMessageWithSource,
+		&discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Server goal **%s** deleted.", goal.Name),
+		},
+	)
+}
+
+// formatContributors lists contributions as a ranking of members.
+func formatContributors(unit string, contributions []goals.Contribution) string {
+	if len(contributions) == 0 {
+		return "No contributions yet."
+	}
+
+	var b strings.Builder
+	for i, contribution := range contributions {
+		fmt.Fprintf(&b, "%d. <@%s>: %s\n", i+1, contribution.UserID, goals.FormatAmount(unit, contribution.Amount))
+	}
+
+	return b.String()
+}
+
+func newGuildGoalEmbed(g *goals.Goal, contributions []goals.Contribution) *discordutil.EmbedBuilder {
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle(g.Name).
+		SetDescription(fmt.Sprintf(
+			"%s / %s **(%.2f%%)**\n%s",
+			goals.FormatAmount(g.Unit, g.Current),
+			goals.FormatAmount(g.Unit, g.Target),
+			g.Percentage(),
+			formatNextReset(g),
+		)).
+		AddField("Top contributors", formatContributors(g.Unit, contributions), false).
+		SetFooter(fmt.Sprintf("Goal ID: %d • Log activities to contribute", g.ID), "").
+		SetTimestamp(time.Now())
+
+	if g.Current >= g.Target {
+		embed.SetColor(discordutil.ColorSuccess)
+	} else {
+		embed.SetColor(discordutil.ColorPrimary)
+	}
+
+	return embed
+}
+
+// refreshGuildGoalMessage edits the progress message of a guild goal, sending
+// a new one if it has none or it was deleted. Failures are logged and the
+// message is left until the goal is next updated.
+func refreshGuildGoalMessage(ctx context.Context, s *discordgo.Session, logger *slog.Logger, gs *goals.GoalService, g *goals.Goal) {
+	contributions, err := gs.FindTopContributors(ctx, g.ID, g.DueAt, guildGoalTopContributors)
+	if err != nil {
+		logger.Error("Failed to find guild goal contributors", slog.Int64("goal_id", g.ID), slog.String("err", err.Error()))
+		return
+	}
+
+	embed := newGuildGoalEmbed(g, contributions)
+	messageID := g.MessageID
+
+	var msg *discordgo.Message
+	if messageID != nil {
+		msg, err = s.ChannelMessageEditEmbed(*g.ChannelID, *messageID, embed.MessageEmbed, discordgo.WithContext(ctx))
+	}
+	if messageID == nil || err != nil {
+		msg, err = s.ChannelMessageSendEmbed(*g.ChannelID, embed.MessageEmbed, discordgo.WithContext(ctx))
+	}
+
+	if err != nil {
+		logger.Warn("Unable to send guild goal message", slog.Int64("goal_id", g.ID), slog.String("err", err.Error()))
+	} else {
+		messageID = &msg.ID
+	}
+
+	if err = gs.SetMessage(ctx, g.ID, messageID); err != nil {
+		logger.Error("Failed to save guild goal message", slog.Int64("goal_id", g.ID), slog.String("err", err.Error()))
+	}
+}
+
+// RefreshMessages updates the progress messages of guild goals which have
+// changed. Meant to be run as a bot task.
+func (c *GuildGoalCommand) RefreshMessages(ctx context.Context, s *discordgo.Session) error {
+	stale, err := c.goals.FindStaleMessages(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range stale {
+		refreshGuildGoalMessage(ctx, s, c.logger, c.goals, g)
+	}
+
+	return nil
+}
+
+func sendGuildGoalAnnouncement(ctx context.Context, s *discordgo.Session, logger *slog.Logger, g *goals.Goal, embed *discordgo.MessageEmbed) {
+	_, err := s.ChannelMessageSendEmbed(*g.ChannelID, embed, discordgo.WithContext(ctx))
+	if err != nil {
+		logger.Warn("Unable to send guild goal announcement", slog.Int64("goal_id", g.ID), slog.String("err", err.Error()))
+	}
+}
+
+// announceGuildGoalPeriod announces the result of a closed period of a guild
+// goal in its channel.
+func announceGuildGoalPeriod(ctx context.Context, s *discordgo.Session, logger *slog.Logger, gs *goals.GoalService, p *goals.Period) error {
+	contributions, err := gs.FindTopContributors(ctx, p.Goal.ID, p.End, guildGoalTopContributors)
+	if err != nil {
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle(fmt.Sprintf("Server goal period closed: %s", p.Goal.Name)).
+		AddField("Period", fmt.Sprintf("<t:%d:f> to <t:%d:f>", p.Start.Unix(), p.End.Unix()), false).
+		AddField("Result", fmt.Sprintf(
+			"%s / %s **(%.2f%%)**",
+			goals.FormatAmount(p.Goal.Unit, p.Achieved),
+			goals.FormatAmount(p.Goal.Unit, p.Target),
+			p.Achieved/p.Target*100,
+		), false).
+		AddField("Top contributors", formatContributors(p.Goal.Unit, contributions), false).
+		AddField("Next period", formatNextPeriod(p.Goal), false).
+		SetFooter(fmt.Sprintf("Goal ID: %d", p.Goal.ID), "").
+		SetTimestamp(p.End)
+
+	if p.Completed() {
+		embed.SetColor(discordutil.ColorSuccess).SetDescription("The server completed this goal!")
+	} else {
+		embed.SetColor(discordutil.ColorDanger).SetDescription("The server didn't reach this goal.")
+	}
+
+	sendGuildGoalAnnouncement(ctx, s, logger, p.Goal, embed.MessageEmbed)
+	return nil
+}
+
+// AnnounceReached announces guild goals which members have pushed past their
+// targets. Meant to be run as a bot task.
+func (c *GuildGoalCommand) AnnounceReached(ctx context.Context, s *discordgo.Session) error {
+	reached, err := c.goals.ClaimReached(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range reached {
+		embed := discordutil.NewEmbedBuilder().
+			SetTitle(fmt.Sprintf("Server goal reached: %s", r.Goal.Name)).
+			SetColor(discordutil.ColorSuccess).
+			SetDescription(fmt.Sprintf(
+				"<@%s> pushed the server past its target of %s, due <t:%d:R>!",
+				r.UserID,
+				goals.FormatAmount(r.Goal.Unit, r.Goal.Target),
+				r.Goal.DueAt.Unix(),
+			)).
+			SetFooter(fmt.Sprintf("Goal ID: %d", r.Goal.ID), "").
+			SetTimestamp(time.Now())
+
+		sendGuildGoalAnnouncement(ctx, s, c.logger, r.Goal, embed.MessageEmbed)
+	}
+
+	return nil
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/users"
 	"github.com/xoltia/botsu/pkg/discordutil"
 	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
 var HistoryCommandData = &discordgo.ApplicationCommand{
@@ -42,18 +45,188 @@ var HistoryCommandData = &discordgo.ApplicationCommand{
 			Description: "The page of history to view.",
 			Required:    false,
 		},
+		{
+			Name:        "search",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Description: "Only show activities with a name containing this text.",
+			Required:    false,
+		},
+		{
+			Name:        "type",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Description: "Only show activities of this type.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{
+					Name:  "Listening",
+					Value: activities.ActivityImmersionTypeListening,
+				},
+				{
+					Name:  "Reading",
+					Value: activities.ActivityImmersionTypeReading,
+				},
+			},
+		},
+		{
+			Name:        "media-type",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Description: "Only show activities of this media type.",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{
+					Name:  "Anime",
+					Value: activities.ActivityMediaTypeAnime,
+				},
+				{
+					Name:  "Manga",
+					Value: activities.ActivityMediaTypeManga,
+				},
+				{
+					Name:  "Book",
+					Value: activities.ActivityMediaTypeBook,
+				},
+				{
+					Name:  "Video",
+					Value: activities.ActivityMediaTypeVideo,
+				},
+				{
+					Name:  "Visual Novel",
+					Value: activities.ActivityMediaTypeVisualNovel,
+				},
+			},
+		},
+		{
+			Name:         "start",
+			Type:         discordgo.ApplicationCommandOptionString,
+			Description:  "Only show activities on or after this date.",
+			Required:     false,
+			Autocomplete: true,
+		},
+		{
+			Name:         "end",
+			Type:         discordgo.ApplicationCommandOptionString,
+			Description:  "Only show activities on or before this date.",
+			Required:     false,
+			Autocomplete: true,
+		},
+		{
+			Name:         "min-duration",
+			Type:         discordgo.ApplicationCommandOptionString,
+			Description:  "Only show activities lasting at least this long, e.g. 30m or 1h.",
+			Required:     false,
+			Autocomplete: true,
+		},
 	},
 }
 
 type HistoryCommand struct {
-	r *activities.ActivityRepository
+	r  *activities.ActivityRepository
+	ts *users.UserTimeService
+}
+
+func NewHistoryCommand(r *activities.ActivityRepository, ts *users.UserTimeService) *HistoryCommand {
+	return &HistoryCommand{r: r, ts: ts}
+}
+
+// Returns nil filter if no filter options are provided, or a user-facing error
+// message if any of the options are invalid.
+func (c *HistoryCommand) getFilter(ctx *bot.InteractionContext) (*activities.ActivityFilter, string, error) {
+	var args struct {
+		Search      string  `discordopt:"search"`
+		Type        *string `discordopt:"type"`
+		MediaType   *string `discordopt:"media-type"`
+		Start       string  `discordopt:"start"`
+		End         string  `discordopt:"end"`
+		MinDuration string  `discordopt:"min-duration"`
+	}
+
+	if err := discordutil.UnmarshalOptions(ctx.Options(), &args); err != nil {
+		return nil, "", err
+	}
+
+	filter := &activities.ActivityFilter{
+		PrimaryType: args.Type,
+		MediaType:   args.MediaType,
+		NameSearch:  strings.TrimSpace(args.Search),
+	}
+
+	minDuration, err := parseDurationOption(args.MinDuration)
+	if err != nil {
+		return nil, "Invalid minimum duration.", nil
+	}
+	filter.MinDuration = minDuration
+
+	if args.Start != "" || args.End != "" {
+		now, err := c.ts.GetTime(ctx.Context(), ctx.User().ID, ctx.Interaction().GuildID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if args.Start != "" {
+			start, err := timeparse.ParseDate(args.Start, now)
+			if err != nil {
+				return nil, "Invalid start date.", nil
+			}
+			filter.Start = &start
+		}
+
+		if args.End != "" {
+			// Dates without a time include the entire day
+			end, err := timeparse.ParseDateEnd(args.End, now)
+			if err != nil {
+				return nil, "Invalid end date.", nil
+			}
+			filter.End = &end
+		}
+	}
+
+	if filter.IsEmpty() {
+		return nil, "", nil
+	}
+
+	return filter, "", nil
 }
 
-func NewHistoryCommand(r *activities.ActivityRepository) *HistoryCommand {
-	return &HistoryCommand{r: r}
+func describeHistoryFilter(filter *activities.ActivityFilter) string {
+	if filter.IsEmpty() {
+		return ""
+	}
+
+	parts := make([]string, 0, 6)
+
+	if filter.NameSearch != "" {
+		parts = append(parts, fmt.Sprintf("Name contains **%s**", filter.NameSearch))
+	}
+	if filter.PrimaryType != nil {
+		parts = append(parts, fmt.Sprintf("Type: **%s**", *filter.PrimaryType))
+	}
+	if filter.MediaType != nil {
+		parts = append(parts, fmt.Sprintf("Media type: **%s**", *filter.MediaType))
+	}
+	if filter.Start != nil {
+		parts = append(parts, fmt.Sprintf("From <t:%d>", filter.Start.Unix()))
+	}
+	if filter.End != nil {
+		parts = append(parts, fmt.Sprintf("Until <t:%d>", filter.End.Unix()))
+	}
+	if filter.MinDuration > 0 {
+		parts = append(parts, fmt.Sprintf("At least **%s**", filter.MinDuration))
+	}
+
+	return strings.Join(parts, "\n")
 }
 
 func (c *HistoryCommand) Handle(ctx *bot.InteractionContext) error {
+	if ctx.IsAutocomplete() {
+		focused := focusedSubcommandOption(ctx)
+		if isDateOption(focused) {
+			return respondDateAutocomplete(ctx, c.ts, focused)
+		} else if isDurationOption(focused) {
+			return respondDurationAutocomplete(ctx, focused)
+		}
+		return nil
+	}
+
 	if err := ctx.DeferResponse(); err != nil {
 		return err
 	}
@@ -74,13 +247,38 @@ func (c *HistoryCommand) Handle(ctx *bot.InteractionContext) error {
 		user = discordutil.GetInteractionUser(i)
 	}
 
-	page, err := c.r.PageByUserID(ctx.Context(), user.ID, ctx.Interaction().GuildID, pageSize, offset)
+	filter, invalidMessage, err := c.getFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if invalidMessage != "" {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: invalidMessage,
+		}, false)
+		return err
+	}
+
+	page, err := c.r.PageByUserID(ctx.Context(), user.ID, ctx.Interaction().GuildID, pageSize, offset, filter)
 	if err != nil {
 		return err
 	}
 
+	if len(page.Activities) == 0 {
+		content := "No activities found."
+		if filter != nil {
+			content = "No activities found matching your filters."
+		}
+
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: content,
+		}, false)
+		return err
+	}
+
 	embed := discordutil.NewEmbedBuilder().
 		SetTitle("Activity History").
+		SetDescription(describeHistoryFilter(filter)).
 		SetColor(discordutil.ColorPrimary).
 		SetAuthor(user.Username, user.AvatarURL("256"), "").
 		SetFooter(fmt.Sprintf("Page %d of %d", page.Page, page.PageCount), "")
@@ -220,7 +418,7 @@ func (c *HistoryCommand) Handle(ctx *bot.InteractionContext) error {
 			offset = (page.PageCount - 1) * pageSize
 		}
 
-		page, err = c.r.PageByUserID(ciContext, user.ID, ctx.Interaction().GuildID, pageSize, offset)
+		page, err = c.r.PageByUserID(ciContext, user.ID, ctx.Interaction().GuildID, pageSize, offset, filter)
 		if err != nil {
 			cancel()
 			return err
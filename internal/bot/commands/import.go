@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"net/http"
 	"path"
 	"strconv"
@@ -14,6 +13,8 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/internal/works"
 	activitiesPub "github.com/xoltia/botsu/pkg/activities"
 	"github.com/xoltia/botsu/pkg/discordutil"
 )
@@ -35,6 +36,44 @@ var ImportCommandData = &discordgo.ApplicationCommand{
 				},
 			},
 		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "csv",
+			Description: "Import activities from a CSV file with a header row",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "A CSV file with name, type or media type, duration and optionally date and notes columns",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "mapping",
+					Description: "Columns to read each field from if not detected, e.g. name=Title, duration=Time Spent",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "aib",
+			Description: "Import your activities from an AnotherImmersionBot database",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "The AnotherImmersionBot SQLite database (bot_data.db)",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "video-metadata",
+					Description: "Fetch the titles and channels of videos (default: true)",
+					Required:    false,
+				},
+			},
+		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "list",
@@ -57,11 +96,17 @@ var ImportCommandData = &discordgo.ApplicationCommand{
 }
 
 type ImportCommand struct {
-	r *activities.ActivityRepository
+	r  *activities.ActivityRepository
+	ws *works.WorkService
+	ts *users.UserTimeService
 }
 
-func NewImportCommand(r *activities.ActivityRepository) *ImportCommand {
-	return &ImportCommand{r}
+func NewImportCommand(
+	r *activities.ActivityRepository,
+	ws *works.WorkService,
+	ts *users.UserTimeService,
+) *ImportCommand {
+	return &ImportCommand{r: r, ws: ws, ts: ts}
 }
 
 func (c *ImportCommand) handleList(
@@ -132,7 +177,7 @@ func (c *ImportCommand) handleUndo(
 
 	var removed int64
 
-	if removed, err = c.r.UndoImportByUserIDAndTimestamp(ctx, cmd.User().ID, time.Unix(0, timestamp)); err != nil {
+	if removed, err = c.ws.UndoImport(ctx, cmd.User().ID, time.Unix(0, timestamp)); err != nil {
 		embedBuilder.SetDescription("Failed to undo import!")
 
 		_, err = cmd.Followup(&discordgo.WebhookParams{
@@ -152,7 +197,7 @@ func (c *ImportCommand) handleUndo(
 		return err
 	}
 
-	embedBuilder.SetDescription(fmt.Sprintf("Successfully removed import! %d entries were removed.", removed))
+	embedBuilder.SetDescription(fmt.Sprintf("Successfully removed import! %d entries were removed. Use `/trash restore import:%d` to bring them back.", removed, timestamp))
 	embedBuilder.SetTitle("Success!")
 	embedBuilder.SetColor(discordutil.ColorSuccess)
 
@@ -186,6 +231,14 @@ func (c *ImportCommand) Handle(cmd *bot.InteractionContext) error {
 		return c.handleUndo(ctx, cmd, opts)
 	}
 
+	if subcommand == "csv" {
+		return c.handleCSV(cmd, opts)
+	}
+
+	if subcommand == "aib" {
+		return c.handleAIB(cmd, opts)
+	}
+
 	attachmentOption, err := discordutil.GetRequiredOption(opts, "file")
 
 	if err != nil {
@@ -248,51 +301,17 @@ func (c *ImportCommand) Handle(cmd *bot.InteractionContext) error {
 		return err
 	}
 
+	rows := make([]activities.ImportRow, len(as))
 	for i, a := range as {
 		// Important: make sure ID is overwritten
 		a.UserID = cmd.User().ID
-
-		if err = activities.ValidateExternalActivity(a); err == nil {
-			continue
-		}
-
-		embedBuilder.SetTitle("Invalid Activity!")
-
-		description := fmt.Sprintf(
-			"Activity with ID %d on line %d was unable to be imported: %s",
-			a.ID,
-			i+1,
-			err)
-
-		embedBuilder.SetDescription(description)
-
-		_, err := cmd.Followup(&discordgo.WebhookParams{
-			Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
-		}, false)
-
-		return err
+		rows[i] = activities.ImportRow{Line: i + 1, Activity: a}
 	}
 
-	if err := c.r.ImportMany(ctx, as); err != nil {
-		cmd.Logger.Error("Failed to import activities", slog.String("err", err.Error()))
-
-		embedBuilder.SetDescription("Failed to import activities. Check your import list for incomplete imports and try again later.")
-
-		_, err := cmd.Followup(&discordgo.WebhookParams{
-			Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
-		}, false)
-
+	plan, err := c.planImport(ctx, cmd.User().ID, rows)
+	if err != nil {
 		return err
 	}
 
-	embedBuilder.SetTitle("Success!")
-	embedBuilder.SetDescription(fmt.Sprintf("Successfully imported **%d** activities.\nView your import history with `/import list`.", len(as)))
-	embedBuilder.SetColor(discordutil.ColorSuccess)
-	embedBuilder.SetTimestamp(time.Now())
-
-	_, err = cmd.Followup(&discordgo.WebhookParams{
-		Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
-	}, false)
-
-	return err
+	return c.confirmImport(cmd, plan, discordutil.NewEmbedBuilder())
 }
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/aib"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+const (
+	maxAIBAttachmentSize = 25 << 20
+	maxAIBActivities     = 50000
+	aibReadTimeout       = 30 * time.Second
+	aibProgressInterval  = 3 * time.Second
+)
+
+func (c *ImportCommand) handleAIB(
+	cmd *bot.InteractionContext,
+	opts []*discordgo.ApplicationCommandInteractionDataOption,
+) error {
+	attachmentOption, err := discordutil.GetRequiredOption(opts, "file")
+	if err != nil {
+		return err
+	}
+
+	attachmentID, ok := attachmentOption.Value.(string)
+	if !ok {
+		return errors.New("expected string value from attachment option")
+	}
+
+	attachment := cmd.Data().Resolved.Attachments[attachmentID]
+
+	switch strings.ToLower(path.Ext(attachment.Filename)) {
+	case ".db", ".sqlite", ".sqlite3":
+	default:
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: "Invalid file type, expected the AnotherImmersionBot database file (e.g. `bot_data.db`).",
+		}, false)
+		return err
+	}
+
+	if attachment.Size > maxAIBAttachmentSize {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf("File is too large, the maximum size is %d MiB.", maxAIBAttachmentSize>>20),
+		}, false)
+		return err
+	}
+
+	input, err := downloadAttachment(cmd.Context(), attachment.URL, maxAIBAttachmentSize)
+	if err != nil {
+		return err
+	}
+
+	userID := cmd.User().ID
+	as, err := readAIBActivities(cmd, input, userID)
+	if errors.Is(err, aib.ErrTooManyActivities) {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf("Too many activities, at most %d can be imported at once.", maxAIBActivities),
+		}, false)
+		return err
+	} else if err != nil {
+		cmd.Logger.Warn("Failed to read AnotherImmersionBot database", slog.String("err", err.Error()))
+
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: "Failed to read file. Make sure it is an AnotherImmersionBot database.",
+		}, false)
+		return err
+	}
+
+	if len(as) == 0 {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: "No activities of yours were found in this database.",
+		}, false)
+		return err
+	}
+
+	var (
+		lastProgress time.Time
+		videoErrors  int
+	)
+
+	converted, err := aib.ConvertAll(cmd.Context(), as, aib.Options{
+		VideoMetadata:  discordutil.GetBoolOptionOrDefault(opts, "video-metadata", true),
+		MaxConcurrency: 3,
+		Progress: func(done, total int) {
+			if time.Since(lastProgress) < aibProgressInterval {
+				return
+			}
+			lastProgress = time.Now()
+
+			_, err := cmd.EditResponse(&discordgo.WebhookEdit{
+				Content: ref.New(fmt.Sprintf("Converting activities... (%d/%d)", done, total)),
+			})
+			if err != nil {
+				cmd.Logger.Warn("Failed to report import progress", slog.String("err", err.Error()))
+			}
+		},
+		VideoError: func(a *aib.Activity, err error) {
+			videoErrors++
+			cmd.Logger.Debug(
+				"Failed to fetch video metadata",
+				slog.String("url", *a.URL),
+				slog.String("err", err.Error()),
+			)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.EditResponse(&discordgo.WebhookEdit{
+		Content: ref.New(fmt.Sprintf("Converted %d activities from AnotherImmersionBot.", len(converted))),
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := make([]activities.ImportRow, len(converted))
+	for i, a := range converted {
+		// Important: make sure ID is overwritten
+		a.UserID = userID
+		rows[i] = activities.ImportRow{Line: i + 1, Activity: a}
+	}
+
+	plan, err := c.planImport(cmd.Context(), userID, rows)
+	if err != nil {
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder()
+	if videoErrors > 0 {
+		embed.SetDescription(fmt.Sprintf("Metadata could not be fetched for %d videos.", videoErrors))
+	}
+
+	return c.confirmImport(cmd, plan, embed)
+}
+
+// readAIBActivities reads the activities of a user from a database file,
+// which SQLite can only open from disk. Reading is cut off after
+// aibReadTimeout, as the file can be crafted to make queries slow.
+func readAIBActivities(cmd *bot.InteractionContext, input []byte, userID string) ([]*aib.Activity, error) {
+	f, err := os.CreateTemp("", "aib-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(input)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := aib.Open(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), aibReadTimeout)
+	defer cancel()
+
+	return aib.ReadActivities(ctx, db, userID, maxAIBActivities)
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/bot"
+	activitiesPub "github.com/xoltia/botsu/pkg/activities"
+	"github.com/xoltia/botsu/pkg/discordutil"
+)
+
+const maxCSVAttachmentSize = 4 << 20
+
+func (c *ImportCommand) handleCSV(
+	cmd *bot.InteractionContext,
+	opts []*discordgo.ApplicationCommandInteractionDataOption,
+) error {
+	attachmentOption, err := discordutil.GetRequiredOption(opts, "file")
+	if err != nil {
+		return err
+	}
+
+	attachmentID, ok := attachmentOption.Value.(string)
+	if !ok {
+		return errors.New("expected string value from attachment option")
+	}
+
+	attachment := cmd.Data().Resolved.Attachments[attachmentID]
+	if attachment.Size > maxCSVAttachmentSize {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf("File is too large, the maximum size is %d MiB.", maxCSVAttachmentSize>>20),
+		}, false)
+		return err
+	}
+
+	mapping, err := activitiesPub.ParseCSVMapping(discordutil.GetStringOptionOrDefault(opts, "mapping", ""))
+	if errors.Is(err, activitiesPub.ErrInvalidMapping) {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf(
+				"Invalid mapping: %s.\nUse `field=column` pairs separated by commas, where field is one of "+
+					"`name`, `type`, `media-type`, `duration`, `date` or `notes`.",
+				err,
+			),
+		}, false)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	input, err := downloadAttachment(cmd.Context(), attachment.URL, maxCSVAttachmentSize)
+	if err != nil {
+		return err
+	}
+
+	userID := cmd.User().ID
+	guildID := cmd.Interaction().GuildID
+
+	now, err := c.ts.GetTime(cmd.Context(), userID, guildID)
+	if err != nil {
+		return err
+	}
+
+	rows, mapping, err := activitiesPub.ReadCSV(bytes.NewReader(input), mapping, now)
+	if errors.Is(err, activitiesPub.ErrMissingColumn) {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf(
+				"Could not find the columns to import (%s).\nUse the `mapping` option to choose them, "+
+					"e.g. `name=Title, type=Category, duration=Minutes, date=Day`.",
+				err,
+			),
+		}, false)
+		return err
+	} else if err != nil {
+		_, err = cmd.Followup(&discordgo.WebhookParams{
+			Content: "Failed to read file. Make sure it is a valid CSV file with a header row.",
+		}, false)
+		return err
+	}
+
+	for _, row := range rows {
+		if row.Activity == nil {
+			continue
+		}
+
+		// Important: make sure ID is overwritten
+		row.Activity.UserID = userID
+		if guildID != "" {
+			row.Activity.GuildID = &guildID
+		}
+	}
+
+	plan, err := c.planImport(cmd.Context(), userID, rows)
+	if err != nil {
+		return err
+	}
+
+	return c.confirmImport(cmd, plan, newCSVImportPreviewEmbed(rows, mapping))
+}
+
+// newCSVImportPreviewEmbed shows the first rows as they were read, so that
+// a wrong mapping is noticed before importing.
+func newCSVImportPreviewEmbed(rows []activitiesPub.CSVRow, mapping activitiesPub.CSVMapping) *discordutil.EmbedBuilder {
+	var (
+		table strings.Builder
+		shown int
+	)
+
+	table.WriteString("```\n")
+
+	for _, r := range rows {
+		if r.Activity == nil {
+			continue
+		}
+
+		if shown++; shown > 10 {
+			continue
+		}
+
+		fmt.Fprintf(
+			&table,
+			"%4d %s %-9s %8s %s\n",
+			r.Line,
+			r.Activity.Date.Format("2006/01/02"),
+			r.Activity.PrimaryType,
+			r.Activity.Duration.Round(time.Minute).String(),
+			truncateLongString(r.Activity.Name, 24),
+		)
+	}
+
+	if shown > 10 {
+		fmt.Fprintf(&table, "...and %d more\n", shown-10)
+	}
+
+	table.WriteString("```")
+
+	embed := discordutil.NewEmbedBuilder()
+	if shown > 0 {
+		embed.SetDescription(table.String())
+	}
+
+	embed.AddField("Columns", fmt.Sprintf("`%s`", mapping), false)
+
+	return embed
+}
@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+const (
+	maxImportPreviewLines = 10
+	// Keeps maxImportPreviewLines of errors within an embed field
+	maxImportErrorLength = 80
+)
+
+// planImport compares rows to the user's existing activities around the same
+// dates.
+func (c *ImportCommand) planImport(ctx context.Context, userID string, rows []activities.ImportRow) (*activities.ImportPlan, error) {
+	var existing []*activities.Activity
+
+	if start, end, ok := activities.ImportRange(rows); ok {
+		filter := &activities.ActivityFilter{Start: &start, End: &end}
+		err := c.r.StreamByUserID(ctx, userID, filter, func(a *activities.Activity) error {
+			existing = append(existing, a)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return activities.PlanImport(rows, existing), nil
+}
+
+func describeImportRows(rows []activities.ImportRow, describe func(row activities.ImportRow) string) string {
+	var sb strings.Builder
+
+	for i, row := range rows {
+		if i == maxImportPreviewLines {
+			fmt.Fprintf(&sb, "...and %d more", len(rows)-i)
+			break
+		}
+
+		fmt.Fprintf(&sb, "Line %d: %s\n", row.Line, describe(row))
+	}
+
+	return sb.String()
+}
+
+func describeImportActivity(row activities.ImportRow) string {
+	return fmt.Sprintf(
+		"%s, %s on %s",
+		truncateLongString(row.Activity.Name, 24),
+		row.Activity.Duration.Round(time.Minute),
+		row.Activity.Date.Format("2006/01/02 15:04"),
+	)
+}
+
+func describeImportError(row activities.ImportRow) string {
+	return truncateLongString(row.Err.Error(), maxImportErrorLength)
+}
+
+func addImportPlanFields(embed *discordutil.EmbedBuilder, plan *activities.ImportPlan) {
+	embed.AddField("New", fmt.Sprintf("%d activities", len(plan.New)), true)
+	embed.AddField("Duplicates", fmt.Sprintf("%d activities", len(plan.Duplicates)), true)
+	embed.AddField("Near duplicates", fmt.Sprintf("%d activities", len(plan.NearDuplicates)), true)
+
+	if len(plan.NearDuplicates) > 0 {
+		embed.AddField(
+			"Similar to existing activities",
+			describeImportRows(plan.NearDuplicates, describeImportActivity),
+			false,
+		)
+	}
+
+	if len(plan.Invalid) > 0 {
+		embed.AddField(
+			fmt.Sprintf("Skipped %d invalid rows", len(plan.Invalid)),
+			describeImportRows(plan.Invalid, describeImportError),
+			false,
+		)
+	}
+}
+
+// confirmImport shows the plan of an import below the given preview and
+// imports the activities the user chooses. Duplicates are skipped unless
+// the user chooses to include them.
+func (c *ImportCommand) confirmImport(
+	cmd *bot.InteractionContext,
+	plan *activities.ImportPlan,
+	embed *discordutil.EmbedBuilder,
+) error {
+	addImportPlanFields(embed, plan)
+
+	embed.
+		SetTitle("Confirm import").
+		SetColor(discordutil.ColorWarning).
+		SetFooter("Imports can be undone with /import undo.", "").
+		SetTimestamp(time.Now())
+
+	choices := map[string][]*activities.Activity{}
+	buttons := []discordgo.MessageComponent{}
+
+	addChoice := func(id, label string, style discordgo.ButtonStyle, as []*activities.Activity) {
+		choices[id] = as
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf(label, len(as)),
+			Style:    style,
+			CustomID: id,
+		})
+	}
+
+	if len(plan.New)+len(plan.NearDuplicates) > 0 {
+		addChoice("import_confirm", "Import %d activities", discordgo.SuccessButton, plan.Activities(false, false))
+	}
+
+	if len(plan.New) > 0 && len(plan.NearDuplicates) > 0 {
+		addChoice("import_skip_near", "Skip near duplicates (import %d)", discordgo.PrimaryButton, plan.Activities(false, true))
+	}
+
+	if len(plan.Duplicates) > 0 {
+		addChoice("import_all", "Include duplicates (import %d)", discordgo.SecondaryButton, plan.Activities(true, false))
+	}
+
+	if len(choices) == 0 {
+		embed.SetTitle("Nothing to import").SetColor(discordutil.ColorDanger)
+		embed.Footer = nil
+		_, err := cmd.Followup(&discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+		}, false)
+		return err
+	}
+
+	buttons = append(buttons, discordgo.Button{
+		Label:    "Cancel",
+		Style:    discordgo.SecondaryButton,
+		CustomID: "import_cancel",
+	})
+
+	msg, err := cmd.Followup(&discordgo.WebhookParams{
+		Embeds:     []*discordgo.MessageEmbed{embed.MessageEmbed},
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
+	}, true)
+	if err != nil {
+		return err
+	}
+
+	collectionContext, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+	defer cancel()
+
+	ci, err := cmd.Bot.CollectSingleComponentInteraction(
+		collectionContext,
+		msg,
+		discordutil.NewInteractionUserFilter(cmd.Interaction()),
+	)
+
+	if err != nil {
+		_, err = cmd.Session().FollowupMessageEdit(cmd.Interaction().Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content:    ref.New("Timed out."),
+			Components: &[]discordgo.MessageComponent{},
+			Embeds:     &[]*discordgo.MessageEmbed{},
+		})
+		return err
+	}
+
+	customID := ci.MessageComponentData().CustomID
+	if customID == "import_cancel" {
+		return cmd.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "Cancelled.",
+				Components: []discordgo.MessageComponent{},
+				Embeds:     []*discordgo.MessageEmbed{},
+			},
+		})
+	}
+
+	as, ok := choices[customID]
+	if !ok {
+		return errors.New("invalid custom id")
+	}
+
+	// Importing may take longer than the time to respond to the button
+	err = cmd.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.ws.Import(cmd.Context(), as); err != nil {
+		cmd.Logger.Error("Failed to import activities", slog.String("err", err.Error()))
+
+		_, err = cmd.Session().FollowupMessageEdit(cmd.Interaction().Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content:    ref.New("Failed to import activities. Check your import list for incomplete imports and try again later."),
+			Components: &[]discordgo.MessageComponent{},
+			Embeds:     &[]*discordgo.MessageEmbed{},
+		})
+		return err
+	}
+
+	embed.
+		SetTitle("Success!").
+		SetDescription(fmt.Sprintf("Successfully imported **%d** activities.\nView your import history with `/import list`.", len(as))).
+		SetColor(discordutil.ColorSuccess)
+	embed.Footer = nil
+
+	_, err = cmd.Session().FollowupMessageEdit(cmd.Interaction().Interaction, msg.ID, &discordgo.WebhookEdit{
+		Components: &[]discordgo.MessageComponent{},
+		Embeds:     &[]*discordgo.MessageEmbed{embed.MessageEmbed},
+	})
+	return err
+}
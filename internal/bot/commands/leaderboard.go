@@ -9,15 +9,27 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/golang-module/carbon/v2"
-	"github.com/jackc/pgx/v5"
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
 	"github.com/xoltia/botsu/internal/guilds"
+	"github.com/xoltia/botsu/internal/scoring"
 	"github.com/xoltia/botsu/internal/users"
 	"github.com/xoltia/botsu/pkg/discordutil"
 	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
+var leaderboardModeOption = &discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        "mode",
+	Description: "Rank by total duration or by points (default: duration)",
+	Required:    false,
+	Choices: []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "Duration", Value: "duration"},
+		{Name: "Points", Value: "points"},
+	},
+}
+
 var LeaderboardCommandData = &discordgo.ApplicationCommand{
 	Name:         "leaderboard",
 	Description:  "View the leaderboard",
@@ -27,26 +39,31 @@ var LeaderboardCommandData = &discordgo.ApplicationCommand{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "day",
 			Description: "View the leaderboard for the current day",
+			Options:     []*discordgo.ApplicationCommandOption{leaderboardModeOption},
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "week",
 			Description: "View the leaderboard for the current week",
+			Options:     []*discordgo.ApplicationCommandOption{leaderboardModeOption},
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "month",
 			Description: "View the leaderboard for the current month",
+			Options:     []*discordgo.ApplicationCommandOption{leaderboardModeOption},
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "year",
 			Description: "View the leaderboard for the current year",
+			Options:     []*discordgo.ApplicationCommandOption{leaderboardModeOption},
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Name:        "all",
 			Description: "View the leaderboard for all time",
+			Options:     []*discordgo.ApplicationCommandOption{leaderboardModeOption},
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
@@ -54,33 +71,51 @@ var LeaderboardCommandData = &discordgo.ApplicationCommand{
 			Description: "View the leaderboard over a custom time period",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "start",
-					Description: "The start date",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "start",
+					Description:  "The start date, " + dateOptionDescription,
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "end",
-					Description: "The end date",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "end",
+					Description:  "The end date, " + dateOptionDescription,
+					Required:     true,
+					Autocomplete: true,
 				},
+				leaderboardModeOption,
 			},
 		},
 	},
 }
 
 type LeaderboardCommand struct {
-	r *activities.ActivityRepository
-	u *users.UserRepository
-	g *guilds.GuildRepository
+	r  *activities.ActivityRepository
+	u  *users.UserRepository
+	g  *guilds.GuildRepository
+	ts *users.UserTimeService
+	sc *scoring.ScoringService
 }
 
-func NewLeaderboardCommand(r *activities.ActivityRepository, u *users.UserRepository, g *guilds.GuildRepository) *LeaderboardCommand {
-	return &LeaderboardCommand{r: r, u: u, g: g}
+func NewLeaderboardCommand(
+	r *activities.ActivityRepository,
+	u *users.UserRepository,
+	g *guilds.GuildRepository,
+	ts *users.UserTimeService,
+	sc *scoring.ScoringService,
+) *LeaderboardCommand {
+	return &LeaderboardCommand{r: r, u: u, g: g, ts: ts, sc: sc}
 }
 
 func (c *LeaderboardCommand) Handle(ctx *bot.InteractionContext) error {
+	if ctx.IsAutocomplete() {
+		if focused := focusedSubcommandOption(ctx); isDateOption(focused) {
+			return respondDateAutocomplete(ctx, c.ts, focused)
+		}
+		return nil
+	}
+
 	if err := ctx.DeferResponse(); err != nil {
 		return err
 	}
@@ -116,31 +151,10 @@ func (c *LeaderboardCommand) Handle(ctx *bot.InteractionContext) error {
 		end = now.EndOfYear().ToStdTime()
 	case "all":
 		start = time.Unix(0, 0)
-		end = time.Now()
+		// Members' local dates may be ahead of UTC
+		end = now.AddDay().ToStdTime()
 	case "custom":
 		options := subcommand.Options
-		user, err := c.u.FindByID(ctx.Context(), i.Member.User.ID)
-		guildID := i.GuildID
-
-		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-			return err
-		}
-
-		timezone := carbon.UTC
-
-		if user != nil && user.Timezone != nil {
-			timezone = *user.Timezone
-		} else if guildID != "" {
-			guild, err := c.g.FindByID(ctx.ResponseContext(), guildID)
-			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-				return err
-			}
-
-			if guild != nil && guild.Timezone != nil {
-				timezone = *guild.Timezone
-			}
-		}
-
 		startString, err := discordutil.GetRequiredStringOption(options, "start")
 		if err != nil {
 			return err
@@ -149,11 +163,17 @@ func (c *LeaderboardCommand) Handle(ctx *bot.InteractionContext) error {
 		if err != nil {
 			return err
 		}
-		carbonStart := carbon.SetTimezone(timezone).Parse(startString)
-		carbonEnd := carbon.SetTimezone(timezone).Parse(endString)
 
-		validStart := carbonStart.IsValid()
-		validEnd := carbonEnd.IsValid()
+		userNow, err := c.ts.GetTime(ctx.Context(), ctx.User().ID, i.GuildID)
+		if err != nil {
+			return err
+		}
+
+		customStart, startErr := timeparse.ParseDate(startString, userNow)
+		customEnd, endErr := timeparse.ParseDateEnd(endString, userNow)
+
+		validStart := startErr == nil
+		validEnd := endErr == nil
 		errorMsg := ""
 
 		if !validStart && !validEnd {
@@ -172,17 +192,25 @@ func (c *LeaderboardCommand) Handle(ctx *bot.InteractionContext) error {
 			return err
 		}
 
-		if carbonEnd.Lt(carbonStart) {
-			start = carbonEnd.ToStdTime()
-			end = carbonStart.ToStdTime()
+		if customEnd.Before(customStart) {
+			start = customEnd
+			end = customStart
 		} else {
-			start = carbonStart.ToStdTime()
-			end = carbonEnd.ToStdTime()
+			start = customStart
+			end = customEnd
 		}
 	}
 
+	byPoints := discordutil.GetStringOptionOrDefault(subcommand.Options, "mode", "duration") == "points"
+
 	// Note: Do not go over 100 members as Discord will not allow fetching 100+ in a single chunk
-	topMembers, err := c.r.GetTopMembers(ctx.Context(), i.GuildID, 10, start, end)
+	var topMembers []*activities.MemberStats
+	var err error
+	if byPoints {
+		topMembers, err = c.sc.GetTopMembers(ctx.Context(), i.GuildID, 10, start, end)
+	} else {
+		topMembers, err = c.r.GetTopMembers(ctx.Context(), i.GuildID, 10, start, end)
+	}
 	if err != nil {
 		return err
 	}
@@ -268,7 +296,12 @@ func (c *LeaderboardCommand) Handle(ctx *bot.InteractionContext) error {
 		}
 
 		title := fmt.Sprintf("%d. %s", x+1, displayName)
-		value := m.TotalDuration.Truncate(time.Second).String()
+		var value string
+		if byPoints {
+			value = fmt.Sprintf("%.0f points", m.TotalPoints)
+		} else {
+			value = m.TotalDuration.Truncate(time.Second).String()
+		}
 
 		embed.AddField(title, value, false)
 	}
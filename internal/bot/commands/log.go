@@ -23,14 +23,18 @@ import (
 	"github.com/xoltia/botsu/internal/mediadata"
 	"github.com/xoltia/botsu/internal/users"
 	"github.com/xoltia/botsu/internal/videos"
+	"github.com/xoltia/botsu/internal/works"
 	"github.com/xoltia/botsu/pkg/discordutil"
 	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
 )
 
 var (
 	errInvalidMediaAutocompleteInput = errors.New("invalid media autocomplete input")
 )
 
+const invalidDateMessage = "Invalid date provided. Try something like `yesterday 21:00`, `-2h` or `2024-05-01`."
+
 var manualCommandOptions = []*discordgo.ApplicationCommandOption{
 	{
 		Name:        "name",
@@ -56,19 +60,20 @@ var manualCommandOptions = []*discordgo.ApplicationCommandOption{
 		},
 	},
 	{
-		Name:        "duration",
-		Type:        discordgo.ApplicationCommandOptionInteger,
-		Description: "Duration spent on the activity",
-		MinValue:    ref.New(0.0),
-		Required:    true,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "duration",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Duration spent on the activity, " + durationOptionDescription,
+		Required:     true,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "date",
-		Type:        discordgo.ApplicationCommandOptionString,
-		Description: "Date of activity completion (default is current time)",
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "date",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Date of activity completion (default now), " + dateOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 	{
 		Name:        "media-type",
@@ -109,26 +114,20 @@ var videoCommandOptions = []*discordgo.ApplicationCommandOption{
 		Required:    true,
 	},
 	{
-		Name:        "date",
-		Type:        discordgo.ApplicationCommandOptionString,
-		Description: "Date of activity completion (default is current time)",
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
-	},
-	{
-		Name:        "duration",
-		Type:        discordgo.ApplicationCommandOptionInteger,
-		Description: "Duration spent on the activity",
-		MinValue:    ref.New(0.0),
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "date",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Date of activity completion (default now), " + dateOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "complex-duration",
-		Type:        discordgo.ApplicationCommandOptionString,
-		Description: "Duration spent on the activity",
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "duration",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Duration watched (default whole video), e.g. 20m, 1:30:00, or a range like 5m:20m",
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 }
 
@@ -150,12 +149,12 @@ var vnCommandOptions = []*discordgo.ApplicationCommandOption{
 		Options:     []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "duration",
-		Type:        discordgo.ApplicationCommandOptionInteger,
-		Description: "How long it took to read (mins, overrides reading-speed)",
-		MinValue:    ref.New(0.0),
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "duration",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Reading time (overrides speed), " + durationOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 	{
 		Name:        "reading-speed",
@@ -174,11 +173,12 @@ var vnCommandOptions = []*discordgo.ApplicationCommandOption{
 		Options:     []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "date",
-		Type:        discordgo.ApplicationCommandOptionString,
-		Description: "Date of activity completion (default is current time)",
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "date",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Date of activity completion (default now), " + dateOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 }
 
@@ -199,19 +199,20 @@ var bookCommandOptions = []*discordgo.ApplicationCommandOption{
 		Options:     []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "duration",
-		Type:        discordgo.ApplicationCommandOptionInteger,
-		Description: "How long it took to read (mins, overrides reading-speed)",
-		MinValue:    ref.New(0.0),
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "duration",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Reading time (overrides speed), " + durationOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "date",
-		Type:        discordgo.ApplicationCommandOptionString,
-		Description: "Date of activity completion (default is current time)",
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "date",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Date of activity completion (default now), " + dateOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 }
 
@@ -233,19 +234,20 @@ var animeCommandOptions = []*discordgo.ApplicationCommandOption{
 		Options:     []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "episode-duration",
-		Type:        discordgo.ApplicationCommandOptionInteger,
-		Description: "Duration of each episode (mins, default 24)",
-		MinValue:    ref.New(0.0),
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "episode-duration",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Duration of each episode (default 24m), " + durationOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 	{
-		Name:        "date",
-		Type:        discordgo.ApplicationCommandOptionString,
-		Description: "Date of activity completion (default is current time)",
-		Required:    false,
-		Options:     []*discordgo.ApplicationCommandOption{},
+		Name:         "date",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Description:  "Date of activity completion (default now), " + dateOptionDescription,
+		Required:     false,
+		Autocomplete: true,
+		Options:      []*discordgo.ApplicationCommandOption{},
 	},
 }
 
@@ -289,6 +291,12 @@ var LogCommandData = &discordgo.ApplicationCommand{
 			Type:        discordgo.ApplicationCommandOptionSubCommand,
 			Options:     animeCommandOptions,
 		},
+		{
+			Name:        "bulk",
+			Description: "Log many activities at once from a text file",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options:     bulkCommandOptions,
+		},
 	},
 }
 
@@ -299,6 +307,7 @@ type LogCommand struct {
 	mediaSearcher *mediadata.MediaSearcher
 	goalService   *goals.GoalService
 	timeService   *users.UserTimeService
+	workService   *works.WorkService
 }
 
 func NewLogCommand(
@@ -308,6 +317,7 @@ func NewLogCommand(
 	ms *mediadata.MediaSearcher,
 	gs *goals.GoalService,
 	ts *users.UserTimeService,
+	ws *works.WorkService,
 ) *LogCommand {
 	return &LogCommand{
 		activityRepo:  ar,
@@ -316,11 +326,18 @@ func NewLogCommand(
 		guildRepo:     gr,
 		goalService:   gs,
 		timeService:   ts,
+		workService:   ws,
 	}
 }
 
 func (c *LogCommand) Handle(ctx *bot.InteractionContext) error {
 	if ctx.IsAutocomplete() {
+		focused := focusedSubcommandOption(ctx)
+		if isDateOption(focused) {
+			return respondDateAutocomplete(ctx, c.timeService, focused)
+		} else if isDurationOption(focused) {
+			return respondDurationAutocomplete(ctx, focused)
+		}
 		return c.handleAutocomplete(ctx.ResponseContext(), ctx.Session(), ctx.Interaction())
 	}
 
@@ -343,6 +360,8 @@ func (c *LogCommand) Handle(ctx *bot.InteractionContext) error {
 		return c.handleBook(ctx, subcommand)
 	case "anime":
 		return c.handleAnime(ctx, subcommand)
+	case "bulk":
+		return c.handleBulk(ctx, subcommand)
 	default:
 		return errors.New("invalid subcommand")
 	}
@@ -353,15 +372,71 @@ func (c *LogCommand) checkGoals(cmd *bot.InteractionContext, a *activities.Activ
 	if err != nil {
 		return err
 	}
+
+	return c.reportGoals(cmd, completedGoals, a)
+}
+
+// checkBulkGoals is checkGoals for bulk logs, which only count towards the
+// current period of goals.
+func (c *LogCommand) checkBulkGoals(cmd *bot.InteractionContext, as []*activities.Activity) error {
+	completedGoals, err := c.goalService.CheckCompletedMany(cmd.Context(), cmd.User().ID, as)
+	if err != nil {
+		return err
+	}
+
+	return c.reportGoals(cmd, completedGoals, as...)
+}
+
+// reportGoals announces the user's goals which newly logged activities
+// completed.
+func (c *LogCommand) reportGoals(cmd *bot.InteractionContext, completedGoals []*goals.Goal, as ...*activities.Activity) error {
 	if len(completedGoals) == 0 {
 		return nil
 	}
 
+	embed := newGoalsCompletedEmbed(completedGoals)
+
+	if len(as) == 1 {
+		embed.SetFooter(fmt.Sprintf("Activity ID: %d", as[0].ID), "")
+	}
+
+	_, err := cmd.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+	}, false)
+
+	return err
+}
+
+// logActivity creates an activity, adding its progress to the work it
+// belongs to in the user's library. The returned work is nil for activities
+// which aren't tracked as works.
+func (c *LogCommand) logActivity(cmd *bot.InteractionContext, a *activities.Activity) (*works.Work, error) {
+	ws, err := c.workService.Log(cmd.Context(), a)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws[0], nil
+}
+
+func addWorkField(embed *discordutil.EmbedBuilder, w *works.Work) {
+	if w == nil {
+		return
+	}
+
+	progress := w.DescribeProgress()
+	if w.Status == works.StatusCompleted {
+		progress += " (completed)"
+	}
+
+	embed.AddField("Progress", progress, false)
+}
+
+func newGoalsCompletedEmbed(completedGoals []*goals.Goal) *discordutil.EmbedBuilder {
 	embed := discordutil.NewEmbedBuilder().
 		SetTitle("Goals completed!").
 		SetColor(discordutil.ColorSuccess).
 		SetTimestamp(time.Now()).
-		SetFooter(fmt.Sprintf("Activity ID: %d", a.ID), "").
 		SetDescription("You have completed the following goals:")
 
 	for i, g := range completedGoals {
@@ -370,14 +445,14 @@ func (c *LogCommand) checkGoals(cmd *bot.InteractionContext, a *activities.Activ
 			break
 		}
 
-		embed.AddField(g.Name, fmt.Sprintf("Target: %s\nCompleted: %s", g.Target.String(), g.Current.String()), false)
+		embed.AddField(g.Name, fmt.Sprintf(
+			"Target: %s\nCompleted: %s",
+			goals.FormatAmount(g.Unit, g.Target),
+			goals.FormatAmount(g.Unit, g.Current),
+		), false)
 	}
 
-	_, err = cmd.Followup(&discordgo.WebhookParams{
-		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
-	}, false)
-
-	return err
+	return embed
 }
 
 func (c *LogCommand) handleAutocomplete(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
@@ -428,7 +503,7 @@ func (c *LogCommand) handleAnime(ctx *bot.InteractionContext, subcommand *discor
 	var args struct {
 		Name            string `discordopt:"name,required"`
 		Episodes        uint   `discordopt:"episodes,required"`
-		EpisodeDuration uint   `discordopt:"episode-duration"`
+		EpisodeDuration string `discordopt:"episode-duration"`
 		Date            string `discordopt:"date"`
 	}
 
@@ -442,11 +517,16 @@ func (c *LogCommand) handleAnime(ctx *bot.InteractionContext, subcommand *discor
 		activity.GuildID = &guildID
 	}
 
-	episodeDuration := args.EpisodeDuration
+	episodeDuration, err := parseDurationOption(args.EpisodeDuration)
+	if err != nil {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: invalidDurationMessage,
+		}, false)
+		return err
+	}
 	if episodeDuration == 0 {
-		episodeDuration = 24
+		episodeDuration = 24 * time.Minute
 	}
-	duration := episodeDuration * args.Episodes
 
 	thumbnail := ""
 	var namedSources map[string]string
@@ -477,32 +557,35 @@ func (c *LogCommand) handleAnime(ctx *bot.InteractionContext, subcommand *discor
 	}
 
 	activity.SetMeta("episodes", args.Episodes)
-	activity.Duration = time.Duration(duration) * time.Minute
+	activity.Duration = episodeDuration * time.Duration(args.Episodes)
 	activity.PrimaryType = activities.ActivityImmersionTypeListening
 	activity.MediaType = ref.New(activities.ActivityMediaTypeAnime)
 	activity.UserID = userID
 
 	if args.Date != "" {
-		location, err := c.timeService.GetTimeLocation(ctx.Context(), userID, guildID)
-		if err != nil {
-			return err
-		}
-
-		activity.Date, err = time.ParseInLocation(time.DateTime, args.Date, location)
-		if err != nil {
+		date, err := parseUserDate(ctx.Context(), c.timeService, userID, guildID, args.Date)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			_, err = ctx.Followup(&discordgo.WebhookParams{
-				Content: "Invalid date provided.",
+				Content: invalidDateMessage,
 			}, false)
 			return err
+		} else if err != nil {
+			return err
 		}
+
+		activity.Date = date
+	}
+
+	if ok, err := c.checkDuplicate(ctx, activity); !ok || err != nil {
+		return err
 	}
 
-	err = c.activityRepo.Create(ctx.Context(), activity)
+	work, err := c.logActivity(ctx, activity)
 	if err != nil {
 		return err
 	}
 
-	embed := discordutil.NewEmbedBuilder().
+	embedBuilder := discordutil.NewEmbedBuilder().
 		SetTitle("Activity logged!").
 		AddField("Title", activity.Name, false).
 		AddField("Duration", activity.Duration.String(), false).
@@ -510,8 +593,11 @@ func (c *LogCommand) handleAnime(ctx *bot.InteractionContext, subcommand *discor
 		SetFooter(fmt.Sprintf("ID: %d", activity.ID), "").
 		SetThumbnail(thumbnail).
 		SetTimestamp(activity.Date).
-		SetColor(discordutil.ColorSuccess).
-		MessageEmbed
+		SetColor(discordutil.ColorSuccess)
+
+	addWorkField(embedBuilder, work)
+	c.addDailyGoalField(ctx, embedBuilder)
+	embed := embedBuilder.MessageEmbed
 
 	row := discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{},
@@ -550,10 +636,22 @@ func (c *LogCommand) handleBook(ctx *bot.InteractionContext, subcommand *discord
 	var args struct {
 		Name     string `discordopt:"name,required"`
 		Pages    uint   `discordopt:"pages,required"`
-		Duration uint   `discordopt:"duration"`
+		Duration string `discordopt:"duration"`
 		Date     string `discordopt:"date"`
 	}
 
+	if err := discordutil.UnmarshalOptions(subcommand.Options, &args); err != nil {
+		return err
+	}
+
+	duration, err := parseDurationOption(args.Duration)
+	if err != nil {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: invalidDurationMessage,
+		}, false)
+		return err
+	}
+
 	userID := discordutil.GetInteractionUser(ctx.Interaction()).ID
 	guildID := ctx.Interaction().GuildID
 
@@ -571,7 +669,6 @@ func (c *LogCommand) handleBook(ctx *bot.InteractionContext, subcommand *discord
 	activity.UserID = userID
 
 	pageCount := args.Pages
-	duration := args.Duration
 
 	if pageCount == 0 && duration == 0 {
 		_, err := ctx.Followup(&discordgo.WebhookParams{
@@ -584,7 +681,7 @@ func (c *LogCommand) handleBook(ctx *bot.InteractionContext, subcommand *discord
 
 	if duration != 0 && pageCount != 0 {
 		// if both duration and page count is provided
-		durationMinutes = float64(duration)
+		durationMinutes = duration.Minutes()
 		activity.SetMeta("pages", pageCount)
 		activity.SetMeta("speed", float64(pageCount)/(durationMinutes))
 	} else if pageCount != 0 {
@@ -593,28 +690,32 @@ func (c *LogCommand) handleBook(ctx *bot.InteractionContext, subcommand *discord
 		activity.SetMeta("pages", pageCount)
 	} else {
 		// if only duration is provided
-		durationMinutes = float64(duration)
+		durationMinutes = duration.Minutes()
 	}
 
 	// because time.Duration casts to uint64, we need to convert to seconds first
 	activity.Duration = time.Duration(durationMinutes*60.0) * time.Second
 
 	if args.Date != "" {
-		location, err := c.timeService.GetTimeLocation(ctx.Context(), userID, guildID)
-		if err != nil {
-			return err
-		}
-
-		activity.Date, err = time.ParseInLocation(time.DateTime, args.Date, location)
-		if err != nil {
+		date, err := parseUserDate(ctx.Context(), c.timeService, userID, guildID, args.Date)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			_, err = ctx.Followup(&discordgo.WebhookParams{
-				Content: "Invalid date provided.",
+				Content: invalidDateMessage,
 			}, false)
 			return err
+		} else if err != nil {
+			return err
 		}
+
+		activity.Date = date
+	}
+
+	if ok, err := c.checkDuplicate(ctx, activity); !ok || err != nil {
+		return err
 	}
 
-	if err := c.activityRepo.Create(ctx.Context(), activity); err != nil {
+	work, err := c.logActivity(ctx, activity)
+	if err != nil {
 		return err
 	}
 
@@ -630,7 +731,10 @@ func (c *LogCommand) handleBook(ctx *bot.InteractionContext, subcommand *discord
 		embed.AddField("Pages Read", fmt.Sprintf("%d", pageCount), false)
 	}
 
-	_, err := ctx.Followup(&discordgo.WebhookParams{
+	addWorkField(embed, work)
+	c.addDailyGoalField(ctx, embed)
+
+	_, err = ctx.Followup(&discordgo.WebhookParams{
 		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
 	}, false)
 	if err != nil {
@@ -648,7 +752,7 @@ func (c *LogCommand) handleVisualNovel(ctx *bot.InteractionContext, subcommand *
 	var args struct {
 		Name               string `discordopt:"name,required"`
 		Characters         uint   `discordopt:"characters,required"`
-		Duration           uint   `discordopt:"duration"`
+		Duration           string `discordopt:"duration"`
 		ReadingSpeed       uint   `discordopt:"reading-speed"`
 		ReadingSpeedHourly uint   `discordopt:"reading-speed-hourly"`
 		Date               string `discordopt:"date"`
@@ -672,7 +776,13 @@ func (c *LogCommand) handleVisualNovel(ctx *bot.InteractionContext, subcommand *
 	activity.UserID = userID
 
 	charCount := args.Characters
-	duration := args.Duration
+	duration, err := parseDurationOption(args.Duration)
+	if err != nil {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: invalidDurationMessage,
+		}, false)
+		return err
+	}
 	readingSpeed := args.ReadingSpeed
 	readingSpeedHourly := args.ReadingSpeedHourly
 
@@ -729,7 +839,7 @@ func (c *LogCommand) handleVisualNovel(ctx *bot.InteractionContext, subcommand *
 
 	speedIsKnown := true
 	if duration > 0 {
-		durationMinutes = float64(duration)
+		durationMinutes = duration.Minutes()
 	} else if readingSpeed > 0 {
 		durationMinutes = float64(charCount) / float64(readingSpeed)
 	} else if readingSpeedHourly > 0 {
@@ -746,21 +856,24 @@ func (c *LogCommand) handleVisualNovel(ctx *bot.InteractionContext, subcommand *
 	// because time.Duration casts to uint64, we need to convert to seconds first
 	activity.Duration = time.Duration(durationMinutes*60.0) * time.Second
 	if args.Date != "" {
-		location, err := c.timeService.GetTimeLocation(ctx.Context(), userID, guildID)
-		if err != nil {
-			return err
-		}
-
-		activity.Date, err = time.ParseInLocation(time.DateTime, args.Date, location)
-		if err != nil {
+		date, err := parseUserDate(ctx.Context(), c.timeService, userID, guildID, args.Date)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			_, err = ctx.Followup(&discordgo.WebhookParams{
-				Content: "Invalid date provided.",
+				Content: invalidDateMessage,
 			}, false)
 			return err
+		} else if err != nil {
+			return err
 		}
+
+		activity.Date = date
 	}
 
-	err = c.activityRepo.Create(ctx.Context(), activity)
+	if ok, err := c.checkDuplicate(ctx, activity); !ok || err != nil {
+		return err
+	}
+
+	work, err := c.logActivity(ctx, activity)
 	if err != nil {
 		return err
 	}
@@ -778,6 +891,9 @@ func (c *LogCommand) handleVisualNovel(ctx *bot.InteractionContext, subcommand *
 		embed.AddField("Characters Read", fmt.Sprintf("%d", charCount), false)
 	}
 
+	addWorkField(embed, work)
+	c.addDailyGoalField(ctx, embed)
+
 	_, err = ctx.Followup(&discordgo.WebhookParams{
 		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
 		Files:  attachments,
@@ -796,10 +912,9 @@ func (c *LogCommand) handleVideo(ctx *bot.InteractionContext, subcommand *discor
 	}
 
 	var args struct {
-		URL             string `discordopt:"url,required"`
-		Duration        uint   `discordopt:"duration"`
-		ComplexDuration string `discordopt:"complex-duration"`
-		Date            string `discordopt:"date"`
+		URL      string `discordopt:"url,required"`
+		Duration string `discordopt:"duration"`
+		Date     string `discordopt:"date"`
 	}
 
 	err := discordutil.UnmarshalOptions(subcommand.Options, &args)
@@ -834,25 +949,24 @@ func (c *LogCommand) handleVideo(ctx *bot.InteractionContext, subcommand *discor
 	}
 
 	if args.Date != "" {
-		location, err := c.timeService.GetTimeLocation(ctx.Context(), userID, guildID)
-		if err != nil {
-			return err
-		}
-
-		activity.Date, err = time.ParseInLocation(time.DateTime, args.Date, location)
-		if err != nil {
+		date, err := parseUserDate(ctx.Context(), c.timeService, userID, guildID, args.Date)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			_, err = ctx.Followup(&discordgo.WebhookParams{
-				Content: "Invalid date provided.",
+				Content: invalidDateMessage,
 			}, false)
 			return err
+		} else if err != nil {
+			return err
 		}
+
+		activity.Date = date
 	}
 
 	activity.Duration = video.Duration
-	if args.Duration > 0 {
-		activity.Duration = time.Duration(args.Duration) * time.Minute
-	}
-	if args.ComplexDuration != "" {
+	// Anything other than a plain duration is a range within the video
+	if d, err := timeparse.ParseDuration(args.Duration); err == nil {
+		activity.Duration = d
+	} else if args.Duration != "" {
 		var (
 			lowerDuration time.Duration
 			tDuration     time.Duration
@@ -862,7 +976,7 @@ func (c *LogCommand) handleVideo(ctx *bot.InteractionContext, subcommand *discor
 			tDuration = time.Second * time.Duration(tSeconds)
 		}
 
-		lowerDuration, err = c.activityRepo.GetTotalWatchTimeOfVideoByUserID(ctx.Context(), userID, video.Platform, video.ID)
+		lowerDuration, err = c.activityRepo.GetTotalWatchTimeOfVideoByUserID(ctx.Context(), userID, video.Platform, video.ID, time.Time{})
 		if err != nil {
 			return err
 		}
@@ -873,7 +987,7 @@ func (c *LogCommand) handleVideo(ctx *bot.InteractionContext, subcommand *discor
 			"_": lowerDuration,
 		}
 
-		activity.Duration, err = parseDurationComplex(args.ComplexDuration, video.Duration, vars)
+		activity.Duration, err = parseDurationComplex(args.Duration, video.Duration, vars)
 		if err != nil {
 			_, err = ctx.Followup(&discordgo.WebhookParams{
 				Content: fmt.Sprintf("Invalid duration provided: %s", err.Error()),
@@ -889,8 +1003,11 @@ func (c *LogCommand) handleVideo(ctx *bot.InteractionContext, subcommand *discor
 		}
 	}
 
-	err = c.activityRepo.Create(ctx.Context(), activity)
-	if err != nil {
+	if ok, err := c.checkDuplicateVideo(ctx, guildID, video, activity); !ok || err != nil {
+		return err
+	}
+
+	if _, err = c.logActivity(ctx, activity); err != nil {
 		return err
 	}
 
@@ -909,6 +1026,8 @@ func (c *LogCommand) handleVideo(ctx *bot.InteractionContext, subcommand *discor
 		SetTimestamp(activity.Date).
 		SetColor(discordutil.ColorSuccess)
 
+	c.addDailyGoalField(ctx, embed)
+
 	row := discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			discordgo.Button{
@@ -955,7 +1074,7 @@ func (c *LogCommand) handleManual(ctx *bot.InteractionContext, subcommand *disco
 	var args struct {
 		Name      string  `discordopt:"name,required"`
 		Type      string  `discordopt:"type,required"`
-		Duration  uint    `discordopt:"duration,required"`
+		Duration  string  `discordopt:"duration,required"`
 		MediaType *string `discordopt:"media-type"`
 		Date      string  `discordopt:"date"`
 	}
@@ -969,7 +1088,12 @@ func (c *LogCommand) handleManual(ctx *bot.InteractionContext, subcommand *disco
 	activity := activities.NewActivity()
 	activity.Name = args.Name
 	activity.PrimaryType = args.Type
-	activity.Duration = time.Duration(args.Duration) * time.Minute
+	activity.Duration, err = timeparse.ParseDuration(args.Duration)
+	if err != nil {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: invalidDurationMessage,
+		})
+	}
 	activity.MediaType = args.MediaType
 	activity.UserID = userID
 	activity.Date = time.Now()
@@ -978,20 +1102,23 @@ func (c *LogCommand) handleManual(ctx *bot.InteractionContext, subcommand *disco
 	}
 
 	if args.Date != "" {
-		location, err := c.timeService.GetTimeLocation(ctx.Context(), userID, guildID)
-		if err != nil {
-			return err
-		}
-
-		activity.Date, err = time.ParseInLocation(time.DateTime, args.Date, location)
-		if err != nil {
+		date, err := parseUserDate(ctx.Context(), c.timeService, userID, guildID, args.Date)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
 			return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
-				Content: "Invalid date provided.",
+				Content: invalidDateMessage,
 			})
+		} else if err != nil {
+			return err
 		}
+
+		activity.Date = date
+	}
+
+	if ok, err := c.checkDuplicate(ctx, activity); !ok || err != nil {
+		return err
 	}
 
-	err = c.activityRepo.Create(ctx.Context(), activity)
+	err = c.workService.LogUntracked(ctx.Context(), activity)
 	if err != nil {
 		return err
 	}
@@ -1002,12 +1129,13 @@ func (c *LogCommand) handleManual(ctx *bot.InteractionContext, subcommand *disco
 		AddField("Duration", activity.Duration.String(), false).
 		SetFooter(fmt.Sprintf("ID: %d", activity.ID), "").
 		SetTimestamp(activity.Date).
-		SetColor(discordutil.ColorSuccess).
-		MessageEmbed
+		SetColor(discordutil.ColorSuccess)
 
-	err = ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
-		Embeds: []*discordgo.MessageEmbed{embed},
-	})
+	c.addDailyGoalField(ctx, embed)
+
+	_, err = ctx.RespondOrFollowup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+	}, false)
 	if err != nil {
 		return err
 	}
@@ -1141,8 +1269,13 @@ func parseDurationComplex(str string, upper time.Duration, vars map[string]time.
 		}
 
 		switch durationString[0] {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '-', '+', '.':
-			d, err = time.ParseDuration(durationString)
+		case '-':
+			d, err = timeparse.ParseDuration(durationString[1:])
+			d = -d
+		case '+':
+			d, err = timeparse.ParseDuration(durationString[1:])
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
+			d, err = timeparse.ParseDuration(durationString)
 		default:
 			var ok bool
 			if d, ok = vars[durationString]; !ok {
@@ -0,0 +1,343 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/bulklog"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+const (
+	maxBulkEntries        = 50
+	maxBulkAttachmentSize = 64 * 1024
+)
+
+var bulkCommandOptions = []*discordgo.ApplicationCommandOption{
+	{
+		Name:        "file",
+		Type:        discordgo.ApplicationCommandOptionAttachment,
+		Description: "Text file with one activity per line, e.g. 2024-05-01 anime \"Frieren\" 3ep",
+		Required:    true,
+	},
+}
+
+func (c *LogCommand) handleBulk(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	if err := ctx.DeferResponse(); err != nil {
+		return err
+	}
+
+	attachmentOption, err := discordutil.GetRequiredOption(subcommand.Options, "file")
+	if err != nil {
+		return err
+	}
+
+	attachmentID, ok := attachmentOption.Value.(string)
+	if !ok {
+		return errors.New("expected string value from attachment option")
+	}
+
+	attachment := ctx.Data().Resolved.Attachments[attachmentID]
+	if attachment.Size > maxBulkAttachmentSize {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf("File is too large, the maximum size is %d KiB.", maxBulkAttachmentSize/1024),
+		}, false)
+		return err
+	}
+
+	input, err := downloadAttachment(ctx.Context(), attachment.URL, maxBulkAttachmentSize)
+	if err != nil {
+		return err
+	}
+
+	if !utf8.Valid(input) {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: "File must be UTF-8 encoded text.",
+		}, false)
+		return err
+	}
+
+	userID := ctx.User().ID
+	guildID := ctx.Interaction().GuildID
+
+	now, err := c.timeService.GetTime(ctx.Context(), userID, guildID)
+	if err != nil {
+		return err
+	}
+
+	entries := bulklog.Parse(string(input), now)
+	if len(entries) == 0 {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: "No entries found in file.",
+		}, false)
+		return err
+	}
+
+	if len(entries) > maxBulkEntries {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: fmt.Sprintf("Too many entries, at most %d can be logged at once.", maxBulkEntries),
+		}, false)
+		return err
+	}
+
+	as := make([]*activities.Activity, 0, len(entries))
+	for _, e := range entries {
+		if e.Err != nil {
+			continue
+		}
+
+		a := e.Activity()
+		a.UserID = userID
+		if guildID != "" {
+			a.GuildID = &guildID
+		}
+
+		if e.Err = c.resolveBulkMedia(a); e.Err != nil {
+			continue
+		}
+
+		if e.Err = activities.ValidateExternalActivity(a); e.Err != nil {
+			continue
+		}
+
+		as = append(as, a)
+	}
+
+	embed := newBulkPreviewEmbed(entries, as)
+
+	if len(as) == 0 {
+		embed.SetTitle("No valid entries").SetColor(discordutil.ColorDanger)
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+		}, false)
+		return err
+	}
+
+	row := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    fmt.Sprintf("Log %d activities", len(as)),
+				Style:    discordgo.SuccessButton,
+				CustomID: "bulk_confirm",
+			},
+			discordgo.Button{
+				Label:    "Cancel",
+				Style:    discordgo.SecondaryButton,
+				CustomID: "bulk_cancel",
+			},
+		},
+	}
+
+	msg, err := ctx.Followup(&discordgo.WebhookParams{
+		Embeds:     []*discordgo.MessageEmbed{embed.MessageEmbed},
+		Components: []discordgo.MessageComponent{row},
+	}, true)
+	if err != nil {
+		return err
+	}
+
+	collectionContext, cancel := context.WithTimeout(ctx.Context(), 2*time.Minute)
+	defer cancel()
+
+	ci, err := ctx.Bot.CollectSingleComponentInteraction(
+		collectionContext,
+		msg,
+		discordutil.NewInteractionUserFilter(ctx.Interaction()),
+	)
+
+	if err != nil {
+		_, err = ctx.Session().FollowupMessageEdit(ctx.Interaction().Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content:    ref.New("Timed out."),
+			Components: &[]discordgo.MessageComponent{},
+			Embeds:     &[]*discordgo.MessageEmbed{},
+		})
+		return err
+	}
+
+	switch ci.MessageComponentData().CustomID {
+	case "bulk_confirm":
+		if _, err = c.workService.Log(ctx.Context(), as...); err != nil {
+			return err
+		}
+
+		embed.
+			SetTitle("Activities logged!").
+			SetColor(discordutil.ColorSuccess).
+			SetFooter(fmt.Sprintf("IDs: %d-%d", as[0].ID, as[len(as)-1].ID), "")
+
+		c.addDailyGoalField(ctx, embed)
+
+		err = ctx.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{embed.MessageEmbed},
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.checkBulkGoals(ctx, as)
+	case "bulk_cancel":
+		return ctx.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "Cancelled.",
+				Components: []discordgo.MessageComponent{},
+				Embeds:     []*discordgo.MessageEmbed{},
+			},
+		})
+	default:
+		return errors.New("invalid custom id")
+	}
+}
+
+// resolveBulkMedia replaces autocomplete values such as ${v17} or
+// ${123:jp} in the activity name with the title and metadata of the
+// referenced anime or visual novel.
+func (c *LogCommand) resolveBulkMedia(a *activities.Activity) error {
+	if !isAutocompletedEntry(a.Name) || a.MediaType == nil {
+		return nil
+	}
+
+	input := a.Name
+	if !strings.Contains(input, ":") {
+		input = input[:len(input)-1] + ":primary}"
+	}
+
+	switch *a.MediaType {
+	case activities.ActivityMediaTypeAnime:
+		anime, titleField, err := c.resolveAnimeFromAutocomplete(input)
+		if err != nil {
+			return fmt.Errorf("unknown anime: %s", a.Name)
+		}
+
+		switch titleField {
+		case "jp":
+			a.Name = anime.JapaneseOfficialTitle
+		case "en":
+			a.Name = anime.EnglishOfficialTitle
+		case "x-jat":
+			a.Name = anime.RomajiOfficialTitle
+		default:
+			a.Name = anime.PrimaryTitle
+		}
+
+		a.SetMeta("anidb_id", anime.ID)
+		a.SetMeta("thumbnail", anime.Thumbnail)
+		a.SetMeta("sources", anime.Sources)
+		a.SetMeta("title", anime.PrimaryTitle)
+		a.SetMeta("tags", anime.Tags)
+	case activities.ActivityMediaTypeVisualNovel:
+		v, titleField, err := c.resolveVNFromAutocomplete(input)
+		if err != nil {
+			return fmt.Errorf("unknown visual novel: %s", a.Name)
+		}
+
+		switch titleField {
+		case "en":
+			a.Name = v.EnglishTitle
+		case "romaji":
+			a.Name = v.RomajiTitle
+		default:
+			a.Name = v.JapaneseTitle
+		}
+
+		if a.Name == "" {
+			a.Name = v.RomajiTitle
+		}
+
+		a.SetMeta("vndb_id", v.ID)
+		a.SetMeta("thumbnail", v.ImageURL())
+	}
+
+	return nil
+}
+
+func newBulkPreviewEmbed(entries []*bulklog.Entry, as []*activities.Activity) *discordutil.EmbedBuilder {
+	var (
+		table  strings.Builder
+		errs   strings.Builder
+		total  time.Duration
+		failed int
+	)
+
+	table.WriteString("```\n")
+
+	i := 0
+	for _, e := range entries {
+		if e.Err != nil {
+			failed++
+			if failed <= 10 {
+				fmt.Fprintf(&errs, "Line %d: %s\n", e.Line, e.Err)
+			}
+			continue
+		}
+
+		a := as[i]
+		i++
+		total += a.Duration
+
+		fmt.Fprintf(
+			&table,
+			"%3d %s %-9s %8s %s\n",
+			e.Line,
+			a.Date.Format("01/02 15:04"),
+			e.Kind,
+			a.Duration.Round(time.Minute).String(),
+			truncateLongString(a.Name, 24),
+		)
+	}
+
+	table.WriteString("```")
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Confirm bulk log").
+		SetColor(discordutil.ColorWarning).
+		SetTimestamp(time.Now())
+
+	if len(as) > 0 {
+		embed.SetDescription(table.String())
+		embed.AddField("Total", fmt.Sprintf("%d activities, %s", len(as), total.Round(time.Minute).String()), false)
+	}
+
+	if failed > 0 {
+		if failed > 10 {
+			fmt.Fprintf(&errs, "...and %d more", failed-10)
+		}
+		embed.AddField(fmt.Sprintf("Skipped %d invalid lines", failed), errs.String(), false)
+	}
+
+	return embed
+}
+
+// downloadAttachment reads at most limit bytes of an attachment.
+func downloadAttachment(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code downloading attachment: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
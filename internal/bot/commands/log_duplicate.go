@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/guilds"
+	"github.com/xoltia/botsu/internal/videos"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+// Longest activity expected when looking for activities overlapping a new one.
+const maxDuplicateOverlap = 24 * time.Hour
+
+func (c *LogCommand) duplicateWindow(ctx context.Context, guildID string) (time.Duration, error) {
+	if guildID == "" {
+		return guilds.DefaultDuplicateWindow, nil
+	}
+
+	guild, err := c.guildRepo.FindByID(ctx, guildID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return guilds.DefaultDuplicateWindow, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return guild.GetDuplicateWindow(), nil
+}
+
+// checkDuplicate looks for an activity a is likely a duplicate of and, if
+// one is found, asks the user whether to log a anyway.
+func (c *LogCommand) checkDuplicate(ctx *bot.InteractionContext, a *activities.Activity) (bool, error) {
+	window, err := c.duplicateWindow(ctx.Context(), ctx.Interaction().GuildID)
+	if err != nil || window == 0 {
+		return true, err
+	}
+
+	start := a.Date.Add(-a.Duration - window)
+	end := a.Date.Add(window + maxDuplicateOverlap)
+	filter := &activities.ActivityFilter{Start: &start, End: &end}
+
+	var existing []*activities.Activity
+	err = c.activityRepo.StreamByUserID(ctx.Context(), a.UserID, filter, func(e *activities.Activity) error {
+		existing = append(existing, e)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	duplicate := activities.FindLikelyDuplicate(a, existing, window)
+	if duplicate == nil {
+		return true, nil
+	}
+
+	return c.confirmDuplicate(ctx, fmt.Sprintf(
+		"You already logged **%s** (%s) <t:%d:R> with ID %d.",
+		duplicate.Name,
+		duplicate.Duration,
+		duplicate.Date.Unix(),
+		duplicate.ID,
+	))
+}
+
+// checkDuplicateVideo asks the user whether to log a video anyway if the time
+// logged for it within the duplicate window leaves less than the duration of
+// a unwatched.
+func (c *LogCommand) checkDuplicateVideo(ctx *bot.InteractionContext, guildID string, video *videos.VideoInfo, a *activities.Activity) (bool, error) {
+	window, err := c.duplicateWindow(ctx.Context(), guildID)
+	if err != nil || window == 0 {
+		return true, err
+	}
+
+	// Rewatching a video after the window isn't a duplicate
+	since := a.Date.Add(-a.Duration - window)
+	watched, err := c.activityRepo.GetTotalWatchTimeOfVideoByUserID(ctx.Context(), a.UserID, video.Platform, video.ID, since)
+	if err != nil {
+		return false, err
+	}
+
+	// Allow a minute for rounding of manually entered durations
+	if watched == 0 || watched+a.Duration <= video.Duration+time.Minute {
+		return true, nil
+	}
+
+	return c.confirmDuplicate(ctx, fmt.Sprintf(
+		"You recently logged %s of **%s**, which is %s long.",
+		watched,
+		video.Title,
+		video.Duration,
+	))
+}
+
+// confirmDuplicate privately asks the user whether to log an activity which
+// looks like a duplicate. A deferred response is removed for the prompt, so
+// the logged activity is sent as a new message.
+func (c *LogCommand) confirmDuplicate(ctx *bot.InteractionContext, description string) (bool, error) {
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Possible duplicate").
+		SetDescription(description + "\nDo you want to log it anyway?").
+		SetColor(discordutil.ColorWarning).
+		MessageEmbed
+
+	row := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Log anyway",
+				Style:    discordgo.PrimaryButton,
+				CustomID: "log_duplicate_confirm",
+			},
+			discordgo.Button{
+				Label:    "Cancel",
+				Style:    discordgo.SecondaryButton,
+				CustomID: "log_duplicate_cancel",
+			},
+		},
+	}
+
+	var (
+		msg *discordgo.Message
+		err error
+	)
+
+	if ctx.Deferred() {
+		// Followups to a deferred response share its visibility, so the
+		// prompt can only be ephemeral once the response is gone
+		if err = ctx.Session().InteractionResponseDelete(ctx.Interaction().Interaction); err != nil {
+			return false, err
+		}
+
+		msg, err = ctx.Followup(&discordgo.WebhookParams{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{row},
+			Flags:      discordgo.MessageFlagsEphemeral,
+		}, true)
+	} else {
+		err = ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{row},
+			Flags:      discordgo.MessageFlagsEphemeral,
+		})
+		if err == nil {
+			msg, err = ctx.Session().InteractionResponse(ctx.Interaction().Interaction)
+		}
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	collectionContext, cancel := context.WithTimeout(ctx.Context(), time.Minute)
+	defer cancel()
+
+	ci, err := ctx.Bot.CollectSingleComponentInteraction(
+		collectionContext,
+		msg,
+		discordutil.NewInteractionUserFilter(ctx.Interaction()),
+	)
+
+	if err != nil {
+		_, err = ctx.Session().WebhookMessageEdit(
+			ctx.Interaction().AppID,
+			ctx.Interaction().Token,
+			msg.ID,
+			&discordgo.WebhookEdit{
+				Content:    ref.New("Timed out."),
+				Components: &[]discordgo.MessageComponent{},
+				Embeds:     &[]*discordgo.MessageEmbed{},
+			},
+		)
+		return false, err
+	}
+
+	content := "Cancelled."
+	confirmed := ci.MessageComponentData().CustomID == "log_duplicate_confirm"
+	if confirmed {
+		content = "Logging anyway."
+	}
+
+	err = ctx.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+			Embeds:     []*discordgo.MessageEmbed{},
+		},
+	})
+
+	return confirmed, err
+}
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/privacy"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+var PrivacyCommandData = &discordgo.ApplicationCommand{
+	Name:        "privacy",
+	Description: "Export or delete all of your data",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "export",
+			Description: "Download everything stored about you",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "delete-my-data",
+			Description: "Permanently delete everything stored about you",
+		},
+	},
+}
+
+type PrivacyCommand struct {
+	s *privacy.PrivacyService
+}
+
+func NewPrivacyCommand(s *privacy.PrivacyService) *PrivacyCommand {
+	return &PrivacyCommand{s: s}
+}
+
+func (c *PrivacyCommand) Handle(ctx *bot.InteractionContext) error {
+	if len(ctx.Options()) == 0 {
+		return bot.ErrInvalidOptions
+	}
+
+	switch ctx.Options()[0].Name {
+	case "export":
+		return c.handleExport(ctx)
+	case "delete-my-data":
+		return c.handleDelete(ctx)
+	default:
+		return bot.ErrInvalidOptions
+	}
+}
+
+func encodeUserData(data *privacy.UserData) (*bytes.Buffer, error) {
+	buffer := new(bytes.Buffer)
+	compressed := gzip.NewWriter(buffer)
+
+	encoder := json.NewEncoder(compressed)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer, nil
+}
+
+func (c *PrivacyCommand) handleExport(ctx *bot.InteractionContext) error {
+	err := ctx.Respond(discordgo.InteractionResponseDeferredChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Flags: discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		return err
+	}
+
+	userID := ctx.User().ID
+
+	data, err := c.s.Export(ctx.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	buffer, err := encodeUserData(data)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("botsu-data-%s-%s.json.gz", userID, data.ExportedAt.Format(time.RFC3339))
+
+	_, err = ctx.Followup(&discordgo.WebhookParams{
+		Content: "Here is everything stored about you.",
+		Flags:   discordgo.MessageFlagsEphemeral,
+		Files: []*discordgo.File{
+			{
+				Name:        filename,
+				ContentType: "application/gzip",
+				Reader:      buffer,
+			},
+		},
+	}, false)
+
+	return err
+}
+
+func (c *PrivacyCommand) handleDelete(ctx *bot.InteractionContext) error {
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Delete My Data").
+		SetDescription("This will permanently delete all of your activities (including those in the trash), "+
+			"goals (including server goals you created), works, timers, settings and server memberships. "+
+			"**This cannot be undone.**").
+		SetFooter("Use /privacy export first to keep a copy of your data.", "").
+		SetColor(discordutil.ColorDanger).
+		MessageEmbed
+
+	row := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Delete everything",
+				Style:    discordgo.DangerButton,
+				CustomID: "privacy_delete_confirm",
+			},
+			discordgo.Button{
+				Label:    "Cancel",
+				Style:    discordgo.SecondaryButton,
+				CustomID: "privacy_delete_cancel",
+			},
+		},
+	}
+
+	err := ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{row},
+		Flags:      discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := ctx.Session().InteractionResponse(ctx.Interaction().Interaction)
+	if err != nil {
+		return err
+	}
+
+	collectionContext, cancel := context.WithTimeout(ctx.Context(), time.Minute)
+	defer cancel()
+
+	ci, err := ctx.Bot.CollectSingleComponentInteraction(
+		collectionContext,
+		msg,
+		discordutil.NewInteractionUserFilter(ctx.Interaction()),
+	)
+
+	if err != nil {
+		_, err := ctx.EditResponse(&discordgo.WebhookEdit{
+			Content:    ref.New("Timed out."),
+			Components: &[]discordgo.MessageComponent{},
+			Embeds:     &[]*discordgo.MessageEmbed{},
+		})
+
+		return err
+	}
+
+	switch ci.MessageComponentData().CustomID {
+	case "privacy_delete_confirm":
+		// Deleting may take longer than the time to respond to the button
+		err = ctx.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		if err != nil {
+			return err
+		}
+
+		content := "Failed to delete your data. Nothing was deleted, please try again later."
+		report, err := c.s.Delete(ctx.Context(), ctx.User().ID)
+		if err != nil {
+			ctx.Logger.Error("Failed to delete user data", slog.String("err", err.Error()))
+		} else {
+			content = describeDeletionReport(report)
+		}
+
+		_, err = ctx.Session().InteractionResponseEdit(ci.Interaction, &discordgo.WebhookEdit{
+			Content:    &content,
+			Components: &[]discordgo.MessageComponent{},
+			Embeds:     &[]*discordgo.MessageEmbed{},
+		})
+		return err
+	case "privacy_delete_cancel":
+		return ctx.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "Cancelled.",
+				Components: []discordgo.MessageComponent{},
+				Embeds:     []*discordgo.MessageEmbed{},
+			},
+		})
+	default:
+		return errors.New("invalid custom id")
+	}
+}
+
+func describeDeletionReport(r *privacy.DeletionReport) string {
+	if r.IsEmpty() {
+		return "There was no data stored about you."
+	}
+
+	return fmt.Sprintf(
+		"Your data has been deleted:\n"+
+			"- %d activities\n"+
+			"- %d goals\n"+
+			"- %d works\n"+
+			"- %d timers\n"+
+			"- %d server memberships",
+		r.Activities,
+		r.Goals,
+		r.Works,
+		r.Timers,
+		r.GuildMemberships,
+	)
+}
@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/scoring"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+var ScoringCommandData = &discordgo.ApplicationCommand{
+	Name:                     "scoring",
+	Description:              "Configure how points are awarded in this server",
+	DMPermission:             ref.New(false),
+	DefaultMemberPermissions: ref.New(int64(discordgo.PermissionAdministrator)),
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "view",
+			Description: "View the scoring rules",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "set",
+			Description: "Add or replace a scoring rule",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "unit",
+					Description: "The unit points are awarded for",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Minutes", Value: scoring.UnitMinutes},
+						{Name: "Characters", Value: scoring.UnitCharacters},
+						{Name: "Pages", Value: scoring.UnitPages},
+						{Name: "Episodes", Value: scoring.UnitEpisodes},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "points",
+					Description: "The points awarded",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "per",
+					Description: "The amount of the unit the points are awarded for (default: 1)",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "primary-type",
+					Description: "Only apply to this type of immersion",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Reading", Value: activities.ActivityImmersionTypeReading},
+						{Name: "Listening", Value: activities.ActivityImmersionTypeListening},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "media-type",
+					Description: "Only apply to this type of media",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Anime", Value: activities.ActivityMediaTypeAnime},
+						{Name: "Book", Value: activities.ActivityMediaTypeBook},
+						{Name: "Manga", Value: activities.ActivityMediaTypeManga},
+						{Name: "Video", Value: activities.ActivityMediaTypeVideo},
+						{Name: "Visual Novel", Value: activities.ActivityMediaTypeVisualNovel},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "remove",
+			Description: "Remove a scoring rule",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "rule",
+					Description: "The number of the rule, as shown by /scoring view",
+					Required:    true,
+					MinValue:    ref.New(1.0),
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "reset",
+			Description: "Reset to the default of one point per minute",
+		},
+	},
+}
+
+type ScoringCommand struct {
+	s *scoring.ScoringService
+}
+
+func NewScoringCommand(s *scoring.ScoringService) *ScoringCommand {
+	return &ScoringCommand{s: s}
+}
+
+type scoringSetOptions struct {
+	Unit        string  `discordopt:"unit,required"`
+	Points      float64 `discordopt:"points,required"`
+	Per         float64 `discordopt:"per"`
+	PrimaryType string  `discordopt:"primary-type"`
+	MediaType   string  `discordopt:"media-type"`
+}
+
+func (c *ScoringCommand) Handle(ctx *bot.InteractionContext) error {
+	if len(ctx.Options()) == 0 {
+		return bot.ErrInvalidOptions
+	}
+
+	guildID := ctx.Interaction().GuildID
+	if guildID == "" {
+		return errors.New("this command can only be used in a guild")
+	}
+
+	subcommand := ctx.Options()[0]
+
+	if subcommand.Name == "view" {
+		config, err := c.s.GetConfig(ctx.ResponseContext(), guildID)
+		if err != nil {
+			return err
+		}
+
+		return c.respondConfig(ctx, config, "Scoring")
+	}
+
+	// Changing the config re-scores every member's activities, which may
+	// take longer than the initial response deadline.
+	if err := ctx.DeferResponse(); err != nil {
+		return err
+	}
+
+	if subcommand.Name == "reset" {
+		if err := c.s.SetConfig(ctx.Context(), guildID, nil); err != nil {
+			return err
+		}
+
+		return c.followupConfig(ctx, scoring.DefaultConfig(), "Scoring reset")
+	}
+
+	config, err := c.s.GetConfig(ctx.Context(), guildID)
+	if err != nil {
+		return err
+	}
+
+	var title string
+
+	switch subcommand.Name {
+	case "set":
+		options := scoringSetOptions{Per: 1}
+		if err := discordutil.UnmarshalOptions(subcommand.Options, &options); err != nil {
+			return err
+		}
+
+		rule := scoring.Rule{
+			PrimaryType: options.PrimaryType,
+			MediaType:   options.MediaType,
+			Unit:        options.Unit,
+			Per:         options.Per,
+			Points:      options.Points,
+		}
+
+		err := config.SetRule(rule)
+		if errors.Is(err, scoring.ErrInvalidPoints) {
+			return c.followupMessage(ctx, "Points and per must be greater than 0.")
+		} else if errors.Is(err, scoring.ErrTooManyRules) {
+			return c.followupMessage(ctx, fmt.Sprintf("A server can have at most %d scoring rules.", scoring.MaxRules))
+		} else if err != nil {
+			return err
+		}
+
+		title = "Rule set"
+	case "remove":
+		n, err := discordutil.GetRequiredIntOption(subcommand.Options, "rule")
+		if err != nil {
+			return err
+		}
+
+		if !config.RemoveRule(int(n) - 1) {
+			return c.followupMessage(ctx, "There is no rule with that number.")
+		}
+
+		title = "Rule removed"
+	default:
+		return bot.ErrInvalidOptions
+	}
+
+	if err := c.s.SetConfig(ctx.Context(), guildID, config); err != nil {
+		return err
+	}
+
+	return c.followupConfig(ctx, config, title)
+}
+
+func describeScoringConfig(config *scoring.Config, title string) *discordgo.MessageEmbed {
+	var b strings.Builder
+
+	if len(config.Rules) == 0 {
+		b.WriteString("No rules, activities are not worth any points.")
+	}
+
+	for i := range config.Rules {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, config.Rules[i].String())
+	}
+
+	return discordutil.NewEmbedBuilder().
+		SetTitle(title).
+		SetDescription(b.String()).
+		SetFooter("Each activity is scored by the first rule which matches it", "").
+		SetColor(discordutil.ColorPrimary).
+		MessageEmbed
+}
+
+func (c *ScoringCommand) respondConfig(ctx *bot.InteractionContext, config *scoring.Config, title string) error {
+	return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{describeScoringConfig(config, title)},
+	})
+}
+
+func (c *ScoringCommand) followupConfig(ctx *bot.InteractionContext, config *scoring.Config, title string) error {
+	_, err := ctx.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{describeScoringConfig(config, title)},
+	}, false)
+	return err
+}
+
+func (c *ScoringCommand) followupMessage(ctx *bot.InteractionContext, content string) error {
+	_, err := ctx.Followup(&discordgo.WebhookParams{
+		Content: content,
+	}, false)
+	return err
+}
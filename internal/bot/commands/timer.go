@@ -0,0 +1,540 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/internal/timers"
+	"github.com/xoltia/botsu/internal/works"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+// Timers shorter than this are discarded instead of logged when stopped.
+const minTimerDuration = time.Minute
+
+var TimerCommandData = &discordgo.ApplicationCommand{
+	Name:        "timer",
+	Description: "Time an immersion session and log it when you are done",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "start",
+			Description: "Start a new timer",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Title/name of what you are immersing in",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "type",
+					Description: "Type of activity (default based on media type, or reading)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{
+							Name:  "Listening",
+							Value: activities.ActivityImmersionTypeListening,
+						},
+						{
+							Name:  "Reading",
+							Value: activities.ActivityImmersionTypeReading,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "media-type",
+					Description: "Type of media",
+					Required:    false,
+					Choices:     mediaTypeChoices,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "pause",
+			Description: "Pause your timer",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "resume",
+			Description: "Resume your paused timer",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "stop",
+			Description: "Stop your timer and log the time as an activity",
+		},
+	},
+}
+
+var mediaTypeChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{
+		Name:  "Anime",
+		Value: activities.ActivityMediaTypeAnime,
+	},
+	{
+		Name:  "Manga",
+		Value: activities.ActivityMediaTypeManga,
+	},
+	{
+		Name:  "Book",
+		Value: activities.ActivityMediaTypeBook,
+	},
+	{
+		Name:  "Video",
+		Value: activities.ActivityMediaTypeVideo,
+	},
+	{
+		Name:  "Visual Novel",
+		Value: activities.ActivityMediaTypeVisualNovel,
+	},
+}
+
+type TimerCommand struct {
+	r           *timers.TimerRepository
+	ws          *works.WorkService
+	goalService *goals.GoalService
+	maxDuration time.Duration
+	logger      *slog.Logger
+}
+
+func NewTimerCommand(
+	r *timers.TimerRepository,
+	ws *works.WorkService,
+	gs *goals.GoalService,
+	maxDuration time.Duration,
+	logger *slog.Logger,
+) *TimerCommand {
+	return &TimerCommand{
+		r:           r,
+		ws:          ws,
+		goalService: gs,
+		maxDuration: maxDuration,
+		logger:      logger,
+	}
+}
+
+func (c *TimerCommand) Handle(ctx *bot.InteractionContext) error {
+	if len(ctx.Options()) == 0 {
+		return bot.ErrInvalidOptions
+	}
+
+	subcommand := ctx.Options()[0]
+
+	switch subcommand.Name {
+	case "start":
+		return c.handleStart(ctx, subcommand)
+	case "pause":
+		return c.handlePause(ctx)
+	case "resume":
+		return c.handleResume(ctx)
+	case "stop":
+		return c.handleStop(ctx)
+	default:
+		return bot.ErrInvalidOptions
+	}
+}
+
+// HandleComponent handles the buttons on timer messages. These keep working
+// across restarts since the timer state is stored in the database.
+func (c *TimerCommand) HandleComponent(ctx *bot.InteractionContext) error {
+	userID := ctx.User().ID
+
+	t, err := c.r.GetByUserID(ctx.ResponseContext(), userID)
+	if err != nil && !errors.Is(err, timers.ErrTimerNotFound) {
+		return err
+	}
+
+	if t == nil || t.MessageID == nil || *t.MessageID != ctx.Interaction().Message.ID {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: "This is not your timer, or it has already been stopped.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	}
+
+	switch ctx.ComponentData().CustomID {
+	case "timer:pause":
+		t, err = c.r.Pause(ctx.ResponseContext(), userID)
+	case "timer:resume":
+		t, err = c.r.Resume(ctx.ResponseContext(), userID)
+	case "timer:stop":
+		return c.stopAndRespond(ctx, discordgo.InteractionResponseUpdateMessage)
+	default:
+		return errors.New("invalid custom id")
+	}
+
+	if errors.Is(err, timers.ErrTimerNotFound) {
+		// Already paused or resumed, refresh the message instead
+		t, err = c.r.GetByUserID(ctx.ResponseContext(), userID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.Respond(discordgo.InteractionResponseUpdateMessage, &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{c.newTimerEmbed(t).MessageEmbed},
+		Components: newTimerComponents(t),
+	})
+}
+
+func (c *TimerCommand) handleStart(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	var args struct {
+		Name      string  `discordopt:"name"`
+		Type      string  `discordopt:"type"`
+		MediaType *string `discordopt:"media-type"`
+	}
+
+	if err := discordutil.UnmarshalOptions(subcommand.Options, &args); err != nil {
+		return err
+	}
+
+	t := &timers.Timer{
+		UserID:      ctx.User().ID,
+		ChannelID:   ref.New(ctx.Interaction().ChannelID),
+		Name:        args.Name,
+		PrimaryType: args.Type,
+		MediaType:   args.MediaType,
+	}
+
+	if guildID := ctx.Interaction().GuildID; guildID != "" {
+		t.GuildID = &guildID
+	}
+
+	if t.Name == "" {
+		t.Name = "Timed session"
+	}
+
+	if t.PrimaryType == "" {
+		t.PrimaryType = activities.ActivityImmersionTypeReading
+		if t.MediaType != nil && (*t.MediaType == activities.ActivityMediaTypeAnime || *t.MediaType == activities.ActivityMediaTypeVideo) {
+			t.PrimaryType = activities.ActivityImmersionTypeListening
+		}
+	}
+
+	err := c.r.Create(ctx.ResponseContext(), t)
+	if errors.Is(err, timers.ErrTimerExists) {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: "You already have a timer. Use `/timer stop` to stop it first.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	err = ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{c.newTimerEmbed(t).MessageEmbed},
+		Components: newTimerComponents(t),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := ctx.Session().InteractionResponse(ctx.Interaction().Interaction, discordgo.WithContext(ctx.Context()))
+	if err != nil {
+		return err
+	}
+
+	return c.r.SetMessage(ctx.Context(), t.UserID, msg.ChannelID, msg.ID)
+}
+
+func (c *TimerCommand) handlePause(ctx *bot.InteractionContext) error {
+	t, err := c.r.Pause(ctx.ResponseContext(), ctx.User().ID)
+	if errors.Is(err, timers.ErrTimerNotFound) {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: "You have no running timer.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	err = ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Timer paused at %s.", t.Elapsed.Round(time.Second)),
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.updateTimerMessage(ctx.Context(), ctx.Session(), t, c.newTimerEmbed(t), newTimerComponents(t))
+	return nil
+}
+
+func (c *TimerCommand) handleResume(ctx *bot.InteractionContext) error {
+	t, err := c.r.Resume(ctx.ResponseContext(), ctx.User().ID)
+	if errors.Is(err, timers.ErrTimerNotFound) {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: "You have no paused timer.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	err = ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Content: "Timer resumed.",
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.updateTimerMessage(ctx.Context(), ctx.Session(), t, c.newTimerEmbed(t), newTimerComponents(t))
+	return nil
+}
+
+func (c *TimerCommand) handleStop(ctx *bot.InteractionContext) error {
+	return c.stopAndRespond(ctx, discordgo.InteractionResponseChannelMessageWithSource)
+}
+
+// stopAndRespond stops the user's timer, logs it, and responds with the
+// logged activity using the given response type.
+func (c *TimerCommand) stopAndRespond(ctx *bot.InteractionContext, responseType discordgo.InteractionResponseType) error {
+	t, a, err := c.stop(ctx.ResponseContext(), ctx.User().ID, time.Now())
+	if errors.Is(err, timers.ErrTimerNotFound) {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: "You have no timer.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	var embed *discordutil.EmbedBuilder
+	if a == nil {
+		embed = discordutil.NewEmbedBuilder().
+			SetTitle("Timer stopped").
+			SetDescription(fmt.Sprintf("Sessions shorter than %s are not logged.", minTimerDuration)).
+			AddField("Title", t.Name, false).
+			SetColor(discordutil.ColorWarning)
+	} else {
+		embed = newTimerActivityEmbed(a)
+	}
+
+	err = ctx.Respond(responseType, &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{embed.MessageEmbed},
+		Components: []discordgo.MessageComponent{},
+	})
+	if err != nil {
+		return err
+	}
+
+	if responseType != discordgo.InteractionResponseUpdateMessage {
+		c.updateTimerMessage(ctx.Context(), ctx.Session(), t, embed, []discordgo.MessageComponent{})
+	}
+
+	if a == nil {
+		return nil
+	}
+
+	completedGoals, err := c.goalService.CheckCompleted(ctx.Context(), a)
+	if err != nil {
+		return err
+	}
+
+	if len(completedGoals) == 0 {
+		return nil
+	}
+
+	goalsEmbed := newGoalsCompletedEmbed(completedGoals).
+		SetFooter(fmt.Sprintf("Activity ID: %d", a.ID), "")
+
+	_, err = ctx.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{goalsEmbed.MessageEmbed},
+	}, false)
+
+	return err
+}
+
+// stop removes the user's timer and logs it, capping the duration at the
+// maximum timer length. The returned activity is nil if the timer was too
+// short to be logged. The timer is kept if logging it fails, and the session
+// adds progress to its work like any logged activity.
+func (c *TimerCommand) stop(ctx context.Context, userID string, now time.Time) (*timers.Timer, *activities.Activity, error) {
+	t, tx, err := c.r.BeginDeleteTx(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	duration := t.ElapsedAt(now)
+	if c.maxDuration > 0 && duration > c.maxDuration {
+		duration = c.maxDuration
+	}
+
+	var a *activities.Activity
+	if duration >= minTimerDuration {
+		a = t.Activity(duration)
+		if _, err = c.ws.LogTx(ctx, tx, a); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return t, a, nil
+}
+
+// StopExpired stops and logs every timer that has reached the maximum
+// length, or been paused for as long, letting their owners know by DM. Meant
+// to be run as a bot task.
+func (c *TimerCommand) StopExpired(ctx context.Context, s *discordgo.Session) error {
+	expired, err := c.r.GetExpired(ctx, c.maxDuration)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range expired {
+		logger := c.logger.With(slog.String("user", e.UserID))
+
+		t, a, err := c.stop(ctx, e.UserID, time.Now())
+		if errors.Is(err, timers.ErrTimerNotFound) {
+			// Stopped by the user in the meantime
+			continue
+		} else if err != nil {
+			logger.Error("Unable to stop expired timer", slog.String("err", err.Error()))
+			continue
+		}
+
+		if a == nil {
+			continue
+		}
+
+		if _, err = c.goalService.CheckCompleted(ctx, a); err != nil {
+			logger.Error("Unable to check goals for expired timer", slog.String("err", err.Error()))
+		}
+
+		reason := fmt.Sprintf("Your timer ran for the maximum length of %s", c.maxDuration)
+		if t.IsPaused() {
+			reason = fmt.Sprintf("Your timer was paused for %s", c.maxDuration)
+		}
+
+		embed := newTimerActivityEmbed(a).
+			SetTitle("Timer stopped automatically").
+			SetDescription(fmt.Sprintf(
+				"%s, so it was stopped and logged. Use `/undo id:%d` if this was a mistake.",
+				reason,
+				a.ID,
+			))
+
+		c.updateTimerMessage(ctx, s, t, embed, []discordgo.MessageComponent{})
+
+		channel, err := s.UserChannelCreate(t.UserID, discordgo.WithContext(ctx))
+		if err != nil {
+			logger.Warn("Unable to create DM channel for timer", slog.String("err", err.Error()))
+			continue
+		}
+
+		_, err = s.ChannelMessageSendEmbed(channel.ID, embed.MessageEmbed, discordgo.WithContext(ctx))
+		if err != nil {
+			logger.Warn("Unable to send timer DM", slog.String("err", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// updateTimerMessage edits the message showing the timer, if any. Failure is
+// not fatal since the message may have been deleted or be inaccessible.
+func (c *TimerCommand) updateTimerMessage(
+	ctx context.Context,
+	s *discordgo.Session,
+	t *timers.Timer,
+	embed *discordutil.EmbedBuilder,
+	components []discordgo.MessageComponent,
+) {
+	if t.ChannelID == nil || t.MessageID == nil {
+		return
+	}
+
+	_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         *t.MessageID,
+		Channel:    *t.ChannelID,
+		Embeds:     []*discordgo.MessageEmbed{embed.MessageEmbed},
+		Components: components,
+	}, discordgo.WithContext(ctx))
+
+	if err != nil {
+		c.logger.Debug("Unable to update timer message", slog.String("message", *t.MessageID), slog.String("err", err.Error()))
+	}
+}
+
+func (c *TimerCommand) newTimerEmbed(t *timers.Timer) *discordutil.EmbedBuilder {
+	now := time.Now()
+	embed := discordutil.NewEmbedBuilder().
+		AddField("Title", t.Name, false).
+		AddField("Started", fmt.Sprintf("<t:%d:t>", t.StartedAt.Unix()), true).
+		SetTimestamp(now)
+
+	if t.IsPaused() {
+		embed.
+			SetTitle("Timer paused").
+			SetColor(discordutil.ColorWarning).
+			AddField("Elapsed", t.Elapsed.Round(time.Second).String(), true)
+	} else {
+		// Shown as a relative timestamp so the elapsed time stays current
+		effectiveStart := now.Add(-t.ElapsedAt(now))
+		embed.
+			SetTitle("Timer running").
+			SetColor(discordutil.ColorInfo).
+			AddField("Elapsed", fmt.Sprintf("since <t:%d:R>", effectiveStart.Unix()), true)
+	}
+
+	if c.maxDuration > 0 {
+		embed.SetFooter(fmt.Sprintf("Timers are stopped automatically after %s.", c.maxDuration), "")
+	}
+
+	return embed
+}
+
+func newTimerComponents(t *timers.Timer) []discordgo.MessageComponent {
+	toggle := discordgo.Button{
+		Label:    "Pause",
+		Style:    discordgo.SecondaryButton,
+		CustomID: "timer:pause",
+	}
+
+	if t.IsPaused() {
+		toggle.Label = "Resume"
+		toggle.Style = discordgo.PrimaryButton
+		toggle.CustomID = "timer:resume"
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				toggle,
+				discordgo.Button{
+					Label:    "Stop",
+					Style:    discordgo.DangerButton,
+					CustomID: "timer:stop",
+				},
+			},
+		},
+	}
+}
+
+func newTimerActivityEmbed(a *activities.Activity) *discordutil.EmbedBuilder {
+	return discordutil.NewEmbedBuilder().
+		SetTitle("Activity logged!").
+		AddField("Title", a.Name, false).
+		AddField("Duration", a.Duration.String(), false).
+		SetFooter(fmt.Sprintf("ID: %d", a.ID), "").
+		SetTimestamp(a.Date).
+		SetColor(discordutil.ColorSuccess)
+}
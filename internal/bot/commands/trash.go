@@ -0,0 +1,226 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/works"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+var TrashCommandData = &discordgo.ApplicationCommand{
+	Name:        "trash",
+	Description: "View and restore deleted activities",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List your recently deleted activities and imports",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "restore",
+			Description: "Restore a deleted activity or import",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					MinValue:    ref.New(0.0),
+					Name:        "id",
+					Description: "The ID of the activity to restore",
+					Required:    false,
+				},
+				{
+					// use string instead of int because it is too large for integer options
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "import",
+					Description: "The timestamp of the import to restore (see `/trash list`)",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+type TrashCommand struct {
+	r         *activities.ActivityRepository
+	ws        *works.WorkService
+	retention time.Duration
+}
+
+// Retention is only used for display, a zero value means deleted
+// activities are kept forever.
+func NewTrashCommand(r *activities.ActivityRepository, ws *works.WorkService, retention time.Duration) *TrashCommand {
+	return &TrashCommand{r: r, ws: ws, retention: retention}
+}
+
+func (c *TrashCommand) Handle(ctx *bot.InteractionContext) error {
+	if len(ctx.Options()) == 0 {
+		return bot.ErrInvalidOptions
+	}
+
+	subcommand := ctx.Options()[0]
+
+	switch subcommand.Name {
+	case "list":
+		return c.handleList(ctx)
+	case "restore":
+		return c.handleRestore(ctx, subcommand)
+	default:
+		return bot.ErrInvalidOptions
+	}
+}
+
+func (c *TrashCommand) handleList(ctx *bot.InteractionContext) error {
+	if err := ctx.DeferResponse(); err != nil {
+		return err
+	}
+
+	userID := ctx.User().ID
+
+	deleted, err := c.r.GetRecentlyDeletedByUserID(ctx.Context(), userID, ctx.Interaction().GuildID, 15)
+	if err != nil {
+		return err
+	}
+
+	deletedImports, err := c.r.GetRecentlyDeletedImportsByUserID(ctx.Context(), userID, 5)
+	if err != nil {
+		return err
+	}
+
+	if len(deleted) == 0 && len(deletedImports) == 0 {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: "Your trash is empty.",
+		}, false)
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Trash").
+		SetDescription("Use `/trash restore id:{id}` to restore an activity or `/trash restore import:{timestamp}` to restore an import.").
+		SetColor(discordutil.ColorInfo).
+		SetTimestamp(time.Now())
+
+	if c.retention > 0 {
+		embed.SetFooter(fmt.Sprintf("Deleted activities are permanently removed after %s.", formatRetention(c.retention)), "")
+	}
+
+	for _, a := range deleted {
+		embed.AddField(
+			strconv.FormatUint(a.ID, 10),
+			fmt.Sprintf(
+				"%s (%s)\nDeleted <t:%d:R>",
+				a.Name,
+				a.Date.Format(time.DateTime),
+				a.DeletedAt.Unix(),
+			),
+			true,
+		)
+	}
+
+	for _, h := range deletedImports {
+		embed.AddField(
+			fmt.Sprintf("Import %d", h.Timestamp.UnixNano()),
+			fmt.Sprintf(
+				"%d activities imported <t:%d:R>",
+				h.Count,
+				h.Timestamp.Unix(),
+			),
+			false,
+		)
+	}
+
+	_, err = ctx.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+	}, false)
+
+	return err
+}
+
+func (c *TrashCommand) handleRestore(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	id := discordutil.GetUintOption(subcommand.Options, "id")
+	importTimestamp := discordutil.GetStringOption(subcommand.Options, "import")
+
+	if (id == nil) == (importTimestamp == nil) {
+		return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+			Content: "You must provide either an activity ID or an import timestamp.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	}
+
+	if err := ctx.DeferResponse(); err != nil {
+		return err
+	}
+
+	userID := ctx.User().ID
+	embedBuilder := discordutil.NewEmbedBuilder().
+		SetColor(discordutil.ColorWarning).
+		SetTitle("Nothing restored")
+
+	var restored int64
+
+	if id != nil {
+		ok, err := c.ws.RestoreActivity(ctx.Context(), *id, userID)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			embedBuilder.SetDescription("No deleted activity of yours was found with that ID.")
+			_, err = ctx.Followup(&discordgo.WebhookParams{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+			}, false)
+			return err
+		}
+
+		restored = 1
+	} else {
+		timestamp, err := strconv.ParseInt(*importTimestamp, 10, 64)
+		if err != nil {
+			embedBuilder.SetDescription("Invalid timestamp!")
+			_, err = ctx.Followup(&discordgo.WebhookParams{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+			}, false)
+			return err
+		}
+
+		restored, err = c.ws.RestoreImport(ctx.Context(), userID, time.Unix(0, timestamp))
+		if err != nil {
+			return err
+		}
+
+		if restored == 0 {
+			embedBuilder.SetDescription("No activities were restored. Make sure you are using the correct timestamp!")
+			_, err = ctx.Followup(&discordgo.WebhookParams{
+				Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+			}, false)
+			return err
+		}
+	}
+
+	embedBuilder.
+		SetTitle("Success!").
+		SetColor(discordutil.ColorSuccess).
+		SetDescription(fmt.Sprintf("Restored **%d** activities. Your goal progress has been updated.", restored))
+
+	_, err := ctx.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{embedBuilder.MessageEmbed},
+	}, false)
+
+	return err
+}
+
+func formatRetention(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days >= 1 && d%(24*time.Hour) == 0 {
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	return d.String()
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/xoltia/botsu/internal/activities"
 	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/works"
 	"github.com/xoltia/botsu/pkg/discordutil"
 	"github.com/xoltia/botsu/pkg/ref"
 )
@@ -29,11 +30,12 @@ var UndoCommandData = &discordgo.ApplicationCommand{
 }
 
 type UndoCommand struct {
-	r *activities.ActivityRepository
+	r  *activities.ActivityRepository
+	ws *works.WorkService
 }
 
-func NewUndoCommand(r *activities.ActivityRepository) *UndoCommand {
-	return &UndoCommand{r: r}
+func NewUndoCommand(r *activities.ActivityRepository, ws *works.WorkService) *UndoCommand {
+	return &UndoCommand{r: r, ws: ws}
 }
 
 func (c *UndoCommand) Handle(ctx *bot.InteractionContext) error {
@@ -79,7 +81,7 @@ func (c *UndoCommand) undoActivity(ctx *bot.InteractionContext, id uint64) error
 		AddField("Date", fmt.Sprintf("<t:%d>", activity.Date.Unix()), true).
 		AddField("Created At", fmt.Sprintf("<t:%d>", activity.CreatedAt.Unix()), true).
 		AddField("Duration", activity.Duration.String(), true).
-		SetFooter("This cannot be undone!", "").
+		SetFooter("Deleted activities can be restored with /trash restore.", "").
 		SetColor(discordutil.ColorWarning).
 		MessageEmbed
 
@@ -137,7 +139,7 @@ func (c *UndoCommand) undoActivity(ctx *bot.InteractionContext, id uint64) error
 	defer cancel()
 
 	if ci.MessageComponentData().CustomID == "undo_confirm" {
-		err = c.r.DeleteByID(ciCtx, activity.ID)
+		err = c.ws.DeleteActivity(ciCtx, activity.ID)
 		if err != nil {
 			return err
 		}
@@ -145,7 +147,7 @@ func (c *UndoCommand) undoActivity(ctx *bot.InteractionContext, id uint64) error
 		err := ctx.Session().InteractionRespond(ci.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseUpdateMessage,
 			Data: &discordgo.InteractionResponseData{
-				Content:    "Activity deleted.",
+				Content:    fmt.Sprintf("Activity deleted. Use `/trash restore id:%d` to bring it back.", activity.ID),
 				Components: []discordgo.MessageComponent{},
 				Embeds:     []*discordgo.MessageEmbed{},
 			},
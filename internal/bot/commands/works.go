@@ -0,0 +1,562 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bot"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/internal/works"
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+var workMediaTypeChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{
+		Name:  "Anime",
+		Value: activities.ActivityMediaTypeAnime,
+	},
+	{
+		Name:  "Manga",
+		Value: activities.ActivityMediaTypeManga,
+	},
+	{
+		Name:  "Book",
+		Value: activities.ActivityMediaTypeBook,
+	},
+	{
+		Name:  "Visual Novel",
+		Value: activities.ActivityMediaTypeVisualNovel,
+	},
+}
+
+var workStatusChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{
+		Name:  "Reading",
+		Value: works.StatusReading,
+	},
+	{
+		Name:  "Completed",
+		Value: works.StatusCompleted,
+	},
+	{
+		Name:  "On hold",
+		Value: works.StatusOnHold,
+	},
+	{
+		Name:  "Dropped",
+		Value: works.StatusDropped,
+	},
+}
+
+var workOption = &discordgo.ApplicationCommandOption{
+	Type:         discordgo.ApplicationCommandOptionString,
+	Name:         "work",
+	Description:  "The work in your library",
+	Required:     true,
+	Autocomplete: true,
+}
+
+var WorksCommandData = &discordgo.ApplicationCommand{
+	Name:        "works",
+	Description: "View and manage your library of anime, manga, books and visual novels",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List the works in a library",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "status",
+					Description: "Only show works with this status",
+					Required:    false,
+					Choices:     workStatusChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "The user to view the library of (defaults to yourself)",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "page",
+					Description: "The page to view",
+					MinValue:    ref.New(1.0),
+					Required:    false,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: "Add a work to your library",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "title",
+					Description: "The title of the work",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "media-type",
+					Description: "The type of media",
+					Required:    true,
+					Choices:     workMediaTypeChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "status",
+					Description: "The status of the work (default reading)",
+					Required:    false,
+					Choices:     workStatusChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "total-episodes",
+					Description: "The number of episodes",
+					MinValue:    ref.New(1.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "total-volumes",
+					Description: "The number of volumes",
+					MinValue:    ref.New(1.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "total-characters",
+					Description: "The number of characters",
+					MinValue:    ref.New(1.0),
+					Required:    false,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "update",
+			Description: "Update the status, progress or rating of a work",
+			Options: []*discordgo.ApplicationCommandOption{
+				workOption,
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "status",
+					Description: "The status of the work",
+					Required:    false,
+					Choices:     workStatusChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "episodes",
+					Description: "Episodes watched",
+					MinValue:    ref.New(0.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "total-episodes",
+					Description: "The number of episodes (0 if unknown)",
+					MinValue:    ref.New(0.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "volumes",
+					Description: "Volumes read",
+					MinValue:    ref.New(0.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "total-volumes",
+					Description: "The number of volumes (0 if unknown)",
+					MinValue:    ref.New(0.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "characters",
+					Description: "Characters read",
+					MinValue:    ref.New(0.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "total-characters",
+					Description: "The number of characters (0 if unknown)",
+					MinValue:    ref.New(0.0),
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "rating",
+					Description: "Your rating out of 10 (0 to remove)",
+					MinValue:    ref.New(0.0),
+					MaxValue:    10,
+					Required:    false,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "started",
+					Description:  "When you started the work, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "finished",
+					Description:  "When you finished the work, " + dateOptionDescription,
+					Required:     false,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "remove",
+			Description: "Remove a work from your library (logged activities are kept)",
+			Options: []*discordgo.ApplicationCommandOption{
+				workOption,
+			},
+		},
+	},
+}
+
+type WorksCommand struct {
+	r  *works.WorkRepository
+	ts *users.UserTimeService
+}
+
+func NewWorksCommand(r *works.WorkRepository, ts *users.UserTimeService) *WorksCommand {
+	return &WorksCommand{r: r, ts: ts}
+}
+
+func (c *WorksCommand) Handle(ctx *bot.InteractionContext) error {
+	if ctx.IsAutocomplete() {
+		return c.handleAutocomplete(ctx)
+	}
+
+	if len(ctx.Options()) == 0 {
+		return bot.ErrInvalidOptions
+	}
+
+	subcommand := ctx.Options()[0]
+
+	switch subcommand.Name {
+	case "list":
+		return c.handleList(ctx, subcommand)
+	case "add":
+		return c.handleAdd(ctx, subcommand)
+	case "update":
+		return c.handleUpdate(ctx, subcommand)
+	case "remove":
+		return c.handleRemove(ctx, subcommand)
+	default:
+		return bot.ErrInvalidOptions
+	}
+}
+
+func (c *WorksCommand) handleAutocomplete(ctx *bot.InteractionContext) error {
+	focused := focusedSubcommandOption(ctx)
+	if focused == nil {
+		return nil
+	}
+
+	if focused.Name == "started" || focused.Name == "finished" {
+		return respondDateAutocomplete(ctx, c.ts, focused)
+	}
+
+	results, err := c.r.SearchByUserID(ctx.ResponseContext(), ctx.User().ID, focused.StringValue(), 25)
+	if err != nil {
+		return err
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(results))
+	for _, w := range results {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateLongString(fmt.Sprintf("%s (%s, %s)", w.Title, workMediaTypeName(w.MediaType), w.DescribeProgress()), 100),
+			Value: strconv.FormatInt(w.ID, 10),
+		})
+	}
+
+	return ctx.Respond(discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
+		Choices: choices,
+	})
+}
+
+func (c *WorksCommand) handleList(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	const pageSize = 10
+
+	if err := ctx.DeferResponse(); err != nil {
+		return err
+	}
+
+	user := discordutil.GetUserOption(subcommand.Options, "user", ctx.Session())
+	if user == nil {
+		user = ctx.User()
+	}
+
+	status := discordutil.GetStringOption(subcommand.Options, "status")
+	pageNumber := discordutil.GetUintOptionOrDefault(subcommand.Options, "page", 1)
+
+	page, err := c.r.PageByUserID(ctx.Context(), user.ID, status, pageSize, int(pageNumber-1)*pageSize)
+	if err != nil {
+		return err
+	}
+
+	if len(page.Works) == 0 {
+		_, err = ctx.Followup(&discordgo.WebhookParams{
+			Content: "No works found. Works are added when logging anime, manga, books and visual novels, or with `/works add`.",
+		}, false)
+		return err
+	}
+
+	title := "Library"
+	if status != nil {
+		title = fmt.Sprintf("Library (%s)", works.StatusName(*status))
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle(title).
+		SetAuthor(user.Username, user.AvatarURL(""), "").
+		SetColor(discordutil.ColorInfo).
+		SetFooter(fmt.Sprintf("Page %d of %d", page.Page, page.PageCount), "")
+
+	for _, w := range page.Works {
+		embed.AddField(truncateLongString(fmt.Sprintf("%d. %s", w.ID, w.Title), 256), describeWork(w), false)
+	}
+
+	_, err = ctx.Followup(&discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+	}, false)
+	return err
+}
+
+func describeWork(w *works.Work) string {
+	lines := []string{
+		fmt.Sprintf("%s · %s · %s", workMediaTypeName(w.MediaType), works.StatusName(w.Status), w.DescribeProgress()),
+	}
+
+	details := make([]string, 0, 3)
+	if w.Rating != nil {
+		details = append(details, fmt.Sprintf("Rated %d/10", *w.Rating))
+	}
+	if w.StartedAt != nil {
+		details = append(details, fmt.Sprintf("Started <t:%d:d>", w.StartedAt.Unix()))
+	}
+	if w.FinishedAt != nil {
+		details = append(details, fmt.Sprintf("Finished <t:%d:d>", w.FinishedAt.Unix()))
+	}
+	if len(details) > 0 {
+		lines = append(lines, strings.Join(details, " · "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func workMediaTypeName(mediaType string) string {
+	for _, choice := range workMediaTypeChoices {
+		if choice.Value == mediaType {
+			return choice.Name
+		}
+	}
+
+	return mediaType
+}
+
+func (c *WorksCommand) handleAdd(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	var args struct {
+		Title           string `discordopt:"title,required"`
+		MediaType       string `discordopt:"media-type,required"`
+		Status          string `discordopt:"status"`
+		TotalEpisodes   uint   `discordopt:"total-episodes"`
+		TotalVolumes    uint   `discordopt:"total-volumes"`
+		TotalCharacters uint   `discordopt:"total-characters"`
+	}
+
+	if err := discordutil.UnmarshalOptions(subcommand.Options, &args); err != nil {
+		return err
+	}
+
+	w := &works.Work{
+		UserID:          ctx.User().ID,
+		MediaType:       args.MediaType,
+		Title:           strings.TrimSpace(args.Title),
+		Status:          args.Status,
+		TotalEpisodes:   optionalTotal(args.TotalEpisodes),
+		TotalVolumes:    optionalTotal(args.TotalVolumes),
+		TotalCharacters: optionalTotal(args.TotalCharacters),
+	}
+
+	if w.Status == "" {
+		w.Status = works.StatusReading
+	}
+
+	w.UpdateStatus(time.Now())
+
+	if w.Title == "" {
+		return respondEphemeral(ctx, "The title cannot be empty.")
+	}
+
+	err := c.r.Create(ctx.Context(), w)
+	if errors.Is(err, works.ErrWorkExists) {
+		return respondEphemeral(ctx, "That work is already in your library. Use `/works update` to change it.")
+	} else if err != nil {
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Work added!").
+		AddField(w.Title, describeWork(w), false).
+		SetFooter(fmt.Sprintf("ID: %d", w.ID), "").
+		SetColor(discordutil.ColorSuccess)
+
+	return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+	})
+}
+
+func (c *WorksCommand) handleUpdate(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	var args struct {
+		Work            string `discordopt:"work,required"`
+		Status          string `discordopt:"status"`
+		Episodes        *uint  `discordopt:"episodes"`
+		TotalEpisodes   *uint  `discordopt:"total-episodes"`
+		Volumes         *uint  `discordopt:"volumes"`
+		TotalVolumes    *uint  `discordopt:"total-volumes"`
+		Characters      *uint  `discordopt:"characters"`
+		TotalCharacters *uint  `discordopt:"total-characters"`
+		Rating          *uint  `discordopt:"rating"`
+		Started         string `discordopt:"started"`
+		Finished        string `discordopt:"finished"`
+	}
+
+	if err := discordutil.UnmarshalOptions(subcommand.Options, &args); err != nil {
+		return err
+	}
+
+	w, err := c.getWork(ctx, args.Work)
+	if errors.Is(err, works.ErrWorkNotFound) {
+		return respondEphemeral(ctx, "Work not found.")
+	} else if err != nil {
+		return err
+	}
+
+	if args.Episodes != nil {
+		w.Episodes = int(*args.Episodes)
+	}
+	if args.TotalEpisodes != nil {
+		w.TotalEpisodes = optionalTotal(*args.TotalEpisodes)
+	}
+	if args.Volumes != nil {
+		w.Volumes = int(*args.Volumes)
+	}
+	if args.TotalVolumes != nil {
+		w.TotalVolumes = optionalTotal(*args.TotalVolumes)
+	}
+	if args.Characters != nil {
+		w.Characters = int(*args.Characters)
+	}
+	if args.TotalCharacters != nil {
+		w.TotalCharacters = optionalTotal(*args.TotalCharacters)
+	}
+	if args.Rating != nil {
+		w.Rating = optionalTotal(*args.Rating)
+	}
+
+	if args.Started != "" {
+		started, err := parseUserDate(ctx.Context(), c.ts, ctx.User().ID, ctx.Interaction().GuildID, args.Started)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
+			return respondEphemeral(ctx, "Invalid start date.")
+		} else if err != nil {
+			return err
+		}
+		w.StartedAt = &started
+	}
+
+	if args.Finished != "" {
+		finished, err := parseUserDate(ctx.Context(), c.ts, ctx.User().ID, ctx.Interaction().GuildID, args.Finished)
+		if errors.Is(err, timeparse.ErrInvalidDate) {
+			return respondEphemeral(ctx, "Invalid finish date.")
+		} else if err != nil {
+			return err
+		}
+		w.FinishedAt = &finished
+	}
+
+	if args.Status != "" && args.Status != w.Status {
+		if w.Status == works.StatusCompleted && args.Finished == "" {
+			w.FinishedAt = nil
+		}
+		w.Status = args.Status
+	}
+
+	w.UpdateStatus(time.Now())
+
+	if err := c.r.Update(ctx.Context(), w); err != nil {
+		return err
+	}
+
+	embed := discordutil.NewEmbedBuilder().
+		SetTitle("Work updated!").
+		AddField(w.Title, describeWork(w), false).
+		SetFooter(fmt.Sprintf("ID: %d", w.ID), "").
+		SetColor(discordutil.ColorSuccess)
+
+	return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed},
+	})
+}
+
+func (c *WorksCommand) handleRemove(ctx *bot.InteractionContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) error {
+	w, err := c.getWork(ctx, discordutil.GetStringOptionOrDefault(subcommand.Options, "work", ""))
+	if errors.Is(err, works.ErrWorkNotFound) {
+		return respondEphemeral(ctx, "Work not found.")
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := c.r.DeleteByID(ctx.Context(), w.ID, w.UserID); err != nil {
+		return err
+	}
+
+	return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Removed **%s** from your library.", w.Title),
+	})
+}
+
+// getWork returns the user's work with the ID chosen through autocomplete.
+func (c *WorksCommand) getWork(ctx *bot.InteractionContext, input string) (*works.Work, error) {
+	id, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		return nil, works.ErrWorkNotFound
+	}
+
+	return c.r.GetByID(ctx.Context(), id, ctx.User().ID)
+}
+
+func respondEphemeral(ctx *bot.InteractionContext, content string) error {
+	return ctx.Respond(discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+}
+
+// optionalTotal converts an option where 0 means unknown to a nullable value.
+func optionalTotal(n uint) *int {
+	if n == 0 {
+		return nil
+	}
+
+	return ref.New(int(n))
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// ComponentHandler handles message component interactions that must keep
+// working after the interaction that created the message has ended, such
+// as buttons on long-lived messages or after a restart.
+type ComponentHandler interface {
+	HandleComponent(ctx *InteractionContext) error
+}
+
+// ComponentCollection maps custom ID prefixes to their handlers. A custom ID
+// belongs to a prefix if it is either equal to it or starts with the prefix
+// followed by a colon (e.g. "timer:stop" for the prefix "timer").
+type ComponentCollection map[string]ComponentHandler
+
+func (c ComponentCollection) Add(prefix string, handler ComponentHandler) {
+	c[prefix] = handler
+}
+
+func (c ComponentCollection) Get(customID string) (ComponentHandler, bool) {
+	prefix, _, _ := strings.Cut(customID, ":")
+	handler, ok := c[prefix]
+	return handler, ok
+}
+
+func (c ComponentCollection) Handle(ctx *InteractionContext) error {
+	customID := ctx.ComponentData().CustomID
+	handler, ok := c.Get(customID)
+
+	if !ok {
+		ctx.Logger.Warn("Component handler not found", slog.String("custom_id", customID))
+		return nil
+	}
+
+	return handler.HandleComponent(ctx)
+}
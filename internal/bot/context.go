@@ -26,6 +26,7 @@ type InteractionContext struct {
 	responseCtx       context.Context
 	responseCtxCancel context.CancelFunc
 	data              discordgo.ApplicationCommandInteractionData
+	componentData     discordgo.MessageComponentInteractionData
 	deferred          bool
 }
 
@@ -54,7 +55,7 @@ func NewInteractionContext(
 		slog.Time("response_deadline", responseDeadline),
 	)
 
-	return &InteractionContext{
+	c := &InteractionContext{
 		Logger:            logger,
 		Bot:               bot,
 		s:                 s,
@@ -63,8 +64,15 @@ func NewInteractionContext(
 		ctxCancel:         cancel,
 		responseCtx:       responseDeadlineContext,
 		responseCtxCancel: cancel2,
-		data:              i.ApplicationCommandData(),
 	}
+
+	if i.Type == discordgo.InteractionMessageComponent {
+		c.componentData = i.MessageComponentData()
+	} else {
+		c.data = i.ApplicationCommandData()
+	}
+
+	return c
 }
 
 func (c *InteractionContext) Cancel() {
@@ -103,6 +111,14 @@ func (c *InteractionContext) Options() []*discordgo.ApplicationCommandInteractio
 	return c.data.Options
 }
 
+func (c *InteractionContext) ComponentData() discordgo.MessageComponentInteractionData {
+	return c.componentData
+}
+
+func (c *InteractionContext) IsComponent() bool {
+	return c.i.Type == discordgo.InteractionMessageComponent
+}
+
 func (c *InteractionContext) IsAutocomplete() bool {
 	return c.i.Type == discordgo.InteractionApplicationCommandAutocomplete
 }
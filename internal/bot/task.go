@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TaskFunc is work run periodically in the background while the bot is
+// logged in. The context is cancelled when the bot is closed.
+type TaskFunc func(ctx context.Context, s *discordgo.Session) error
+
+type task struct {
+	name     string
+	interval time.Duration
+	run      TaskFunc
+}
+
+func (b *Bot) runTask(t task) {
+	defer b.wg.Done()
+
+	logger := b.logger.With(slog.String("task", t.name))
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.botContext.Done():
+			return
+		case <-ticker.C:
+			logger.Debug("Running task")
+			if err := t.run(b.botContext, b.session); err != nil {
+				logger.Error("Task failed", slog.String("err", err.Error()))
+			}
+		}
+	}
+}
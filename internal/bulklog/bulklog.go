@@ -0,0 +1,396 @@
+// Package bulklog parses multi-line activity logs, one entry per line.
+//
+// Each line has the form
+//
+//	[date] <type> <name> <amount>...
+//
+// where date is anything timeparse.ParseDate accepts, such as yesterday,
+// 2024-05-01 21:30 or 3 days ago (defaulting to the current time), type is
+// one of the keys of Kinds, name is either a single word, a double quoted
+// string or an autocomplete value such as ${v17}, and amounts are any of
+// 3ep, 12000c, 50p or a duration such as 1h20m, 90 or 1.5h (see
+// timeparse.ParseDuration).
+// Empty lines and lines starting with # are ignored.
+package bulklog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/pkg/ref"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+var (
+	ErrMissingType       = errors.New("missing activity type")
+	ErrMissingName       = errors.New("missing name")
+	ErrMissingAmount     = errors.New("missing amount")
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+)
+
+const (
+	KindAnime       = "anime"
+	KindManga       = "manga"
+	KindBook        = "book"
+	KindVisualNovel = "vn"
+	KindVideo       = "video"
+	KindListening   = "listening"
+	KindReading     = "reading"
+)
+
+// Kinds maps every accepted type keyword to its kind.
+var Kinds = map[string]string{
+	"anime":        KindAnime,
+	"manga":        KindManga,
+	"book":         KindBook,
+	"vn":           KindVisualNovel,
+	"visual-novel": KindVisualNovel,
+	"video":        KindVideo,
+	"listening":    KindListening,
+	"listen":       KindListening,
+	"reading":      KindReading,
+	"read":         KindReading,
+}
+
+const (
+	defaultEpisodeDuration = 24 * time.Minute
+	defaultCharsPerMinute  = 150.0
+	defaultPagesPerMinute  = 0.5
+)
+
+type Entry struct {
+	// 1-based line number within the input
+	Line       int
+	Raw        string
+	Date       time.Time
+	Kind       string
+	Name       string
+	Episodes   uint
+	Characters uint
+	Pages      uint
+	// Explicitly provided duration, zero if it should be estimated
+	Duration time.Duration
+	Err      error
+}
+
+// Parse parses every non-empty line of the input. Entries that could not be
+// parsed are still returned with Err set. Relative dates are resolved
+// against now, in its location.
+func Parse(input string, now time.Time) []*Entry {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	entries := make([]*Entry, 0, len(lines))
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := &Entry{Line: i + 1, Raw: line}
+		entry.Err = entry.parse(line, now)
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func (e *Entry) parse(line string, now time.Time) error {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return err
+	}
+
+	tokens, e.Date, err = parseDate(tokens, now)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		return ErrMissingType
+	}
+
+	kind, ok := Kinds[strings.ToLower(tokens[0].value)]
+	if !ok || tokens[0].quoted {
+		return fmt.Errorf("unknown activity type: %s", tokens[0].value)
+	}
+	e.Kind = kind
+	tokens = tokens[1:]
+
+	if len(tokens) == 0 || (!tokens[0].quoted && isAmount(tokens[0].value)) {
+		return ErrMissingName
+	}
+	e.Name = tokens[0].value
+	tokens = tokens[1:]
+
+	for _, t := range tokens {
+		if err := e.parseAmount(t.value); err != nil {
+			return err
+		}
+	}
+
+	return e.validate()
+}
+
+func (e *Entry) parseAmount(s string) error {
+	lower := strings.ToLower(s)
+	number, unit := splitNumber(lower)
+
+	set := func(dst *uint, name string) error {
+		if *dst != 0 {
+			return fmt.Errorf("%s given more than once", name)
+		}
+
+		n, err := strconv.ParseUint(number, 10, 32)
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid %s: %s", name, s)
+		}
+
+		*dst = uint(n)
+		return nil
+	}
+
+	switch unit {
+	case "ep", "eps", "episode", "episodes":
+		return set(&e.Episodes, "episodes")
+	case "c", "ch", "char", "chars", "characters":
+		return set(&e.Characters, "characters")
+	case "p", "pg", "pgs", "page", "pages":
+		return set(&e.Pages, "pages")
+	}
+
+	if e.Duration != 0 {
+		return errors.New("duration given more than once")
+	}
+
+	d, err := timeparse.ParseDuration(lower)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid amount: %s", s)
+	}
+
+	e.Duration = d
+	return nil
+}
+
+func (e *Entry) validate() error {
+	if e.Episodes != 0 && e.Kind != KindAnime {
+		return fmt.Errorf("episodes are not supported for %s", e.Kind)
+	}
+
+	if e.Characters != 0 && e.Kind != KindVisualNovel {
+		return fmt.Errorf("characters are not supported for %s", e.Kind)
+	}
+
+	if e.Pages != 0 && e.Kind != KindBook && e.Kind != KindManga {
+		return fmt.Errorf("pages are not supported for %s", e.Kind)
+	}
+
+	switch e.Kind {
+	case KindAnime:
+		if e.Episodes == 0 && e.Duration == 0 {
+			return fmt.Errorf("%w: expected episodes or a duration", ErrMissingAmount)
+		}
+	case KindVisualNovel:
+		if e.Characters == 0 && e.Duration == 0 {
+			return fmt.Errorf("%w: expected characters or a duration", ErrMissingAmount)
+		}
+	case KindBook, KindManga:
+		if e.Pages == 0 && e.Duration == 0 {
+			return fmt.Errorf("%w: expected pages or a duration", ErrMissingAmount)
+		}
+	default:
+		if e.Duration == 0 {
+			return fmt.Errorf("%w: expected a duration", ErrMissingAmount)
+		}
+	}
+
+	return nil
+}
+
+// Activity creates an activity from the entry, estimating the duration
+// the same way the individual log commands do. The user, guild and any
+// media metadata are left for the caller to fill in.
+func (e *Entry) Activity() *activities.Activity {
+	a := activities.NewActivity()
+	a.Name = e.Name
+	a.Date = e.Date
+
+	minutes := e.Duration.Minutes()
+
+	switch e.Kind {
+	case KindAnime:
+		a.PrimaryType = activities.ActivityImmersionTypeListening
+		a.MediaType = ref.New(activities.ActivityMediaTypeAnime)
+		if e.Episodes != 0 {
+			a.SetMeta("episodes", e.Episodes)
+		}
+		if e.Duration == 0 {
+			a.Duration = time.Duration(e.Episodes) * defaultEpisodeDuration
+		}
+	case KindVisualNovel:
+		a.PrimaryType = activities.ActivityImmersionTypeReading
+		a.MediaType = ref.New(activities.ActivityMediaTypeVisualNovel)
+		if e.Characters != 0 {
+			a.SetMeta("characters", e.Characters)
+			if e.Duration != 0 {
+				a.SetMeta("speed", float64(e.Characters)/minutes)
+			}
+		}
+		if e.Duration == 0 {
+			a.Duration = minutesToDuration(float64(e.Characters) / defaultCharsPerMinute)
+		}
+	case KindBook, KindManga:
+		a.PrimaryType = activities.ActivityImmersionTypeReading
+		if e.Kind == KindBook {
+			a.MediaType = ref.New(activities.ActivityMediaTypeBook)
+		} else {
+			a.MediaType = ref.New(activities.ActivityMediaTypeManga)
+		}
+		if e.Pages != 0 {
+			a.SetMeta("pages", e.Pages)
+			if e.Duration != 0 {
+				a.SetMeta("speed", float64(e.Pages)/minutes)
+			}
+		}
+		if e.Duration == 0 {
+			a.Duration = minutesToDuration(float64(e.Pages) / defaultPagesPerMinute)
+		}
+	case KindVideo:
+		a.PrimaryType = activities.ActivityImmersionTypeListening
+		a.MediaType = ref.New(activities.ActivityMediaTypeVideo)
+	case KindListening:
+		a.PrimaryType = activities.ActivityImmersionTypeListening
+	case KindReading:
+		a.PrimaryType = activities.ActivityImmersionTypeReading
+	}
+
+	if e.Duration != 0 {
+		a.Duration = e.Duration
+	}
+
+	return a
+}
+
+// because time.Duration casts to uint64, we need to convert to seconds first
+func minutesToDuration(minutes float64) time.Duration {
+	return time.Duration(minutes*60.0) * time.Second
+}
+
+type token struct {
+	value  string
+	quoted bool
+}
+
+func tokenize(line string) ([]token, error) {
+	var (
+		tokens []token
+		sb     strings.Builder
+		inWord bool
+	)
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, token{value: sb.String()})
+			sb.Reset()
+			inWord = false
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '"' && !inWord:
+			end := -1
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == '"' {
+					end = j
+					break
+				}
+			}
+
+			if end == -1 {
+				return nil, ErrUnterminatedQuote
+			}
+
+			tokens = append(tokens, token{value: string(runes[i+1 : end]), quoted: true})
+			i = end
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			sb.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	flush()
+	return tokens, nil
+}
+
+// Most tokens a date can span, as in "last friday 8:30 pm".
+const maxDateTokens = 4
+
+// parseDate takes the date from the start of a line, if there is one. Dates
+// can span several tokens, so the longest run of them which parses as a date
+// is taken.
+func parseDate(tokens []token, now time.Time) ([]token, time.Time, error) {
+	n := 0
+	for n < len(tokens) && n < maxDateTokens && !tokens[n].quoted {
+		n++
+	}
+
+	for ; n > 0; n-- {
+		date, err := timeparse.ParseDate(joinTokens(tokens[:n]), now)
+		if err != nil {
+			continue
+		}
+
+		// Activity types don't start with digits, so a token which does
+		// after the date is a time which didn't parse
+		if n < len(tokens) && !tokens[n].quoted && startsWithDigit(tokens[n].value) {
+			return nil, time.Time{}, fmt.Errorf("%w: %s", timeparse.ErrInvalidDate, joinTokens(tokens[:n+1]))
+		}
+
+		return tokens[n:], date, nil
+	}
+
+	if len(tokens) > 0 && !tokens[0].quoted && startsWithDigit(tokens[0].value) {
+		return nil, time.Time{}, fmt.Errorf("%w: %s", timeparse.ErrInvalidDate, tokens[0].value)
+	}
+
+	return tokens, now, nil
+}
+
+func joinTokens(tokens []token) string {
+	values := make([]string, len(tokens))
+	for i, t := range tokens {
+		values[i] = t.value
+	}
+	return strings.Join(values, " ")
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+func splitNumber(s string) (number, unit string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+
+	if i == -1 {
+		return s, ""
+	}
+
+	return s[:i], s[i:]
+}
+
+func isAmount(s string) bool {
+	number, _ := splitNumber(s)
+	return number != ""
+}
@@ -0,0 +1,126 @@
+package bulklog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/bulklog"
+)
+
+var now = time.Date(2024, 5, 6, 21, 30, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	input := `
+# weekend catch up
+2024-05-01 anime "Frieren" 3ep
+yesterday vn ${v17} 12000c 1h20m
+today 08:15 book 本 50p
+manga "Yotsuba&!" 30m
+listening podcast 1h
+`
+
+	entries := bulklog.Parse(input, now)
+	require.Len(t, entries, 5)
+
+	for _, e := range entries {
+		assert.NoError(t, e.Err, "line %d", e.Line)
+	}
+
+	assert.Equal(t, 3, entries[0].Line)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), entries[0].Date)
+	assert.Equal(t, bulklog.KindAnime, entries[0].Kind)
+	assert.Equal(t, "Frieren", entries[0].Name)
+	assert.Equal(t, uint(3), entries[0].Episodes)
+
+	assert.Equal(t, now.AddDate(0, 0, -1), entries[1].Date)
+	assert.Equal(t, bulklog.KindVisualNovel, entries[1].Kind)
+	assert.Equal(t, "${v17}", entries[1].Name)
+	assert.Equal(t, uint(12000), entries[1].Characters)
+	assert.Equal(t, 80*time.Minute, entries[1].Duration)
+
+	assert.Equal(t, time.Date(2024, 5, 6, 8, 15, 0, 0, time.UTC), entries[2].Date)
+	assert.Equal(t, "本", entries[2].Name)
+	assert.Equal(t, uint(50), entries[2].Pages)
+
+	assert.Equal(t, now, entries[3].Date)
+	assert.Equal(t, "Yotsuba&!", entries[3].Name)
+	assert.Equal(t, 30*time.Minute, entries[3].Duration)
+
+	assert.Equal(t, bulklog.KindListening, entries[4].Kind)
+	assert.Equal(t, time.Hour, entries[4].Duration)
+}
+
+func TestParseDates(t *testing.T) {
+	cases := map[string]time.Time{
+		"3 days ago anime Frieren 3ep":          now.AddDate(0, 0, -3),
+		"last friday 8:30 pm anime Frieren 3ep": time.Date(2024, 5, 3, 20, 30, 0, 0, time.UTC),
+		"5/1 anime Frieren 3ep":                 time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"昨日 anime Frieren 3ep":                  now.AddDate(0, 0, -1),
+		"21:00 anime Frieren 3ep":               time.Date(2024, 5, 6, 21, 0, 0, 0, time.UTC),
+	}
+
+	for input, expected := range cases {
+		entries := bulklog.Parse(input, now)
+		require.Len(t, entries, 1, input)
+		assert.NoError(t, entries[0].Err, input)
+		assert.Equal(t, expected, entries[0].Date, input)
+		assert.Equal(t, "Frieren", entries[0].Name, input)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"2024-05-01":                        "missing activity type",
+		"2024-13-01 anime Frieren 3ep":      "invalid date: 2024-13-01",
+		"podcast 1h":                        "unknown activity type: podcast",
+		"anime 3ep":                         "missing name",
+		"anime \"Frieren 3ep":               "unterminated quote",
+		"anime Frieren":                     "missing amount: expected episodes or a duration",
+		"vn Frieren 3ep":                    "episodes are not supported for vn",
+		"anime Frieren 3ep 4ep":             "episodes given more than once",
+		"listening podcast 1h 2h":           "duration given more than once",
+		"reading article lots":              "invalid amount: lots",
+		"yesterday 25:00 anime Frieren 3ep": "invalid date: yesterday 25:00",
+	}
+
+	for input, expected := range cases {
+		entries := bulklog.Parse(input, now)
+		require.Len(t, entries, 1, input)
+		assert.EqualError(t, entries[0].Err, expected, input)
+	}
+}
+
+func TestEntryActivity(t *testing.T) {
+	entries := bulklog.Parse(`
+anime Frieren 3ep
+vn Sakura 15000c
+vn Sakura 15000c 50m
+book Book 30p
+video Clip 90
+`, now)
+	require.Len(t, entries, 5)
+
+	anime := entries[0].Activity()
+	assert.Equal(t, activities.ActivityImmersionTypeListening, anime.PrimaryType)
+	assert.Equal(t, activities.ActivityMediaTypeAnime, *anime.MediaType)
+	assert.Equal(t, 72*time.Minute, anime.Duration)
+
+	vn := entries[1].Activity()
+	assert.Equal(t, activities.ActivityImmersionTypeReading, vn.PrimaryType)
+	assert.Equal(t, 100*time.Minute, vn.Duration)
+
+	vnWithDuration := entries[2].Activity()
+	assert.Equal(t, 50*time.Minute, vnWithDuration.Duration)
+	assert.Equal(t, 300.0, vnWithDuration.Meta.(map[string]interface{})["speed"])
+
+	book := entries[3].Activity()
+	assert.Equal(t, activities.ActivityMediaTypeBook, *book.MediaType)
+	assert.Equal(t, time.Hour, book.Duration)
+
+	video := entries[4].Activity()
+	assert.Equal(t, activities.ActivityMediaTypeVideo, *video.MediaType)
+	assert.Equal(t, 90*time.Minute, video.Duration)
+}
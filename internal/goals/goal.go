@@ -1,26 +1,106 @@
 package goals
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/adhocore/gronx"
 	"github.com/xoltia/botsu/internal/activities"
-	"github.com/xoltia/botsu/internal/videos"
+	"github.com/xoltia/botsu/internal/scoring"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+// Units goals can be measured in. Characters, pages and episodes are read
+// from activity meta, so activities logged without them count for nothing.
+const (
+	UnitDuration   = "duration"
+	UnitCharacters = "characters"
+	UnitPages      = "pages"
+	UnitEpisodes   = "episodes"
+	UnitActivities = "activities"
+	UnitPoints     = "points"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid goal amount")
+	ErrPaused        = errors.New("goal is paused")
+	ErrNotPaused     = errors.New("goal is not paused")
+	ErrInvalidWork   = errors.New("invalid work")
 )
 
 type Goal struct {
-	ID              int64
-	UserID          string
-	Name            string
-	ActivityType    *string
-	MediaType       *string
-	YoutubeChannels []string
-	Target          time.Duration
-	Current         time.Duration
-	Cron            string
-	DueAt           time.Time
-	CreatedAt       time.Time
+	ID           int64
+	UserID       string
+	Name         string
+	ActivityType *string
+	MediaType    *string
+	// YoutubeChannels are the handles of the channels counted, and
+	// YoutubeChannelIDs their IDs. Goals created before channel IDs were
+	// resolved only have handles.
+	YoutubeChannels   []string
+	YoutubeChannelIDs []string
+	// Work is an AniDB or VNDB work counted, formatted by ParseWork
+	Work *string
+	// NameContains is text which counted activities' names contain,
+	// ignoring case
+	NameContains *string
+	// Tag is a tag of counted anime or a hashtag of counted videos
+	Tag *string
+	// Unit is what Target and Current are measured in. Durations are in
+	// nanoseconds, like activity durations.
+	Unit    string
+	Target  float64
+	Current float64
+	// Cron, or IntervalDays for goals reset every number of days, is the
+	// schedule on which periods end. Goals with neither end once, at DueAt.
+	Cron         string
+	IntervalDays int
+	DueAt        time.Time
+	CreatedAt    time.Time
+	// Summary is set to send the user a summary of each period as it closes
+	Summary bool
+	// RemindedAt is when the user was last reminded of the goal
+	RemindedAt *time.Time
+	// GuildID is set for guild goals, which every member of the guild
+	// contributes to. Their progress is shown in a message in ChannelID.
+	GuildID   *string
+	ChannelID *string
+	MessageID *string
+	// PausedAt is set while the goal is paused, during which activities
+	// don't count towards it and its periods don't close
+	PausedAt *time.Time
+}
+
+// Change is an edit, pause or resumption of a goal.
+type Change struct {
+	ChangedAt   time.Time
+	Description string
+}
+
+// Contribution is the amount a member contributed to a period of a guild
+// goal.
+type Contribution struct {
+	UserID string
+	Amount float64
+}
+
+// Period is a closed period of a goal.
+type Period struct {
+	Goal     *Goal
+	Start    time.Time
+	End      time.Time
+	Target   float64
+	Achieved float64
+}
+
+func (p *Period) Completed() bool {
+	return p.Achieved >= p.Target
 }
 
 func (g *Goal) MatchesActivity(a *activities.Activity) bool {
@@ -32,22 +112,240 @@ func (g *Goal) MatchesActivity(a *activities.Activity) bool {
 		return false
 	}
 
-	if len(g.YoutubeChannels) == 0 {
+	if g.NameContains != nil && !strings.Contains(strings.ToLower(a.Name), strings.ToLower(*g.NameContains)) {
+		return false
+	}
+
+	if g.Work == nil && g.Tag == nil && len(g.YoutubeChannels) == 0 && len(g.YoutubeChannelIDs) == 0 {
 		return true
 	}
 
-	meta, ok := a.Meta.(*videos.VideoInfo)
-	if !ok {
+	meta := persistedMeta(a)
+
+	if g.Work != nil && !slices.Contains(activityWorks(meta), *g.Work) {
 		return false
 	}
 
-	if len(g.YoutubeChannels) > 0 && !slices.Contains(g.YoutubeChannels, meta.ChannelHandle) {
-		return false
+	if g.Tag != nil {
+		hasTag := func(t string) bool { return equalTags(t, *g.Tag) }
+		if !slices.ContainsFunc(metaStrings(meta, "tags"), hasTag) && !slices.ContainsFunc(metaStrings(meta, "hashtags"), hasTag) {
+			return false
+		}
+	}
+
+	if len(g.YoutubeChannelIDs) > 0 {
+		channelID, _ := meta["channel_id"].(string)
+		return slices.Contains(g.YoutubeChannelIDs, channelID)
+	}
+
+	if len(g.YoutubeChannels) > 0 {
+		channelHandle, _ := meta["channel_handle"].(string)
+		return slices.Contains(g.YoutubeChannels, channelHandle)
 	}
 
 	return true
 }
 
+// persistedMeta returns the meta of an activity as it is stored in the
+// database, so that goals match activities the same way when they are logged
+// and when they are read back, e.g. for recalculation.
+func persistedMeta(a *activities.Activity) map[string]interface{} {
+	if meta, ok := a.Meta.(map[string]interface{}); ok {
+		return meta
+	}
+
+	meta := make(map[string]interface{})
+	if b, err := json.Marshal(a.Meta); err == nil {
+		_ = json.Unmarshal(b, &meta)
+	}
+
+	return meta
+}
+
+// metaStrings reads a list of strings from activity meta, which are
+// []string when set by the bot and []interface{} when read back.
+func metaStrings(meta map[string]interface{}, key string) []string {
+	switch v := meta[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	default:
+		return nil
+	}
+}
+
+// Meta keys of the IDs of works in external databases, by the prefix the
+// work is given by ParseWork.
+var workMetaKeys = map[string]string{
+	"anidb": "anidb_id",
+	"vndb":  "vndb_id",
+}
+
+func activityWorks(meta map[string]interface{}) (works []string) {
+	for prefix, key := range workMetaKeys {
+		if id, ok := meta[key].(string); ok && id != "" {
+			works = append(works, prefix+":"+id)
+		}
+	}
+	return
+}
+
+func equalTags(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "#"), strings.TrimPrefix(b, "#"))
+}
+
+var (
+	anidbURLRegex = regexp.MustCompile(`^(?:https?://)?(?:www\.)?anidb\.net/(?:anime/|a)(\d+)`)
+	vndbURLRegex  = regexp.MustCompile(`^(?:https?://)?(?:www\.)?vndb\.org/(v\d+)`)
+	workRegex     = regexp.MustCompile(`^(anidb:\d+|vndb:v\d+)$`)
+)
+
+// ParseWork parses an AniDB or VNDB work from an ID such as anidb:69 or
+// vndb:v17, or a link to it.
+func ParseWork(input string) (string, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+
+	if m := anidbURLRegex.FindStringSubmatch(input); m != nil {
+		return "anidb:" + m[1], nil
+	} else if m := vndbURLRegex.FindStringSubmatch(input); m != nil {
+		return "vndb:" + m[1], nil
+	} else if workRegex.MatchString(input) {
+		return input, nil
+	}
+
+	return "", ErrInvalidWork
+}
+
+func (g *Goal) Paused() bool {
+	return g.PausedAt != nil
+}
+
+func (g *Goal) Pause(now time.Time) error {
+	if g.Paused() {
+		return ErrPaused
+	}
+
+	g.PausedAt = &now
+	return nil
+}
+
+// Resume unpauses the goal. If its period ended while it was paused, that
+// period is closed and returned, the periods missed after it are skipped
+// without being recorded and a new period starts at now. One-off goals can't
+// be resumed past their deadline.
+func (g *Goal) Resume(now time.Time) (closed []*Period, err error) {
+	if !g.Paused() {
+		return nil, ErrNotPaused
+	}
+
+	if g.IsDue(now) {
+		if g.Schedule().IsDeadline() {
+			return nil, ErrEnded
+		}
+
+		var p *Period
+		if p, err = g.closePeriod(g.DueAt.In(now.Location())); err != nil {
+			return
+		}
+		closed = append(closed, p)
+
+		if g.DueAt, err = g.nextTick(now, true); err != nil {
+			return
+		}
+	}
+
+	g.PausedAt = nil
+	return
+}
+
+func formatFilter(v *string) string {
+	if v == nil {
+		return "any"
+	}
+	return *v
+}
+
+// CountsSame reports whether two versions of a goal count the same
+// activities by the same amounts.
+func CountsSame(a, b *Goal) bool {
+	return a.Unit == b.Unit &&
+		a.Schedule().Equal(b.Schedule()) &&
+		formatFilter(a.ActivityType) == formatFilter(b.ActivityType) &&
+		formatFilter(a.MediaType) == formatFilter(b.MediaType) &&
+		formatFilter(a.Work) == formatFilter(b.Work) &&
+		formatFilter(a.NameContains) == formatFilter(b.NameContains) &&
+		formatFilter(a.Tag) == formatFilter(b.Tag) &&
+		slices.Equal(a.YoutubeChannels, b.YoutubeChannels) &&
+		slices.Equal(a.YoutubeChannelIDs, b.YoutubeChannelIDs)
+}
+
+// DescribeChanges describes how a goal was changed, one line per field
+// changed. Schedules are described in loc.
+func DescribeChanges(before, after *Goal, loc *time.Location) (changes []string) {
+	if before.Paused() != after.Paused() {
+		if after.Paused() {
+			changes = append(changes, "Paused")
+		} else {
+			changes = append(changes, "Resumed")
+		}
+	}
+
+	if before.Name != after.Name {
+		changes = append(changes, fmt.Sprintf("Name: %s → %s", before.Name, after.Name))
+	}
+
+	if before.Unit != after.Unit || before.Target != after.Target {
+		changes = append(changes, fmt.Sprintf(
+			"Target: %s → %s",
+			FormatAmount(before.Unit, before.Target),
+			FormatAmount(after.Unit, after.Target),
+		))
+	}
+
+	if !before.Schedule().Equal(after.Schedule()) {
+		changes = append(changes, fmt.Sprintf("Schedule: %s → %s", before.Schedule().Describe(loc), after.Schedule().Describe(loc)))
+	}
+
+	if formatFilter(before.ActivityType) != formatFilter(after.ActivityType) {
+		changes = append(changes, fmt.Sprintf("Activity type: %s → %s", formatFilter(before.ActivityType), formatFilter(after.ActivityType)))
+	}
+
+	if formatFilter(before.MediaType) != formatFilter(after.MediaType) {
+		changes = append(changes, fmt.Sprintf("Media type: %s → %s", formatFilter(before.MediaType), formatFilter(after.MediaType)))
+	}
+
+	if formatFilter(before.Work) != formatFilter(after.Work) {
+		changes = append(changes, fmt.Sprintf("Work: %s → %s", formatFilter(before.Work), formatFilter(after.Work)))
+	}
+
+	if formatFilter(before.NameContains) != formatFilter(after.NameContains) {
+		changes = append(changes, fmt.Sprintf("Title: %s → %s", formatFilter(before.NameContains), formatFilter(after.NameContains)))
+	}
+
+	if formatFilter(before.Tag) != formatFilter(after.Tag) {
+		changes = append(changes, fmt.Sprintf("Tag: %s → %s", formatFilter(before.Tag), formatFilter(after.Tag)))
+	}
+
+	if !slices.Equal(before.YoutubeChannels, after.YoutubeChannels) {
+		channels := [2]string{"any", "any"}
+		for i, c := range [2][]string{before.YoutubeChannels, after.YoutubeChannels} {
+			if len(c) > 0 {
+				channels[i] = strings.Join(c, ", ")
+			}
+		}
+		changes = append(changes, fmt.Sprintf("YouTube channels: %s → %s", channels[0], channels[1]))
+	}
+
+	return
+}
+
 func (g *Goal) IsDue(now time.Time) bool {
 	return g.DueAt.Before(now)
 }
@@ -57,7 +355,119 @@ func (g *Goal) NextDueTime(now time.Time) (t time.Time, err error) {
 		return g.DueAt, nil
 	}
 
-	return gronx.NextTickAfter(g.Cron, now, true)
+	return g.nextTick(now, true)
+}
+
+// ReminderDue reports whether the user should be reminded of the goal at now,
+// given how long before goals are due they want to be reminded. Each reminder
+// is sent at most once per period, and not once the target is reached.
+func (g *Goal) ReminderDue(reminders []time.Duration, now time.Time) bool {
+	if g.Current >= g.Target || !now.Before(g.DueAt) {
+		return false
+	}
+
+	for _, r := range reminders {
+		remindAt := g.DueAt.Add(-r)
+		if !now.Before(remindAt) && (g.RemindedAt == nil || g.RemindedAt.Before(remindAt)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Most periods closed by one rollover. Goals left for longer, such as an
+// hourly goal after a long downtime, skip recording the remaining periods.
+const maxRolloverPeriods = 100
+
+// Rollover closes every period of the goal which is due at now and starts
+// the next one, returning the closed periods from oldest to newest.
+func (g *Goal) Rollover(now time.Time) (closed []*Period, err error) {
+	for g.IsDue(now) {
+		if len(closed) == maxRolloverPeriods {
+			g.DueAt, err = g.nextTick(now, true)
+			return
+		}
+
+		end := g.DueAt.In(now.Location())
+
+		var p *Period
+		if p, err = g.closePeriod(end); err != nil {
+			return
+		}
+		closed = append(closed, p)
+
+		// One-off goals end with their only period
+		if g.Schedule().IsDeadline() {
+			g.PausedAt = &end
+			return
+		}
+
+		g.DueAt, err = g.nextTick(end, false)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// closePeriod returns the current period of the goal, ending at end, and
+// resets the goal's progress for the next period.
+func (g *Goal) closePeriod(end time.Time) (*Period, error) {
+	start, err := g.prevTick(end, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if start.Before(g.CreatedAt) {
+		start = g.CreatedAt
+	}
+
+	p := &Period{
+		Goal:     g,
+		Start:    start,
+		End:      end,
+		Target:   g.Target,
+		Achieved: g.Current,
+	}
+
+	g.Current = 0
+	return p, nil
+}
+
+// PeriodStats summarizes the results of periods ordered from oldest to
+// newest.
+type PeriodStats struct {
+	Periods       int
+	Completed     int
+	LongestStreak int
+	CurrentStreak int
+}
+
+func NewPeriodStats(completed []bool) PeriodStats {
+	stats := PeriodStats{Periods: len(completed)}
+
+	for _, c := range completed {
+		if !c {
+			stats.CurrentStreak = 0
+			continue
+		}
+
+		stats.Completed++
+		stats.CurrentStreak++
+		stats.LongestStreak = max(stats.LongestStreak, stats.CurrentStreak)
+	}
+
+	return stats
+}
+
+// CompletionRate returns the percentage of periods completed.
+func (s PeriodStats) CompletionRate() float64 {
+	if s.Periods == 0 {
+		return 0
+	}
+	return float64(s.Completed) / float64(s.Periods) * 100
 }
 
 func (g *Goal) PreviousDueTime(now time.Time) (t time.Time, err error) {
@@ -65,5 +475,84 @@ func (g *Goal) PreviousDueTime(now time.Time) (t time.Time, err error) {
 		return g.DueAt, nil
 	}
 
-	return gronx.PrevTickBefore(g.Cron, now, false)
+	return g.prevTick(now, false)
+}
+
+// Amount returns how much an activity counts towards the goal. Points are
+// awarded according to config, the scoring config of the guild the activity
+// was logged in.
+func (g *Goal) Amount(a *activities.Activity, config *scoring.Config) float64 {
+	switch g.Unit {
+	case UnitDuration, "":
+		return float64(a.Duration)
+	case UnitCharacters, UnitPages, UnitEpisodes:
+		n, _ := a.MetaNumber(g.Unit)
+		return max(n, 0)
+	case UnitActivities:
+		return 1
+	case UnitPoints:
+		if config == nil {
+			config = scoring.DefaultConfig()
+		}
+		return config.Score(a)
+	default:
+		return 0
+	}
+}
+
+// Percentage returns the progress towards the target as a percentage.
+func (g *Goal) Percentage() float64 {
+	return g.Current / g.Target * 100
+}
+
+// ParseAmount parses an amount of a unit, such as a target, from user input.
+// Durations are parsed with timeparse and other amounts are numbers which
+// may use thousands separators or a k suffix, e.g. 100,000 or 100k.
+func ParseAmount(unit, input string) (float64, error) {
+	var (
+		v   float64
+		err error
+	)
+
+	input = strings.TrimSpace(input)
+
+	if unit == UnitDuration {
+		var d time.Duration
+		d, err = timeparse.ParseDuration(input)
+		v = float64(d)
+	} else {
+		multiplier := 1.0
+		input = strings.ReplaceAll(input, ",", "")
+		if s, ok := strings.CutSuffix(strings.ToLower(input), "k"); ok {
+			input, multiplier = s, 1000
+		}
+		v, err = strconv.ParseFloat(input, 64)
+		v *= multiplier
+	}
+
+	if err != nil || v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidAmount, input)
+	}
+
+	return v, nil
+}
+
+// FormatAmount formats an amount of a unit for display, e.g. "1h30m0s" or
+// "100000 characters".
+func FormatAmount(unit string, v float64) string {
+	switch unit {
+	case UnitDuration, "":
+		return time.Duration(v).String()
+	case UnitActivities:
+		if v == 1 {
+			return "1 activity"
+		}
+		return fmt.Sprintf("%.0f activities", v)
+	}
+
+	n := strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	if n == "1" {
+		return n + " " + strings.TrimSuffix(unit, "s")
+	}
+	return n + " " + unit
 }
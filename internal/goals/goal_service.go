@@ -2,66 +2,156 @@ package goals
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/scoring"
 	"github.com/xoltia/botsu/internal/users"
 )
 
 type GoalService struct {
 	*GoalRepository
+	ar *activities.ActivityRepository
 	ts *users.UserTimeService
+	sc *scoring.ScoringService
 }
 
-func NewGoalService(repo *GoalRepository, ts *users.UserTimeService) *GoalService {
-	return &GoalService{repo, ts}
+func NewGoalService(repo *GoalRepository, ar *activities.ActivityRepository, ts *users.UserTimeService, sc *scoring.ScoringService) *GoalService {
+	return &GoalService{repo, ar, ts, sc}
+}
+
+// amount returns how much an activity counts towards a goal. Points are
+// awarded by the scoring config of the goal's guild, or for user goals the
+// guild the activity was logged in. Configs are cached in configs.
+func (s *GoalService) amount(ctx context.Context, configs map[string]*scoring.Config, g *Goal, a *activities.Activity) (float64, error) {
+	guildID := g.GuildID
+	if guildID == nil {
+		guildID = a.GuildID
+	}
+
+	if g.Unit != UnitPoints || guildID == nil {
+		return g.Amount(a, nil), nil
+	}
+
+	config, ok := configs[*guildID]
+	if !ok {
+		var err error
+		config, err = s.sc.GetConfig(ctx, *guildID)
+		if err != nil {
+			return 0, err
+		}
+		configs[*guildID] = config
+	}
+
+	return g.Amount(a, config), nil
+}
+
+// Location returns the timezone of a goal, which is that of its guild for
+// guild goals.
+func (s *GoalService) Location(ctx context.Context, g *Goal) (*time.Location, error) {
+	if g.GuildID != nil {
+		return s.ts.GetGuildTimeLocation(ctx, *g.GuildID)
+	}
+	return s.ts.GetTimeLocation(ctx, g.UserID, "")
+}
+
+// now returns the current time in the timezone of a goal.
+func (s *GoalService) now(ctx context.Context, g *Goal) (time.Time, error) {
+	location, err := s.Location(ctx, g)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().In(location), nil
 }
 
 func (s *GoalService) NextCron(ctx context.Context, g *Goal) (t time.Time, err error) {
-	location, err := s.ts.GetTimeLocation(ctx, g.UserID, "")
+	now, err := s.now(ctx, g)
 	if err != nil {
 		return
 	}
-	now := time.Now().In(location)
 	return g.NextDueTime(now)
 }
 
 func (s *GoalService) PreviousCron(ctx context.Context, g *Goal) (t time.Time, err error) {
-	location, err := s.ts.GetTimeLocation(ctx, g.UserID, "")
+	now, err := s.now(ctx, g)
 	if err != nil {
 		return
 	}
-	now := time.Now().In(location)
 	return g.PreviousDueTime(now)
 }
 
 func (s *GoalService) CheckCompleted(ctx context.Context, a *activities.Activity) (completed []*Goal, err error) {
-	now, err := s.ts.GetTime(ctx, a.UserID, "")
+	return s.checkCompleted(ctx, a.UserID, []*activities.Activity{a}, false)
+}
+
+// CheckCompletedMany adds bulk logged activities, all belonging to the given
+// user, to the progress of their matching goals and returns the goals that
+// were completed by doing so. Bulk logs often backfill old activities, so
+// unlike with CheckCompleted, activities dated before the start of a goal's
+// current period do not count towards it.
+func (s *GoalService) CheckCompletedMany(ctx context.Context, userID string, as []*activities.Activity) (completed []*Goal, err error) {
+	return s.checkCompleted(ctx, userID, as, true)
+}
+
+func (s *GoalService) checkCompleted(ctx context.Context, userID string, as []*activities.Activity, currentPeriodOnly bool) (completed []*Goal, err error) {
+	now, err := s.ts.GetTime(ctx, userID, "")
 	if err != nil {
 		return
 	}
 
-	goals, tx, err := s.BeginUpdateTxByUserID(ctx, a.UserID)
+	goals, tx, err := s.BeginUpdateTxByUserID(ctx, userID)
 	if err != nil {
 		return
 	}
 
 	defer tx.Rollback(ctx) //nolint:errcheck
 
+	configs := make(map[string]*scoring.Config)
+
 	for _, g := range goals {
+		if g.Paused() {
+			continue
+		}
+
 		changed := false
 		if g.IsDue(now) {
-			g.DueAt, err = g.NextDueTime(now)
-			if err != nil {
+			if err = s.rolloverTx(ctx, tx, g, now); err != nil {
 				return
 			}
-			g.Current = 0
 			changed = true
+
+			if g.Ended() {
+				if err = s.UpdateTx(ctx, tx, g); err != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		var periodStart time.Time
+		if currentPeriodOnly {
+			periodStart, err = g.PreviousDueTime(now)
+			if err != nil {
+				return
+			}
 		}
 
 		alreadyCompleted := g.Current >= g.Target
-		if g.MatchesActivity(a) {
-			g.Current += a.Duration
+		for _, a := range as {
+			if a.Date.Before(periodStart) || !g.MatchesActivity(a) {
+				continue
+			}
+
+			var amount float64
+			amount, err = s.amount(ctx, configs, g, a)
+			if err != nil {
+				return
+			}
+
+			g.Current += amount
 			changed = true
 		}
 		if g.Current >= g.Target && !alreadyCompleted {
@@ -94,12 +184,10 @@ func (s *GoalService) CheckAll(ctx context.Context, userID string) (goals []*Goa
 	defer tx.Rollback(ctx) //nolint:errcheck
 
 	for _, g := range goals {
-		if g.IsDue(now) {
-			g.DueAt, err = g.NextDueTime(now)
-			if err != nil {
+		if g.IsDue(now) && !g.Paused() {
+			if err = s.rolloverTx(ctx, tx, g, now); err != nil {
 				return
 			}
-			g.Current = 0
 			err = s.UpdateTx(ctx, tx, g)
 			if err != nil {
 				return
@@ -110,3 +198,328 @@ func (s *GoalService) CheckAll(ctx context.Context, userID string) (goals []*Goa
 	err = tx.Commit(ctx)
 	return
 }
+
+// RecalculateTx recomputes the progress of the current period of every goal
+// belonging to the user from their logged activities, as part of the
+// transaction which changed them after the fact, for example by deleting or
+// restoring them. The recalculated goals are returned.
+func (s *GoalService) RecalculateTx(ctx context.Context, tx pgx.Tx, userID string) (goals []*Goal, err error) {
+	now, err := s.ts.GetTime(ctx, userID, "")
+	if err != nil {
+		return
+	}
+
+	goals, err = s.FindByUserIDForUpdateTx(ctx, tx, userID)
+	if err != nil || len(goals) == 0 {
+		return
+	}
+
+	periodStarts := make([]time.Time, len(goals))
+	earliestStart := now
+
+	for i, g := range goals {
+		if g.Paused() {
+			continue
+		}
+
+		if g.IsDue(now) {
+			if err = s.rolloverTx(ctx, tx, g, now); err != nil {
+				return
+			}
+
+			// Ended goals are paused, so are skipped below
+			if g.Ended() {
+				if err = s.UpdateTx(ctx, tx, g); err != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		periodStarts[i], err = g.PreviousDueTime(now)
+		if err != nil {
+			return
+		}
+
+		if periodStarts[i].Before(earliestStart) {
+			earliestStart = periodStarts[i]
+		}
+	}
+
+	as, err := s.ar.GetByUserIDInRangeTx(ctx, tx, userID, earliestStart, now)
+	if err != nil {
+		return
+	}
+
+	configs := make(map[string]*scoring.Config)
+
+	for i, g := range goals {
+		if g.Paused() {
+			continue
+		}
+
+		g.Current = 0
+		for _, a := range as {
+			if a.Date.Before(periodStarts[i]) || !g.MatchesActivity(a) {
+				continue
+			}
+
+			var amount float64
+			amount, err = s.amount(ctx, configs, g, a)
+			if err != nil {
+				return
+			}
+
+			g.Current += amount
+		}
+
+		if err = s.UpdateTx(ctx, tx, g); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Update locks a goal and applies update to it at the current time in the
+// goal's timezone, recording what changed in its history. Changes to what a
+// goal counts apply to its current period, whose progress is recalculated.
+func (s *GoalService) Update(ctx context.Context, id int64, update func(g *Goal, now time.Time) error) (g *Goal, changes []string, err error) {
+	return s.update(ctx, id, func(g *Goal, now time.Time) ([]*Period, error) {
+		return nil, update(g, now)
+	})
+}
+
+// Resume unpauses a goal, recording the period it was paused in if that
+// period has since ended.
+func (s *GoalService) Resume(ctx context.Context, id int64) (g *Goal, err error) {
+	g, _, err = s.update(ctx, id, func(g *Goal, now time.Time) ([]*Period, error) {
+		if g.Ended() {
+			return nil, ErrEnded
+		}
+		return g.Resume(now)
+	})
+	return
+}
+
+// update is Update with an update which may close periods of the goal,
+// which are recorded along with the changes.
+func (s *GoalService) update(ctx context.Context, id int64, update func(g *Goal, now time.Time) ([]*Period, error)) (g *Goal, changes []string, err error) {
+	g, tx, err := s.BeginUpdateTxByID(ctx, id)
+	if tx != nil {
+		defer tx.Rollback(ctx) //nolint:errcheck
+	}
+	if err != nil {
+		return
+	}
+
+	now, err := s.now(ctx, g)
+	if err != nil {
+		return
+	}
+
+	before := *g
+	closed, err := update(g, now)
+	if err != nil {
+		return
+	}
+
+	changes = DescribeChanges(&before, g, now.Location())
+	if len(changes) == 0 {
+		return
+	}
+
+	if err = s.UpdateTx(ctx, tx, g); err != nil {
+		return
+	}
+
+	if err = s.InsertPeriodsTx(ctx, tx, closed); err != nil {
+		return
+	}
+
+	err = s.InsertChangeTx(ctx, tx, g.ID, &Change{
+		ChangedAt:   now,
+		Description: strings.Join(changes, "\n"),
+	})
+	if err != nil {
+		return
+	}
+
+	// Guild goals are made up of contributions which can't be recalculated
+	if !CountsSame(&before, g) && g.GuildID == nil && !g.Paused() {
+		var recalculated []*Goal
+		recalculated, err = s.RecalculateTx(ctx, tx, g.UserID)
+		if err != nil {
+			return
+		}
+
+		for _, r := range recalculated {
+			if r.ID == g.ID {
+				g = r
+			}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	return
+}
+
+// rolloverTx closes the due periods of a goal, recording them in its history.
+// The goal itself is left for the caller to update. Recorded periods are
+// passed on to the rollover worker's handlers by ClaimClosedPeriods.
+func (s *GoalService) rolloverTx(ctx context.Context, tx pgx.Tx, g *Goal, now time.Time) error {
+	closed, err := g.Rollover(now)
+	if err != nil {
+		return err
+	}
+
+	return s.InsertPeriodsTx(ctx, tx, closed)
+}
+
+// CloseDue closes the due periods of up to limit goals, advancing them to
+// their next period in the timezone of the goal. The number of goals closed
+// is returned, so fewer than limit are closed once no more goals are due.
+func (s *GoalService) CloseDue(ctx context.Context, limit int) (n int, err error) {
+	goals, tx, err := s.BeginCloseDueTx(ctx, time.Now(), limit)
+	if tx != nil {
+		defer tx.Rollback(ctx) //nolint:errcheck
+	}
+	if err != nil {
+		return
+	}
+
+	for _, g := range goals {
+		var now time.Time
+		now, err = s.now(ctx, g)
+		if err != nil {
+			return 0, err
+		}
+
+		if err = s.rolloverTx(ctx, tx, g, now); err != nil {
+			return 0, err
+		}
+
+		if err = s.UpdateTx(ctx, tx, g); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(goals), nil
+}
+
+// ContributeTx recomputes what a user contributed to the current periods of
+// the goals of the guilds they are a member of, as part of the transaction
+// which changed their activities. The member who takes a goal past its
+// target is recorded for it to be announced.
+func (s *GoalService) ContributeTx(ctx context.Context, tx pgx.Tx, userID string) (err error) {
+	goals, err := s.FindGuildGoalsByMemberTx(ctx, tx, userID)
+	if err != nil || len(goals) == 0 {
+		return
+	}
+
+	periodStarts := make([]time.Time, len(goals))
+	var earliestStart, latestNow time.Time
+
+	for i, g := range goals {
+		if g.Paused() {
+			continue
+		}
+
+		var now time.Time
+		now, err = s.now(ctx, g)
+		if err != nil {
+			return
+		}
+
+		if g.IsDue(now) {
+			if err = s.rolloverTx(ctx, tx, g, now); err != nil {
+				return
+			}
+
+			// Ended goals are paused, so are skipped below
+			if g.Ended() {
+				if err = s.UpdateTx(ctx, tx, g); err != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		periodStarts[i], err = g.PreviousDueTime(now)
+		if err != nil {
+			return
+		}
+
+		if earliestStart.IsZero() || periodStarts[i].Before(earliestStart) {
+			earliestStart = periodStarts[i]
+		}
+		if now.After(latestNow) {
+			latestNow = now
+		}
+	}
+
+	if earliestStart.IsZero() {
+		return
+	}
+
+	as, err := s.ar.GetByUserIDInRangeTx(ctx, tx, userID, earliestStart, latestNow)
+	if err != nil {
+		return
+	}
+
+	configs := make(map[string]*scoring.Config)
+
+	for i, g := range goals {
+		if g.Paused() {
+			continue
+		}
+
+		contribution := 0.0
+		for _, a := range as {
+			if a.Date.Before(periodStarts[i]) || !g.MatchesActivity(a) {
+				continue
+			}
+
+			var amount float64
+			amount, err = s.amount(ctx, configs, g, a)
+			if err != nil {
+				return
+			}
+
+			contribution += amount
+		}
+
+		var previous float64
+		previous, err = s.SetContributionTx(ctx, tx, g, userID, contribution)
+		if err != nil {
+			return
+		}
+
+		if previous == contribution {
+			continue
+		}
+
+		wasReached := g.Current >= g.Target
+		g.Current = max(g.Current+contribution-previous, 0)
+
+		if err = s.UpdateTx(ctx, tx, g); err != nil {
+			return
+		}
+
+		switch reached := g.Current >= g.Target; {
+		case reached && !wasReached:
+			err = s.SetReachedByTx(ctx, tx, g.ID, &userID)
+		case !reached && wasReached:
+			err = s.SetReachedByTx(ctx, tx, g.ID, nil)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
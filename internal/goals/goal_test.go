@@ -0,0 +1,221 @@
+package goals_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/internal/scoring"
+	"github.com/xoltia/botsu/internal/videos"
+)
+
+func TestAmount(t *testing.T) {
+	a := activities.NewActivity()
+	a.Duration = 90 * time.Minute
+	a.SetMeta("characters", 12000)
+
+	amount := func(unit string) float64 {
+		g := &goals.Goal{Unit: unit}
+		return g.Amount(a, nil)
+	}
+
+	assert.Equal(t, float64(90*time.Minute), amount(goals.UnitDuration))
+	assert.Equal(t, 12000.0, amount(goals.UnitCharacters))
+	assert.Equal(t, 0.0, amount(goals.UnitPages))
+	assert.Equal(t, 1.0, amount(goals.UnitActivities))
+	assert.Equal(t, 90.0, amount(goals.UnitPoints))
+
+	c := &scoring.Config{}
+	assert.NoError(t, c.SetRule(scoring.Rule{Unit: scoring.UnitCharacters, Per: 1000, Points: 1}))
+	assert.Equal(t, 12.0, (&goals.Goal{Unit: goals.UnitPoints}).Amount(a, c))
+}
+
+func TestParseAmount(t *testing.T) {
+	v, err := goals.ParseAmount(goals.UnitDuration, "1h30m")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(90*time.Minute), v)
+
+	v, err = goals.ParseAmount(goals.UnitCharacters, "100k")
+	assert.NoError(t, err)
+	assert.Equal(t, 100000.0, v)
+
+	v, err = goals.ParseAmount(goals.UnitCharacters, "1,500")
+	assert.NoError(t, err)
+	assert.Equal(t, 1500.0, v)
+
+	_, err = goals.ParseAmount(goals.UnitEpisodes, "0")
+	assert.ErrorIs(t, err, goals.ErrInvalidAmount)
+
+	_, err = goals.ParseAmount(goals.UnitEpisodes, "a few")
+	assert.ErrorIs(t, err, goals.ErrInvalidAmount)
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "1h30m0s", goals.FormatAmount(goals.UnitDuration, float64(90*time.Minute)))
+	assert.Equal(t, "100000 characters", goals.FormatAmount(goals.UnitCharacters, 100000))
+	assert.Equal(t, "1 episode", goals.FormatAmount(goals.UnitEpisodes, 1))
+	assert.Equal(t, "12.35 points", goals.FormatAmount(goals.UnitPoints, 12.345))
+	assert.Equal(t, "3 activities", goals.FormatAmount(goals.UnitActivities, 3))
+}
+
+func TestReminderDue(t *testing.T) {
+	due := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	g := &goals.Goal{Target: float64(time.Hour), Current: float64(15 * time.Minute), DueAt: due}
+	reminders := []time.Duration{24 * time.Hour, 6 * time.Hour}
+
+	assert.False(t, g.ReminderDue(reminders, due.Add(-25*time.Hour)))
+	assert.True(t, g.ReminderDue(reminders, due.Add(-20*time.Hour)))
+
+	// Reminded for the first reminder but not the second
+	remindedAt := due.Add(-20 * time.Hour)
+	g.RemindedAt = &remindedAt
+	assert.False(t, g.ReminderDue(reminders, due.Add(-10*time.Hour)))
+	assert.True(t, g.ReminderDue(reminders, due.Add(-5*time.Hour)))
+	assert.False(t, g.ReminderDue(reminders, due.Add(time.Minute)))
+
+	g.Current = g.Target
+	assert.False(t, g.ReminderDue(reminders, due.Add(-5*time.Hour)))
+}
+
+func TestRollover(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	g := &goals.Goal{
+		Cron:      "@daily",
+		Target:    float64(time.Hour),
+		Current:   float64(2 * time.Hour),
+		DueAt:     time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		CreatedAt: created,
+	}
+
+	closed, err := g.Rollover(time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, closed, 3)
+
+	// The first period started when the goal was created
+	assert.Equal(t, created, closed[0].Start)
+	assert.True(t, closed[0].Completed())
+	assert.Equal(t, time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), closed[1].End)
+	assert.False(t, closed[1].Completed())
+	assert.Equal(t, closed[1].End, closed[2].Start)
+
+	assert.Equal(t, time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC), g.DueAt)
+	assert.Zero(t, g.Current)
+
+	closed, err = g.Rollover(time.Date(2024, 5, 4, 10, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Empty(t, closed)
+}
+
+func TestPeriodStats(t *testing.T) {
+	stats := goals.NewPeriodStats([]bool{true, true, false, true, true, true, false, true})
+	assert.Equal(t, 8, stats.Periods)
+	assert.Equal(t, 6, stats.Completed)
+	assert.Equal(t, 3, stats.LongestStreak)
+	assert.Equal(t, 1, stats.CurrentStreak)
+	assert.Equal(t, 75.0, stats.CompletionRate())
+
+	assert.Zero(t, goals.NewPeriodStats(nil).CompletionRate())
+}
+
+func TestResume(t *testing.T) {
+	paused := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	g := &goals.Goal{
+		Cron:    "@daily",
+		Current: float64(time.Hour),
+		DueAt:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	_, err := g.Resume(paused)
+	assert.ErrorIs(t, err, goals.ErrNotPaused)
+	assert.NoError(t, g.Pause(paused))
+	assert.ErrorIs(t, g.Pause(paused), goals.ErrPaused)
+
+	// The paused period is closed and those missed after it are skipped
+	closed, err := g.Resume(time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if assert.Len(t, closed, 1) {
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), closed[0].Start)
+		assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), closed[0].End)
+		assert.Equal(t, float64(time.Hour), closed[0].Achieved)
+	}
+	assert.False(t, g.Paused())
+	assert.Zero(t, g.Current)
+	assert.Equal(t, time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC), g.DueAt)
+}
+
+func TestDescribeChanges(t *testing.T) {
+	reading := "reading"
+	before := &goals.Goal{
+		Name:   "Reading",
+		Unit:   goals.UnitDuration,
+		Target: float64(time.Hour),
+		Cron:   "@daily",
+	}
+
+	after := *before
+	assert.Empty(t, goals.DescribeChanges(before, &after, time.UTC))
+
+	after.Unit = goals.UnitPages
+	after.Target = 50
+	after.ActivityType = &reading
+	after.YoutubeChannels = []string{"@HakuiKoyori"}
+	after.Cron = "0 0 * * 1"
+
+	assert.Equal(t, []string{
+		"Target: 1h0m0s → 50 pages",
+		"Schedule: Every day at midnight → Every Monday at midnight",
+		"Activity type: any → reading",
+		"YouTube channels: any → @HakuiKoyori",
+	}, goals.DescribeChanges(before, &after, time.UTC))
+}
+
+func TestMatchesActivity(t *testing.T) {
+	anime := activities.ActivityMediaTypeAnime
+	work := "anidb:69"
+	tag := "#Slice of Life"
+	title := "one piece"
+
+	a := activities.NewActivity()
+	a.Name = "One Piece"
+	a.MediaType = &anime
+	a.SetMeta("anidb_id", "69")
+	a.SetMeta("tags", []string{"slice of life", "comedy"})
+
+	g := &goals.Goal{Work: &work, Tag: &tag, NameContains: &title}
+	assert.True(t, g.MatchesActivity(a))
+
+	other := "vndb:v17"
+	g.Work = &other
+	assert.False(t, g.MatchesActivity(a))
+
+	// Channels are matched by ID from both live and persisted meta
+	video := &goals.Goal{YoutubeChannels: []string{"@HakuiKoyori"}, YoutubeChannelIDs: []string{"UC1DCedRgGHBdm81E1llLhOQ"}}
+	a.Meta = &videos.VideoInfo{ChannelID: "UC1DCedRgGHBdm81E1llLhOQ", ChannelHandle: "@koyori"}
+	assert.True(t, video.MatchesActivity(a))
+
+	a.Meta = map[string]interface{}{"channel_id": "UC1DCedRgGHBdm81E1llLhOQ", "hashtags": []interface{}{"#hololive"}}
+	assert.True(t, video.MatchesActivity(a))
+
+	hashtag := "hololive"
+	video.Tag = &hashtag
+	assert.True(t, video.MatchesActivity(a))
+}
+
+func TestParseWork(t *testing.T) {
+	for input, expected := range map[string]string{
+		"anidb:69":                   "anidb:69",
+		"https://anidb.net/anime/69": "anidb:69",
+		"https://anidb.net/a69":      "anidb:69",
+		"VNDB:v17":                   "vndb:v17",
+		"https://vndb.org/v17/chars": "vndb:v17",
+	} {
+		work, err := goals.ParseWork(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, work)
+	}
+
+	_, err := goals.ParseWork("vndb:17")
+	assert.ErrorIs(t, err, goals.ErrInvalidWork)
+}
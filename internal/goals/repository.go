@@ -2,11 +2,15 @@ package goals
 
 import (
 	"context"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const goalColumns = `id, user_id, name, activity_type, media_type, youtube_channels, unit, target, current, cron, due_at, created_at, summary, reminded_at, guild_id, channel_id, message_id, paused_at, work, name_contains, tag, youtube_channel_ids, interval_days`
+
 type GoalRepository struct {
 	pool *pgxpool.Pool
 }
@@ -15,21 +19,82 @@ func NewGoalRepository(pool *pgxpool.Pool) *GoalRepository {
 	return &GoalRepository{pool}
 }
 
+// scanGoal scans a row selected with goalColumns.
+func scanGoal(row pgx.Row) (*Goal, error) {
+	g := &Goal{}
+	err := row.Scan(
+		&g.ID,
+		&g.UserID,
+		&g.Name,
+		&g.ActivityType,
+		&g.MediaType,
+		&g.YoutubeChannels,
+		&g.Unit,
+		&g.Target,
+		&g.Current,
+		&g.Cron,
+		&g.DueAt,
+		&g.CreatedAt,
+		&g.Summary,
+		&g.RemindedAt,
+		&g.GuildID,
+		&g.ChannelID,
+		&g.MessageID,
+		&g.PausedAt,
+		&g.Work,
+		&g.NameContains,
+		&g.Tag,
+		&g.YoutubeChannelIDs,
+		&g.IntervalDays,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func collectGoals(rows pgx.Rows) (goals []*Goal, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		var g *Goal
+		g, err = scanGoal(rows)
+		if err != nil {
+			return
+		}
+
+		goals = append(goals, g)
+	}
+
+	err = rows.Err()
+	return
+}
+
 func (r *GoalRepository) Create(ctx context.Context, g *Goal) (err error) {
 	err = r.pool.QueryRow(
 		ctx,
-		`INSERT INTO goals (user_id, name, activity_type, media_type, youtube_channels, target, current, cron, due_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		`INSERT INTO goals (user_id, name, activity_type, media_type, youtube_channels, unit, target, current, cron, due_at, summary, guild_id, channel_id, message_stale, work, name_contains, tag, youtube_channel_ids, interval_days, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $12 IS NOT NULL, $14, $15, $16, $17, $18, NOW())
 		RETURNING id`,
 		g.UserID,
 		g.Name,
 		g.ActivityType,
 		g.MediaType,
 		g.YoutubeChannels,
+		g.Unit,
 		g.Target,
 		g.Current,
 		g.Cron,
 		g.DueAt,
+		g.Summary,
+		g.GuildID,
+		g.ChannelID,
+		g.Work,
+		g.NameContains,
+		g.Tag,
+		g.YoutubeChannelIDs,
+		g.IntervalDays,
 	).Scan(&g.ID)
 
 	return
@@ -37,68 +102,98 @@ func (r *GoalRepository) Create(ctx context.Context, g *Goal) (err error) {
 
 func (r *GoalRepository) FindByID(ctx context.Context, id int64) (goal *Goal, err error) {
 	row := r.pool.QueryRow(ctx, `
-		SELECT id, user_id, name, activity_type, media_type, youtube_channels, target, current, cron, due_at, created_at
-		FROM goals		
+		SELECT `+goalColumns+`
+		FROM goals
 		WHERE deleted_at IS NULL
 		AND id = $1
 	`, id)
 
-	goal = &Goal{}
-
-	err = row.Scan(
-		&goal.ID,
-		&goal.UserID,
-		&goal.Name,
-		&goal.ActivityType,
-		&goal.MediaType,
-		&goal.YoutubeChannels,
-		&goal.Target,
-		&goal.Current,
-		&goal.Cron,
-		&goal.DueAt,
-		&goal.CreatedAt,
-	)
-
-	return
+	return scanGoal(row)
 }
 
 func (r *GoalRepository) FindByUserID(ctx context.Context, userID string) (goals []*Goal, err error) {
 	rows, err := r.pool.Query(
 		ctx,
-		`SELECT id, user_id, name, activity_type, media_type, youtube_channels, target, current, cron, due_at, created_at
+		`SELECT `+goalColumns+`
 		FROM goals
 		WHERE deleted_at IS NULL
+		AND guild_id IS NULL
 		AND user_id = $1`,
 		userID,
 	)
 	if err != nil {
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		g := &Goal{}
-		err = rows.Scan(
-			&g.ID,
-			&g.UserID,
-			&g.Name,
-			&g.ActivityType,
-			&g.MediaType,
-			&g.YoutubeChannels,
-			&g.Target,
-			&g.Current,
-			&g.Cron,
-			&g.DueAt,
-			&g.CreatedAt,
-		)
-		if err != nil {
-			return
-		}
+	return collectGoals(rows)
+}
 
-		goals = append(goals, g)
+// FindAllByUserID returns every goal created by a user, including guild goals
+// and deleted goals.
+func (r *GoalRepository) FindAllByUserID(ctx context.Context, userID string) (goals []*Goal, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE user_id = $1
+		ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return
 	}
 
-	return
+	return collectGoals(rows)
+}
+
+// FindRemindable returns the unfinished goals due within horizon of now
+// whose users have reminders enabled.
+func (r *GoalRepository) FindRemindable(ctx context.Context, now time.Time, horizon time.Duration) (goals []*Goal, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND guild_id IS NULL
+		AND paused_at IS NULL
+		AND current < target
+		AND due_at > $1
+		AND due_at <= $2
+		AND EXISTS (
+			SELECT 1
+			FROM users
+			WHERE users.id = goals.user_id
+			AND cardinality(users.goal_reminders) > 0
+		)`,
+		now,
+		now.Add(horizon),
+	)
+	if err != nil {
+		return
+	}
+
+	return collectGoals(rows)
+}
+
+// ClaimReminder records a reminder of a goal at t unless the goal's reminder
+// time has changed from g.RemindedAt, so that only one instance of the bot
+// sends each reminder.
+func (r *GoalRepository) ClaimReminder(ctx context.Context, g *Goal, t time.Time) (bool, error) {
+	tag, err := r.pool.Exec(
+		ctx,
+		`UPDATE goals
+		SET reminded_at = $1
+		WHERE id = $2
+		AND reminded_at IS NOT DISTINCT FROM $3`,
+		t,
+		g.ID,
+		g.RemindedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
 }
 
 func (r *GoalRepository) BeginUpdateTxByUserID(ctx context.Context, userID string) (goals []*Goal, tx pgx.Tx, err error) {
@@ -107,11 +202,19 @@ func (r *GoalRepository) BeginUpdateTxByUserID(ctx context.Context, userID strin
 		return
 	}
 
+	goals, err = r.FindByUserIDForUpdateTx(ctx, tx, userID)
+	return
+}
+
+// FindByUserIDForUpdateTx locks the goals of a user as part of a larger
+// transaction.
+func (r *GoalRepository) FindByUserIDForUpdateTx(ctx context.Context, tx pgx.Tx, userID string) (goals []*Goal, err error) {
 	rows, err := tx.Query(
 		ctx,
-		`SELECT id, user_id, name, activity_type, media_type, youtube_channels, target, current, cron, due_at, created_at
+		`SELECT `+goalColumns+`
 		FROM goals
 		WHERE user_id = $1
+		AND guild_id IS NULL
 		AND DELETED_AT IS NULL
 		FOR UPDATE`,
 		userID,
@@ -120,55 +223,518 @@ func (r *GoalRepository) BeginUpdateTxByUserID(ctx context.Context, userID strin
 		return
 	}
 
-	defer rows.Close()
-
-	for rows.Next() {
-		g := &Goal{}
-		err = rows.Scan(
-			&g.ID,
-			&g.UserID,
-			&g.Name,
-			&g.ActivityType,
-			&g.MediaType,
-			&g.YoutubeChannels,
-			&g.Target,
-			&g.Current,
-			&g.Cron,
-			&g.DueAt,
-			&g.CreatedAt,
-		)
+	return collectGoals(rows)
+}
 
-		if err != nil {
-			return
-		}
+// BeginCloseDueTx locks up to limit goals due at now, the longest overdue
+// first. Goals locked by another transaction are skipped, so that only one
+// instance of the bot closes each period.
+func (r *GoalRepository) BeginCloseDueTx(ctx context.Context, now time.Time, limit int) (goals []*Goal, tx pgx.Tx, err error) {
+	tx, err = r.pool.Begin(ctx)
+	if err != nil {
+		return
+	}
 
-		goals = append(goals, g)
+	rows, err := tx.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND paused_at IS NULL
+		AND due_at <= $1
+		ORDER BY due_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`,
+		now,
+		limit,
+	)
+	if err != nil {
+		return
 	}
 
+	goals, err = collectGoals(rows)
 	return
 }
 
 func (r *GoalRepository) UpdateTx(ctx context.Context, tx pgx.Tx, g *Goal) (err error) {
+	// The progress message of a guild goal is refreshed after any update
 	_, err = tx.Exec(
 		ctx,
 		`UPDATE goals
-		SET name = $1, activity_type = $2, media_type = $3, youtube_channels = $4, target = $5, current = $6, cron = $7, due_at = $8
-		WHERE id = $9`,
+		SET name = $1, activity_type = $2, media_type = $3, youtube_channels = $4, unit = $5, target = $6, current = $7, cron = $8, due_at = $9, summary = $10, paused_at = $11,
+			work = $12, name_contains = $13, tag = $14, youtube_channel_ids = $15, interval_days = $16,
+			message_stale = guild_id IS NOT NULL,
+			-- Periods which closed before being announced are summarized instead
+			reached_by = CASE WHEN due_at = $9 THEN reached_by END
+		WHERE id = $17`,
 		g.Name,
 		g.ActivityType,
 		g.MediaType,
 		g.YoutubeChannels,
+		g.Unit,
 		g.Target,
 		g.Current,
 		g.Cron,
 		g.DueAt,
+		g.Summary,
+		g.PausedAt,
+		g.Work,
+		g.NameContains,
+		g.Tag,
+		g.YoutubeChannelIDs,
+		g.IntervalDays,
 		g.ID,
 	)
 
 	return
 }
 
+// BeginUpdateTxByID locks a goal for it to be changed.
+func (r *GoalRepository) BeginUpdateTxByID(ctx context.Context, id int64) (goal *Goal, tx pgx.Tx, err error) {
+	tx, err = r.pool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	row := tx.QueryRow(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE id = $1
+		AND deleted_at IS NULL
+		FOR UPDATE`,
+		id,
+	)
+
+	goal, err = scanGoal(row)
+	return
+}
+
+// InsertChangeTx records a change to a goal in its history.
+func (r *GoalRepository) InsertChangeTx(ctx context.Context, tx pgx.Tx, goalID int64, c *Change) (err error) {
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO goal_changes (goal_id, changed_at, description)
+		VALUES ($1, $2, $3)`,
+		goalID,
+		c.ChangedAt,
+		c.Description,
+	)
+
+	return
+}
+
+// FindChanges returns up to limit of the latest changes to a goal, from
+// newest to oldest.
+func (r *GoalRepository) FindChanges(ctx context.Context, goalID int64, limit int) (changes []Change, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT changed_at, description
+		FROM goal_changes
+		WHERE goal_id = $1
+		ORDER BY changed_at DESC
+		LIMIT $2`,
+		goalID,
+		limit,
+	)
+	if err != nil {
+		return
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Change])
+}
+
 func (r *GoalRepository) DeleteByID(ctx context.Context, id int64) (err error) {
 	_, err = r.pool.Exec(ctx, "UPDATE GOALS SET deleted_at = (NOW() AT TIME ZONE 'UTC') WHERE id = $1", id)
 	return
 }
+
+// FindByGuildID returns the goals of a guild.
+func (r *GoalRepository) FindByGuildID(ctx context.Context, guildID string) (goals []*Goal, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND guild_id = $1
+		ORDER BY id`,
+		guildID,
+	)
+	if err != nil {
+		return
+	}
+
+	return collectGoals(rows)
+}
+
+// FindGuildGoalsByMemberTx locks the goals of the guilds a user is a member
+// of as part of a larger transaction.
+func (r *GoalRepository) FindGuildGoalsByMemberTx(ctx context.Context, tx pgx.Tx, userID string) (goals []*Goal, err error) {
+	rows, err := tx.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND guild_id IN (
+			SELECT guild_id
+			FROM guild_members
+			WHERE user_id = $1
+		)
+		ORDER BY id
+		FOR UPDATE`,
+		userID,
+	)
+	if err != nil {
+		return
+	}
+
+	return collectGoals(rows)
+}
+
+// SetContributionTx sets what a member contributed to the current period of
+// a guild goal, returning what they had contributed before.
+func (r *GoalRepository) SetContributionTx(ctx context.Context, tx pgx.Tx, g *Goal, userID string, amount float64) (previous float64, err error) {
+	err = tx.QueryRow(
+		ctx,
+		`SELECT COALESCE((
+			SELECT amount
+			FROM goal_contributions
+			WHERE goal_id = $1
+			AND period_end = $2
+			AND user_id = $3
+		), 0)`,
+		g.ID,
+		g.DueAt,
+		userID,
+	).Scan(&previous)
+	if err != nil || previous == amount {
+		return
+	}
+
+	if amount == 0 {
+		_, err = tx.Exec(
+			ctx,
+			`DELETE FROM goal_contributions
+			WHERE goal_id = $1
+			AND period_end = $2
+			AND user_id = $3`,
+			g.ID,
+			g.DueAt,
+			userID,
+		)
+		return
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO goal_contributions (goal_id, period_end, user_id, amount)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (goal_id, period_end, user_id) DO UPDATE
+		SET amount = EXCLUDED.amount`,
+		g.ID,
+		g.DueAt,
+		userID,
+		amount,
+	)
+
+	return
+}
+
+// SetReachedByTx sets or clears the member who took the current period of a
+// guild goal past its target, to be announced.
+func (r *GoalRepository) SetReachedByTx(ctx context.Context, tx pgx.Tx, goalID int64, userID *string) (err error) {
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE goals
+		SET reached_by = $2
+		WHERE id = $1`,
+		goalID,
+		userID,
+	)
+
+	return
+}
+
+// Reached is a guild goal whose current period a member took past its
+// target.
+type Reached struct {
+	Goal   *Goal
+	UserID string
+}
+
+// ClaimReached returns the guild goals which have been taken past their
+// target since they were last claimed, along with who took them past it.
+// Goals claimed by another instance of the bot are skipped, so each is
+// announced once.
+func (r *GoalRepository) ClaimReached(ctx context.Context) (reached []Reached, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`WITH claimed AS (
+			SELECT id AS claimed_id, reached_by AS claimed_by
+			FROM goals
+			WHERE reached_by IS NOT NULL
+			AND deleted_at IS NULL
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE goals
+		SET reached_by = NULL
+		FROM claimed
+		WHERE id = claimed_id
+		RETURNING id, claimed_by`,
+	)
+	if err != nil {
+		return
+	}
+
+	claimed, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Reached, error) {
+		r := Reached{Goal: &Goal{}}
+		err := row.Scan(&r.Goal.ID, &r.UserID)
+		return r, err
+	})
+	if err != nil || len(claimed) == 0 {
+		return
+	}
+
+	goalIDs := make([]int64, len(claimed))
+	for i, r := range claimed {
+		goalIDs[i] = r.Goal.ID
+	}
+
+	rows, err = r.pool.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND id = ANY($1)`,
+		goalIDs,
+	)
+	if err != nil {
+		return
+	}
+
+	goals, err := collectGoals(rows)
+	if err != nil {
+		return
+	}
+
+	byID := make(map[int64]*Goal, len(goals))
+	for _, g := range goals {
+		byID[g.ID] = g
+	}
+
+	for _, r := range claimed {
+		if g, ok := byID[r.Goal.ID]; ok {
+			r.Goal = g
+			reached = append(reached, r)
+		}
+	}
+
+	return
+}
+
+// FindTopContributors returns up to limit of the members who contributed the
+// most to the period of a guild goal ending at periodEnd.
+func (r *GoalRepository) FindTopContributors(ctx context.Context, goalID int64, periodEnd time.Time, limit int) (contributions []Contribution, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT user_id, amount
+		FROM goal_contributions
+		WHERE goal_id = $1
+		AND period_end = $2
+		ORDER BY amount DESC
+		LIMIT $3`,
+		goalID,
+		periodEnd,
+		limit,
+	)
+	if err != nil {
+		return
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Contribution])
+}
+
+// FindStaleMessages returns the guild goals whose progress messages are out
+// of date.
+func (r *GoalRepository) FindStaleMessages(ctx context.Context) (goals []*Goal, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND message_stale
+		AND channel_id IS NOT NULL`,
+	)
+	if err != nil {
+		return
+	}
+
+	return collectGoals(rows)
+}
+
+// SetMessage records the progress message of a guild goal as up to date.
+// A nil messageID leaves the goal without a message until it is next updated.
+func (r *GoalRepository) SetMessage(ctx context.Context, goalID int64, messageID *string) (err error) {
+	_, err = r.pool.Exec(
+		ctx,
+		`UPDATE goals
+		SET message_id = $1, message_stale = FALSE
+		WHERE id = $2`,
+		messageID,
+		goalID,
+	)
+
+	return
+}
+
+// InsertPeriodsTx records closed periods in the history of their goals.
+func (r *GoalRepository) InsertPeriodsTx(ctx context.Context, tx pgx.Tx, periods []*Period) error {
+	for _, p := range periods {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO goal_periods (goal_id, start_at, end_at, target, achieved, completed)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (goal_id, end_at) DO NOTHING`,
+			p.Goal.ID,
+			p.Start,
+			p.End,
+			p.Target,
+			p.Achieved,
+			p.Completed(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimClosedPeriods marks up to limit recorded periods, the oldest first,
+// as passed on to handlers and returns them with their goals. Periods claimed
+// by another instance of the bot are skipped, so each is claimed once.
+func (r *GoalRepository) ClaimClosedPeriods(ctx context.Context, limit int) (periods []*Period, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`UPDATE goal_periods
+		SET notified_at = NOW()
+		WHERE id IN (
+			SELECT p.id
+			FROM goal_periods p
+			JOIN goals g ON g.id = p.goal_id
+			WHERE p.notified_at IS NULL
+			AND g.deleted_at IS NULL
+			ORDER BY p.end_at
+			LIMIT $1
+			FOR UPDATE OF p SKIP LOCKED
+		)
+		RETURNING goal_id, start_at, end_at, target, achieved`,
+		limit,
+	)
+	if err != nil {
+		return
+	}
+
+	claimed, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Period, error) {
+		p := &Period{Goal: &Goal{}}
+		err := row.Scan(&p.Goal.ID, &p.Start, &p.End, &p.Target, &p.Achieved)
+		return p, err
+	})
+	if err != nil || len(claimed) == 0 {
+		return
+	}
+
+	goalIDs := make([]int64, len(claimed))
+	for i, p := range claimed {
+		goalIDs[i] = p.Goal.ID
+	}
+
+	rows, err = r.pool.Query(
+		ctx,
+		`SELECT `+goalColumns+`
+		FROM goals
+		WHERE deleted_at IS NULL
+		AND id = ANY($1)`,
+		goalIDs,
+	)
+	if err != nil {
+		return
+	}
+
+	goals, err := collectGoals(rows)
+	if err != nil {
+		return
+	}
+
+	byID := make(map[int64]*Goal, len(goals))
+	for _, g := range goals {
+		byID[g.ID] = g
+	}
+
+	// Goals deleted since their periods were claimed are skipped
+	for _, p := range claimed {
+		if g, ok := byID[p.Goal.ID]; ok {
+			p.Goal = g
+			periods = append(periods, p)
+		}
+	}
+
+	slices.SortFunc(periods, func(a, b *Period) int {
+		return a.End.Compare(b.End)
+	})
+
+	return
+}
+
+// FindPeriods returns up to limit of the latest closed periods of a goal,
+// from newest to oldest.
+func (r *GoalRepository) FindPeriods(ctx context.Context, g *Goal, limit int) (periods []*Period, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT start_at, end_at, target, achieved
+		FROM goal_periods
+		WHERE goal_id = $1
+		ORDER BY end_at DESC
+		LIMIT $2`,
+		g.ID,
+		limit,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := &Period{Goal: g}
+		if err = rows.Scan(&p.Start, &p.End, &p.Target, &p.Achieved); err != nil {
+			return
+		}
+
+		periods = append(periods, p)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// GetPeriodStats summarizes every closed period of a goal.
+func (r *GoalRepository) GetPeriodStats(ctx context.Context, goalID int64) (stats PeriodStats, err error) {
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT completed
+		FROM goal_periods
+		WHERE goal_id = $1
+		ORDER BY end_at`,
+		goalID,
+	)
+	if err != nil {
+		return
+	}
+
+	completed, err := pgx.CollectRows(rows, pgx.RowTo[bool])
+	if err != nil {
+		return
+	}
+
+	return NewPeriodStats(completed), nil
+}
@@ -0,0 +1,319 @@
+package goals
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	ErrDeadlinePassed  = errors.New("deadline has passed")
+	ErrEnded           = errors.New("goal has ended")
+)
+
+// Most days between the ends of periods of an interval schedule.
+const maxIntervalDays = 365
+
+// Schedule is when the periods of a goal end, in the goal's timezone. Periods
+// end on the ticks of a cron expression, at midnight every IntervalDays days,
+// or, for one-off goals with neither, once at Deadline.
+type Schedule struct {
+	Cron         string
+	IntervalDays int
+	Deadline     time.Time
+}
+
+func (s Schedule) IsDeadline() bool {
+	return s.Cron == "" && s.IntervalDays == 0
+}
+
+func (s Schedule) Equal(o Schedule) bool {
+	return s.Cron == o.Cron && s.IntervalDays == o.IntervalDays && s.Deadline.Equal(o.Deadline)
+}
+
+var weekdays = [...]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// Cron expressions of the schedule presets, other than the weekdays.
+var schedulePresets = map[string]string{
+	"daily":     "@daily",
+	"every day": "@daily",
+	"weekly":    "@weekly",
+	"monthly":   "@monthly",
+	"yearly":    "@yearly",
+}
+
+var (
+	weeklyRegex   = regexp.MustCompile(`^(?:weekly(?: on)?|every) ([a-z]+)$`)
+	intervalRegex = regexp.MustCompile(`^every (\d+) ?(?:days?|d)$`)
+)
+
+// ParseSchedule parses a schedule preset, such as daily, weekly on monday,
+// monthly or every 3 days, a cron expression, or the date of a deadline
+// (YYYY-MM-DD). Deadlines are at the end of their date in the location of
+// now, and must be after now.
+func ParseSchedule(input string, now time.Time) (s Schedule, err error) {
+	cron := strings.TrimSpace(input)
+	input = strings.Join(strings.Fields(strings.ToLower(input)), " ")
+
+	if preset, ok := schedulePresets[input]; ok {
+		s.Cron = preset
+		return
+	}
+
+	weekday := input
+	if m := weeklyRegex.FindStringSubmatch(input); m != nil {
+		weekday = m[1]
+	}
+
+	for i, day := range weekdays {
+		if weekday == day || (len(weekday) >= 3 && strings.HasPrefix(day, weekday)) {
+			s.Cron = fmt.Sprintf("0 0 * * %d", i)
+			return
+		}
+	}
+
+	if m := intervalRegex.FindStringSubmatch(input); m != nil {
+		days, err := strconv.Atoi(m[1])
+		if err != nil || days < 1 || days > maxIntervalDays {
+			return s, ErrInvalidSchedule
+		} else if days == 1 {
+			s.Cron = "@daily"
+		} else {
+			s.IntervalDays = days
+		}
+		return s, nil
+	}
+
+	if date, err := time.ParseInLocation(time.DateOnly, input, now.Location()); err == nil {
+		s.Deadline = date.AddDate(0, 0, 1)
+		if !s.Deadline.After(now) {
+			return Schedule{}, ErrDeadlinePassed
+		}
+		return s, nil
+	}
+
+	gron := gronx.New()
+	if !gron.IsValid(cron) {
+		return s, ErrInvalidSchedule
+	}
+
+	s.Cron = cron
+	return
+}
+
+// Fields of the cron expressions equivalent to the macros supported by
+// gronx.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var singleNumberRegex = regexp.MustCompile(`^\d+$`)
+
+// Describe describes the schedule in words, with deadlines in loc. Cron
+// expressions other than the common ones are given as they are.
+func (s Schedule) Describe(loc *time.Location) string {
+	switch {
+	case s.IntervalDays > 0:
+		return fmt.Sprintf("Every %d days at midnight", s.IntervalDays)
+	case s.IsDeadline():
+		// Deadlines are at the midnight ending their date
+		return "Once, by the end of " + s.Deadline.In(loc).Add(-time.Nanosecond).Format("Monday, January 2, 2006")
+	}
+
+	expr := s.Cron
+	if fields, ok := cronMacros[expr]; ok {
+		expr = fields
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return fmt.Sprintf("On the cron schedule `%s`", s.Cron)
+	}
+
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	if !singleNumberRegex.MatchString(minute) {
+		return fmt.Sprintf("On the cron schedule `%s`", s.Cron)
+	} else if hour == "*" && dom == "*" && month == "*" && dow == "*" {
+		if minute == "0" {
+			return "Every hour"
+		}
+		return fmt.Sprintf("Every hour at %s minutes past", minute)
+	} else if !singleNumberRegex.MatchString(hour) {
+		return fmt.Sprintf("On the cron schedule `%s`", s.Cron)
+	}
+
+	m, _ := strconv.Atoi(minute)
+	h, _ := strconv.Atoi(hour)
+	at := fmt.Sprintf("at %02d:%02d", h, m)
+	if h == 0 && m == 0 {
+		at = "at midnight"
+	}
+
+	switch {
+	case dom == "*" && month == "*" && dow == "*":
+		return "Every day " + at
+	case dom == "*" && month == "*":
+		days, ok := describeWeekdays(dow)
+		if ok {
+			return fmt.Sprintf("Every %s %s", days, at)
+		}
+	case singleNumberRegex.MatchString(dom) && month == "*" && dow == "*":
+		d, _ := strconv.Atoi(dom)
+		return fmt.Sprintf("On the %s of every month %s", ordinal(d), at)
+	case singleNumberRegex.MatchString(dom) && singleNumberRegex.MatchString(month) && dow == "*":
+		d, _ := strconv.Atoi(dom)
+		mon, _ := strconv.Atoi(month)
+		if mon >= 1 && mon <= 12 {
+			return fmt.Sprintf("Every year on %s %d %s", time.Month(mon), d, at)
+		}
+	}
+
+	return fmt.Sprintf("On the cron schedule `%s`", s.Cron)
+}
+
+// describeWeekdays describes a comma separated list of days of the week, as
+// numbers, in a cron expression.
+func describeWeekdays(dow string) (string, bool) {
+	var names []string
+	for _, part := range strings.Split(dow, ",") {
+		if !singleNumberRegex.MatchString(part) {
+			return "", false
+		}
+
+		// Both 0 and 7 are Sunday
+		d, _ := strconv.Atoi(part)
+		if d > 7 {
+			return "", false
+		}
+		names = append(names, time.Weekday(d%7).String())
+	}
+
+	if len(names) == 1 {
+		return names[0], true
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1], true
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// Schedule returns when the goal's periods end.
+func (g *Goal) Schedule() Schedule {
+	s := Schedule{Cron: g.Cron, IntervalDays: g.IntervalDays}
+	if s.IsDeadline() {
+		s.Deadline = g.DueAt
+	}
+	return s
+}
+
+// SetSchedule changes when the goal's periods end, ending its current period
+// on the new schedule as of now. Ended goals are restarted.
+func (g *Goal) SetSchedule(s Schedule, now time.Time) (err error) {
+	if g.Ended() {
+		g.PausedAt = nil
+	}
+
+	g.Cron, g.IntervalDays = s.Cron, s.IntervalDays
+
+	if s.IsDeadline() {
+		g.DueAt = s.Deadline
+		return
+	}
+
+	// Interval schedules are counted from midnight today
+	g.DueAt = time.Time{}
+	g.DueAt, err = g.nextTick(now, true)
+	return
+}
+
+// Ended reports whether the goal is a one-off goal whose only period has
+// closed. Ended goals are paused at their deadline.
+func (g *Goal) Ended() bool {
+	return g.Paused() && g.Schedule().IsDeadline() && !g.PausedAt.Before(g.DueAt)
+}
+
+// nextTick returns the first end of a period after t, or at t if inclusive.
+// Deadlines have no next tick, so one-off goals return ErrEnded.
+func (g *Goal) nextTick(t time.Time, inclusive bool) (time.Time, error) {
+	switch {
+	case g.Cron != "":
+		return gronx.NextTickAfter(g.Cron, t, inclusive)
+	case g.IntervalDays > 0:
+		prev, next := g.intervalTicks(t)
+		if inclusive && prev.Equal(t) {
+			return prev, nil
+		}
+		return next, nil
+	default:
+		return time.Time{}, ErrEnded
+	}
+}
+
+// prevTick returns the last end of a period before t, or at t if inclusive.
+// The only period of one-off goals starts when they are created.
+func (g *Goal) prevTick(t time.Time, inclusive bool) (time.Time, error) {
+	switch {
+	case g.Cron != "":
+		return gronx.PrevTickBefore(g.Cron, t, inclusive)
+	case g.IntervalDays > 0:
+		prev, _ := g.intervalTicks(t)
+		if !inclusive && prev.Equal(t) {
+			return prev.AddDate(0, 0, -g.IntervalDays), nil
+		}
+		return prev, nil
+	default:
+		return g.CreatedAt, nil
+	}
+}
+
+// intervalTicks returns the ticks of an interval schedule either side of t,
+// prev at or before t and next after it. Ticks are counted from the goal's
+// due date, or midnight of t's date if it has none, in t's location.
+func (g *Goal) intervalTicks(t time.Time) (prev, next time.Time) {
+	n := g.IntervalDays
+
+	anchor := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if !g.DueAt.IsZero() {
+		anchor = g.DueAt.In(t.Location())
+	}
+
+	// Days are added by date so that ticks stay at midnight across DST
+	steps := int(t.Sub(anchor).Hours()/24) / n
+	prev = anchor.AddDate(0, 0, steps*n)
+
+	for prev.After(t) {
+		prev = prev.AddDate(0, 0, -n)
+	}
+
+	for next = prev.AddDate(0, 0, n); !next.After(t); next = next.AddDate(0, 0, n) {
+		prev = next
+	}
+
+	return
+}
@@ -0,0 +1,122 @@
+package goals_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/goals"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, tokyo)
+
+	cases := map[string]goals.Schedule{
+		"daily":            {Cron: "@daily"},
+		"Weekly":           {Cron: "@weekly"},
+		"weekly on monday": {Cron: "0 0 * * 1"},
+		"every fri":        {Cron: "0 0 * * 5"},
+		"saturday":         {Cron: "0 0 * * 6"},
+		"monthly":          {Cron: "@monthly"},
+		"every 1 day":      {Cron: "@daily"},
+		"every 3 days":     {IntervalDays: 3},
+		"0 9 * * MON":      {Cron: "0 9 * * MON"},
+		"2024-05-31":       {Deadline: time.Date(2024, 6, 1, 0, 0, 0, 0, tokyo)},
+	}
+
+	for input, expected := range cases {
+		s, err := goals.ParseSchedule(input, now)
+		if assert.NoError(t, err, input) {
+			assert.True(t, expected.Equal(s), input)
+		}
+	}
+
+	for _, input := range []string{"", "fortnightly", "every 0 days", "every 400 days", "2024-13-01", "* * *"} {
+		_, err := goals.ParseSchedule(input, now)
+		assert.ErrorIs(t, err, goals.ErrInvalidSchedule, input)
+	}
+
+	// Deadlines are at the end of their date in the user's timezone
+	_, err = goals.ParseSchedule("2024-04-30", now)
+	assert.ErrorIs(t, err, goals.ErrDeadlinePassed)
+	_, err = goals.ParseSchedule("2024-05-01", now)
+	assert.NoError(t, err)
+}
+
+func TestDescribeSchedule(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	cases := map[string]goals.Schedule{
+		"Every day at midnight":                      {Cron: "@daily"},
+		"Every Sunday at midnight":                   {Cron: "@weekly"},
+		"Every Monday and Thursday at 09:30":         {Cron: "30 9 * * 1,4"},
+		"On the 1st of every month at midnight":      {Cron: "@monthly"},
+		"On the 22nd of every month at 18:00":        {Cron: "0 18 22 * *"},
+		"Every year on January 1 at midnight":        {Cron: "@yearly"},
+		"Every hour at 15 minutes past":              {Cron: "15 * * * *"},
+		"On the cron schedule `*/5 * * * *`":         {Cron: "*/5 * * * *"},
+		"Every 3 days at midnight":                   {IntervalDays: 3},
+		"Once, by the end of Friday, May 31, 2024":   {Deadline: time.Date(2024, 6, 1, 0, 0, 0, 0, tokyo)},
+		"Once, by the end of Saturday, June 1, 2024": {Deadline: time.Date(2024, 6, 2, 0, 0, 0, 0, tokyo)},
+	}
+
+	for expected, s := range cases {
+		assert.Equal(t, expected, s.Describe(tokyo))
+	}
+}
+
+func TestIntervalSchedule(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, tokyo)
+	g := &goals.Goal{CreatedAt: created}
+
+	// The first period ends the given number of days from midnight
+	assert.NoError(t, g.SetSchedule(goals.Schedule{IntervalDays: 3}, created))
+	assert.Equal(t, time.Date(2024, 5, 4, 0, 0, 0, 0, tokyo), g.DueAt)
+
+	start, err := g.PreviousDueTime(created)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, tokyo), start)
+
+	closed, err := g.Rollover(time.Date(2024, 5, 8, 9, 0, 0, 0, tokyo))
+	assert.NoError(t, err)
+	assert.Len(t, closed, 2)
+	assert.Equal(t, created, closed[0].Start)
+	assert.Equal(t, time.Date(2024, 5, 4, 0, 0, 0, 0, tokyo), closed[1].Start)
+	assert.Equal(t, time.Date(2024, 5, 10, 0, 0, 0, 0, tokyo), g.DueAt)
+}
+
+func TestDeadlineSchedule(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deadline := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	g := &goals.Goal{CreatedAt: created, Target: 10, Current: 4}
+
+	assert.NoError(t, g.SetSchedule(goals.Schedule{Deadline: deadline}, created))
+	assert.True(t, g.Schedule().IsDeadline())
+	assert.Equal(t, deadline, g.DueAt)
+
+	start, err := g.PreviousDueTime(created.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, created, start)
+
+	// One-off goals end with their only period
+	closed, err := g.Rollover(deadline.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, deadline, closed[0].End)
+	assert.True(t, g.Ended())
+	_, err = g.Resume(deadline.Add(time.Hour))
+	assert.ErrorIs(t, err, goals.ErrEnded)
+
+	// A new schedule restarts them
+	assert.NoError(t, g.SetSchedule(goals.Schedule{Cron: "@daily"}, deadline.Add(time.Hour)))
+	assert.False(t, g.Ended())
+	assert.False(t, g.Paused())
+	assert.Equal(t, deadline.AddDate(0, 0, 1), g.DueAt)
+}
@@ -1,8 +1,25 @@
 package guilds
 
+import "time"
+
+// DefaultDuplicateWindow is how close together similar activities must be
+// logged to be warned about, unless a guild sets its own window.
+const DefaultDuplicateWindow = 10 * time.Minute
+
 type Guild struct {
 	ID       string
 	Timezone *string
+	// Nil uses DefaultDuplicateWindow, zero disables duplicate warnings
+	DuplicateWindow *time.Duration
+}
+
+// GetDuplicateWindow returns the window of a guild, which may be nil when
+// activities are logged outside of a guild.
+func (g *Guild) GetDuplicateWindow() time.Duration {
+	if g == nil || g.DuplicateWindow == nil {
+		return DefaultDuplicateWindow
+	}
+	return *g.DuplicateWindow
 }
 
 func NewGuild(id string) *Guild {
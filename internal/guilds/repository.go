@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -44,10 +45,10 @@ func (r *GuildRepository) FindByID(ctx context.Context, id string) (*Guild, erro
 	}
 	var guild Guild
 	err := r.pool.QueryRow(ctx,
-		`SELECT id, timezone
+		`SELECT id, timezone, duplicate_window
 		FROM guilds
 		WHERE id = $1;`,
-		id).Scan(&guild.ID, &guild.Timezone)
+		id).Scan(&guild.ID, &guild.Timezone, &guild.DuplicateWindow)
 
 	if err != nil {
 		return nil, err
@@ -99,6 +100,27 @@ func (r *GuildRepository) SetGuildTimezone(ctx context.Context, guildID, timezon
 	return nil
 }
 
+// SetGuildDuplicateWindow sets the duplicate window of a guild, or resets it
+// to the default if window is nil.
+func (r *GuildRepository) SetGuildDuplicateWindow(ctx context.Context, guildID string, window *time.Duration) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE guilds
+		SET duplicate_window = $2
+		WHERE id = $1;`,
+		guildID, window)
+	if err != nil {
+		return err
+	}
+
+	entry, ok := r.cache.Load(guildID)
+	if ok {
+		guild := entry.(*Guild)
+		guild.DuplicateWindow = window
+	}
+
+	return nil
+}
+
 func (r *GuildRepository) RemoveMembers(ctx context.Context, guildID string, userID []string) error {
 	_, err := r.pool.Exec(ctx,
 		`DELETE FROM guild_members
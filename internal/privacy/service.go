@@ -0,0 +1,278 @@
+// Package privacy exports and deletes all data stored about a user.
+package privacy
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/goals"
+	"github.com/xoltia/botsu/internal/timers"
+	"github.com/xoltia/botsu/internal/users"
+	"github.com/xoltia/botsu/internal/works"
+)
+
+type GuildMembership struct {
+	GuildID    string    `json:"guild_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+type GoalPeriod struct {
+	GoalID   int64     `json:"goal_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Target   float64   `json:"target"`
+	Achieved float64   `json:"achieved"`
+}
+
+type GoalChange struct {
+	GoalID      int64     `json:"goal_id"`
+	ChangedAt   time.Time `json:"changed_at"`
+	Description string    `json:"description"`
+}
+
+type GoalContribution struct {
+	GoalID    int64     `json:"goal_id"`
+	PeriodEnd time.Time `json:"period_end"`
+	Amount    float64   `json:"amount"`
+}
+
+// UserData is everything stored about a user.
+type UserData struct {
+	UserID            string                  `json:"user_id"`
+	ExportedAt        time.Time               `json:"exported_at"`
+	Settings          *users.User             `json:"settings"`
+	Guilds            []GuildMembership       `json:"guilds"`
+	Activities        []*activities.Activity  `json:"activities"`
+	Imports           []activities.ImportInfo `json:"imports"`
+	Goals             []*goals.Goal           `json:"goals"`
+	GoalPeriods       []GoalPeriod            `json:"goal_periods"`
+	GoalChanges       []GoalChange            `json:"goal_changes"`
+	GoalContributions []GoalContribution      `json:"goal_contributions"`
+	Works             []*works.Work           `json:"works"`
+	Timer             *timers.Timer           `json:"timer"`
+}
+
+// DeletionReport counts the data removed for a user.
+type DeletionReport struct {
+	Activities       int64
+	Goals            int64
+	Works            int64
+	Timers           int64
+	GuildMemberships int64
+	Settings         bool
+}
+
+// IsEmpty reports whether nothing was stored about the user.
+func (r *DeletionReport) IsEmpty() bool {
+	return r.Activities == 0 &&
+		r.Goals == 0 &&
+		r.Works == 0 &&
+		r.Timers == 0 &&
+		r.GuildMemberships == 0 &&
+		!r.Settings
+}
+
+type PrivacyService struct {
+	pool         *pgxpool.Pool
+	activityRepo *activities.ActivityRepository
+	userRepo     *users.UserRepository
+	goalRepo     *goals.GoalRepository
+	workRepo     *works.WorkRepository
+	timerRepo    *timers.TimerRepository
+}
+
+func NewPrivacyService(
+	pool *pgxpool.Pool,
+	ar *activities.ActivityRepository,
+	ur *users.UserRepository,
+	gr *goals.GoalRepository,
+	wr *works.WorkRepository,
+	tr *timers.TimerRepository,
+) *PrivacyService {
+	return &PrivacyService{
+		pool:         pool,
+		activityRepo: ar,
+		userRepo:     ur,
+		goalRepo:     gr,
+		workRepo:     wr,
+		timerRepo:    tr,
+	}
+}
+
+// Export collects everything stored about a user.
+func (s *PrivacyService) Export(ctx context.Context, userID string) (*UserData, error) {
+	data := &UserData{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+	}
+
+	var err error
+
+	data.Settings, err = s.userRepo.FindByID(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if data.Guilds, err = s.getGuildMemberships(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if data.Activities, err = s.activityRepo.GetAllByUserID(ctx, userID, ""); err != nil {
+		return nil, err
+	}
+
+	if data.Imports, err = s.activityRepo.GetRecentImportsByUserID(ctx, userID, math.MaxInt32); err != nil {
+		return nil, err
+	}
+
+	if data.Goals, err = s.goalRepo.FindAllByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if data.GoalPeriods, err = s.getGoalPeriods(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if data.GoalChanges, err = s.getGoalChanges(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if data.GoalContributions, err = s.getGoalContributions(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if data.Works, err = s.workRepo.GetAllByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	data.Timer, err = s.timerRepo.GetByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, timers.ErrTimerNotFound) {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *PrivacyService) getGuildMemberships(ctx context.Context, userID string) ([]GuildMembership, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT guild_id, created_at, last_seen_at
+		FROM guild_members
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[GuildMembership])
+}
+
+// getGoalPeriods returns the closed periods of the goals a user created.
+func (s *PrivacyService) getGoalPeriods(ctx context.Context, userID string) ([]GoalPeriod, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.goal_id, p.start_at, p.end_at, p.target, p.achieved
+		FROM goal_periods p
+		JOIN goals g ON g.id = p.goal_id
+		WHERE g.user_id = $1
+		ORDER BY p.goal_id, p.end_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[GoalPeriod])
+}
+
+// getGoalChanges returns the history of changes to the goals a user created.
+func (s *PrivacyService) getGoalChanges(ctx context.Context, userID string) ([]GoalChange, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT c.goal_id, c.changed_at, c.description
+		FROM goal_changes c
+		JOIN goals g ON g.id = c.goal_id
+		WHERE g.user_id = $1
+		ORDER BY c.goal_id, c.changed_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[GoalChange])
+}
+
+// getGoalContributions returns what a user contributed to guild goals.
+func (s *PrivacyService) getGoalContributions(ctx context.Context, userID string) ([]GoalContribution, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT goal_id, period_end, amount
+		FROM goal_contributions
+		WHERE user_id = $1
+		ORDER BY goal_id, period_end ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[GoalContribution])
+}
+
+// Delete permanently removes everything stored about a user, including
+// activities in the trash and guild goals they created.
+func (s *PrivacyService) Delete(ctx context.Context, userID string) (*DeletionReport, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	report := &DeletionReport{}
+
+	deletions := []struct {
+		query string
+		count *int64
+	}{
+		{`DELETE FROM activities WHERE user_id = $1`, &report.Activities},
+		{`DELETE FROM daily_user_totals WHERE user_id = $1`, nil},
+		// Guild goals lose what the user contributed to their current periods
+		{`UPDATE goals g
+			SET current = GREATEST(g.current - c.amount, 0), message_stale = TRUE
+			FROM goal_contributions c
+			WHERE c.goal_id = g.id
+			AND c.period_end = g.due_at
+			AND c.user_id = $1`, nil},
+		{`DELETE FROM goal_contributions WHERE user_id = $1`, nil},
+		// Periods, changes and others' contributions cascade
+		{`DELETE FROM goals WHERE user_id = $1`, &report.Goals},
+		{`DELETE FROM works WHERE user_id = $1`, &report.Works},
+		{`DELETE FROM timers WHERE user_id = $1`, &report.Timers},
+		{`DELETE FROM guild_members WHERE user_id = $1`, &report.GuildMemberships},
+	}
+
+	for _, d := range deletions {
+		tag, err := tx.Exec(ctx, d.query, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.count != nil {
+			*d.count = tag.RowsAffected()
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	report.Settings = tag.RowsAffected() > 0
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	s.userRepo.Uncache(userID)
+	return report, nil
+}
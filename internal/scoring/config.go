@@ -0,0 +1,196 @@
+// Package scoring awards points for activities according to per-guild rules,
+// such as one point per minute of listening or per 350 characters of visual
+// novels.
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xoltia/botsu/internal/activities"
+)
+
+const (
+	UnitMinutes    = "minutes"
+	UnitCharacters = "characters"
+	UnitPages      = "pages"
+	UnitEpisodes   = "episodes"
+)
+
+var (
+	ErrInvalidUnit   = errors.New("invalid unit")
+	ErrInvalidPoints = errors.New("points and per must be positive")
+	ErrTooManyRules  = errors.New("too many rules")
+)
+
+const MaxRules = 25
+
+// Rule awards Points for every Per units of activities matching the rule's
+// primary and media type. An empty type matches any activity.
+type Rule struct {
+	PrimaryType string  `json:"primary_type,omitempty"`
+	MediaType   string  `json:"media_type,omitempty"`
+	Unit        string  `json:"unit"`
+	Per         float64 `json:"per"`
+	Points      float64 `json:"points"`
+}
+
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// DefaultConfig is used by guilds which haven't configured scoring.
+func DefaultConfig() *Config {
+	return &Config{
+		Rules: []Rule{
+			{Unit: UnitMinutes, Per: 1, Points: 1},
+		},
+	}
+}
+
+func (r *Rule) Validate() error {
+	switch r.Unit {
+	case UnitMinutes, UnitCharacters, UnitPages, UnitEpisodes:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidUnit, r.Unit)
+	}
+
+	if r.Per <= 0 || r.Points <= 0 {
+		return ErrInvalidPoints
+	}
+
+	return nil
+}
+
+func (r *Rule) matches(a *activities.Activity) bool {
+	if r.PrimaryType != "" && r.PrimaryType != a.PrimaryType {
+		return false
+	}
+
+	if r.MediaType != "" && (a.MediaType == nil || r.MediaType != *a.MediaType) {
+		return false
+	}
+
+	return true
+}
+
+// specificity orders rules so that those matching fewer activities are tried
+// first.
+func (r *Rule) specificity() int {
+	n := 0
+	if r.MediaType != "" {
+		n += 2
+	}
+	if r.PrimaryType != "" {
+		n++
+	}
+	return n
+}
+
+// quantity returns the amount of the rule's unit in an activity. Activities
+// without the unit, such as a visual novel logged without a character count,
+// are not scored by the rule.
+func (r *Rule) quantity(a *activities.Activity) (float64, bool) {
+	switch r.Unit {
+	case UnitMinutes:
+		return a.Duration.Minutes(), true
+	case UnitCharacters, UnitPages, UnitEpisodes:
+		n, ok := a.MetaNumber(r.Unit)
+		return n, ok && n > 0
+	default:
+		return 0, false
+	}
+}
+
+func (r *Rule) String() string {
+	var b strings.Builder
+
+	b.WriteString(formatNumber(r.Points))
+	if r.Points == 1 {
+		b.WriteString(" point per ")
+	} else {
+		b.WriteString(" points per ")
+	}
+
+	if r.Per != 1 {
+		b.WriteString(formatNumber(r.Per))
+		b.WriteString(" ")
+		b.WriteString(r.Unit)
+	} else {
+		b.WriteString(strings.TrimSuffix(r.Unit, "s"))
+	}
+
+	switch {
+	case r.PrimaryType != "" && r.MediaType != "":
+		fmt.Fprintf(&b, " of %s (%s)", r.MediaType, r.PrimaryType)
+	case r.MediaType != "":
+		fmt.Fprintf(&b, " of %s", r.MediaType)
+	case r.PrimaryType != "":
+		fmt.Fprintf(&b, " of %s", r.PrimaryType)
+	}
+
+	return b.String()
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// SetRule adds a rule, replacing any existing rule for the same types and
+// unit. Rules are kept ordered from most to least specific.
+func (c *Config) SetRule(rule Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	for i, r := range c.Rules {
+		if r.PrimaryType == rule.PrimaryType && r.MediaType == rule.MediaType && r.Unit == rule.Unit {
+			c.Rules[i] = rule
+			return nil
+		}
+	}
+
+	if len(c.Rules) >= MaxRules {
+		return ErrTooManyRules
+	}
+
+	i := len(c.Rules)
+	for i > 0 && c.Rules[i-1].specificity() < rule.specificity() {
+		i--
+	}
+
+	c.Rules = append(c.Rules, Rule{})
+	copy(c.Rules[i+1:], c.Rules[i:])
+	c.Rules[i] = rule
+	return nil
+}
+
+// RemoveRule removes the rule at the given zero-based index.
+func (c *Config) RemoveRule(i int) bool {
+	if i < 0 || i >= len(c.Rules) {
+		return false
+	}
+
+	c.Rules = append(c.Rules[:i], c.Rules[i+1:]...)
+	return true
+}
+
+// Score returns the points awarded for an activity by the first rule which
+// matches it and can measure it in its unit. Activities matching no rule are
+// worth nothing.
+func (c *Config) Score(a *activities.Activity) float64 {
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if !r.matches(a) {
+			continue
+		}
+
+		if n, ok := r.quantity(a); ok {
+			return n / r.Per * r.Points
+		}
+	}
+
+	return 0
+}
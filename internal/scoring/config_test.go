@@ -0,0 +1,74 @@
+package scoring_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/scoring"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+func TestScore(t *testing.T) {
+	c := scoring.DefaultConfig()
+	assert.NoError(t, c.SetRule(scoring.Rule{
+		MediaType: activities.ActivityMediaTypeVisualNovel,
+		Unit:      scoring.UnitCharacters,
+		Per:       350,
+		Points:    1,
+	}))
+
+	vn := activities.NewActivity()
+	vn.PrimaryType = activities.ActivityImmersionTypeReading
+	vn.MediaType = ref.New(activities.ActivityMediaTypeVisualNovel)
+	vn.Duration = time.Hour
+	vn.SetMeta("characters", 7000)
+	assert.Equal(t, 20.0, c.Score(vn))
+
+	// Falls back to minutes without a character count
+	noChars := activities.NewActivity()
+	noChars.PrimaryType = activities.ActivityImmersionTypeReading
+	noChars.MediaType = ref.New(activities.ActivityMediaTypeVisualNovel)
+	noChars.Duration = 30 * time.Minute
+	assert.Equal(t, 30.0, c.Score(noChars))
+
+	listening := activities.NewActivity()
+	listening.PrimaryType = activities.ActivityImmersionTypeListening
+	listening.Duration = 90 * time.Minute
+	assert.Equal(t, 90.0, c.Score(listening))
+
+	empty := &scoring.Config{}
+	assert.Equal(t, 0.0, empty.Score(listening))
+}
+
+func TestSetRule(t *testing.T) {
+	c := scoring.DefaultConfig()
+
+	assert.ErrorIs(t, c.SetRule(scoring.Rule{Unit: "words", Per: 1, Points: 1}), scoring.ErrInvalidUnit)
+	assert.ErrorIs(t, c.SetRule(scoring.Rule{Unit: scoring.UnitPages, Per: 0, Points: 1}), scoring.ErrInvalidPoints)
+
+	primary := scoring.Rule{PrimaryType: activities.ActivityImmersionTypeListening, Unit: scoring.UnitMinutes, Per: 1, Points: 2}
+	media := scoring.Rule{MediaType: activities.ActivityMediaTypeManga, Unit: scoring.UnitPages, Per: 1, Points: 1}
+
+	assert.NoError(t, c.SetRule(primary))
+	assert.NoError(t, c.SetRule(media))
+	assert.Equal(t, []scoring.Rule{media, primary, scoring.DefaultConfig().Rules[0]}, c.Rules)
+
+	media.Points = 3
+	assert.NoError(t, c.SetRule(media))
+	assert.Len(t, c.Rules, 3)
+	assert.Equal(t, 3.0, c.Rules[0].Points)
+
+	assert.True(t, c.RemoveRule(0))
+	assert.False(t, c.RemoveRule(5))
+	assert.Equal(t, []scoring.Rule{primary, scoring.DefaultConfig().Rules[0]}, c.Rules)
+}
+
+func TestRuleString(t *testing.T) {
+	r := scoring.Rule{MediaType: activities.ActivityMediaTypeVisualNovel, Unit: scoring.UnitCharacters, Per: 350, Points: 1}
+	assert.Equal(t, "1 point per 350 characters of visual_novel", r.String())
+
+	r = scoring.Rule{PrimaryType: activities.ActivityImmersionTypeListening, Unit: scoring.UnitMinutes, Per: 1, Points: 1.5}
+	assert.Equal(t, "1.5 points per minute of listening", r.String())
+}
@@ -0,0 +1,398 @@
+package scoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/pkg/orderedmap"
+)
+
+// ScoringService stores guild scoring configs and the points awarded to
+// activities under them. Points are stored per guild, since a member's
+// activities count towards every guild they are in.
+type ScoringService struct {
+	pool *pgxpool.Pool
+}
+
+func NewScoringService(pool *pgxpool.Pool) *ScoringService {
+	return &ScoringService{pool}
+}
+
+func parseConfig(raw []byte) (*Config, error) {
+	if raw == nil {
+		return DefaultConfig(), nil
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetConfig returns the scoring config of a guild, or DefaultConfig if it
+// hasn't been configured.
+func (s *ScoringService) GetConfig(ctx context.Context, guildID string) (*Config, error) {
+	var raw []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT scoring
+		FROM guilds
+		WHERE id = $1
+	`, guildID).Scan(&raw)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultConfig(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return parseConfig(raw)
+}
+
+// SetConfig saves the scoring config of a guild and re-scores the activities
+// of its members in the same transaction, so that points are never missing.
+// A nil config resets the guild to DefaultConfig.
+func (s *ScoringService) SetConfig(ctx context.Context, guildID string, c *Config) error {
+	var raw []byte
+	if c != nil {
+		var err error
+		if raw, err = json.Marshal(c); err != nil {
+			return err
+		}
+	} else {
+		c = DefaultConfig()
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO guilds (id, scoring)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE
+		SET scoring = EXCLUDED.scoring
+	`, guildID, raw)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM activity_points
+		WHERE guild_id = $1
+	`, guildID)
+	if err != nil {
+		return err
+	}
+
+	if err = scoreGuild(ctx, tx, guildID, c); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ScoreActivitiesTx scores newly added activities of a user for each guild
+// they are a member of, as part of the transaction which added them. The
+// first time a member is scored for a guild, the activities they logged
+// before joining it are scored too.
+func (s *ScoringService) ScoreActivitiesTx(ctx context.Context, tx pgx.Tx, userID string, as []*activities.Activity) error {
+	rows, err := tx.Query(ctx, `
+		SELECT m.guild_id, m.scored, g.scoring
+		FROM guild_members m
+		LEFT JOIN guilds g ON g.id = m.guild_id
+		WHERE m.user_id = $1
+		FOR UPDATE OF m
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	type membership struct {
+		guildID string
+		scored  bool
+		config  *Config
+	}
+
+	memberships, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (m membership, err error) {
+		var raw []byte
+		if err = row.Scan(&m.guildID, &m.scored, &raw); err != nil {
+			return
+		}
+
+		m.config, err = parseConfig(raw)
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range memberships {
+		if m.scored {
+			err = savePoints(ctx, tx, m.guildID, m.config, as)
+		} else {
+			err = scoreMember(ctx, tx, m.guildID, userID, m.config)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ScoreAll scores every activity which hasn't been scored yet for each guild,
+// such as those logged before scoring was added.
+func (s *ScoringService) ScoreAll(ctx context.Context) error {
+	rows, err := s.pool.Query(ctx, `SELECT id FROM guilds`)
+	if err != nil {
+		return err
+	}
+
+	guildIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for _, guildID := range guildIDs {
+		if err = s.scoreGuildByID(ctx, guildID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scoreGuildByID is scoreGuild in its own transaction, with the guild's config.
+func (s *ScoringService) scoreGuildByID(ctx context.Context, guildID string) error {
+	c, err := s.GetConfig(ctx, guildID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err = scoreGuild(ctx, tx, guildID, c); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// querier is either a pool or a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// scoreGuild scores the activities of a guild's members which haven't been
+// scored for the guild yet, marking the members as scored.
+func scoreGuild(ctx context.Context, db querier, guildID string, c *Config) error {
+	// Deleted activities are scored too, so that they count again if restored
+	rows, err := db.Query(ctx, `
+		SELECT a.id, a.user_id, a.primary_type, a.media_type, a.duration, a.meta
+		FROM activities a
+		JOIN guild_members m ON m.user_id = a.user_id AND m.guild_id = $1
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM activity_points p
+			WHERE p.guild_id = $1
+			AND p.activity_id = a.id
+		)
+	`, guildID)
+	if err != nil {
+		return err
+	}
+
+	if err = scoreRows(ctx, db, guildID, c, rows); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `
+		UPDATE guild_members
+		SET scored = TRUE
+		WHERE guild_id = $1
+		AND NOT scored
+	`, guildID)
+
+	return err
+}
+
+// scoreMember is scoreGuild for a single member.
+func scoreMember(ctx context.Context, db querier, guildID, userID string, c *Config) error {
+	// Deleted activities are scored too, so that they count again if restored
+	rows, err := db.Query(ctx, `
+		SELECT a.id, a.user_id, a.primary_type, a.media_type, a.duration, a.meta
+		FROM activities a
+		WHERE a.user_id = $2
+		AND NOT EXISTS (
+			SELECT 1
+			FROM activity_points p
+			WHERE p.guild_id = $1
+			AND p.activity_id = a.id
+		)
+	`, guildID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = scoreRows(ctx, db, guildID, c, rows); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `
+		UPDATE guild_members
+		SET scored = TRUE
+		WHERE guild_id = $1
+		AND user_id = $2
+	`, guildID, userID)
+
+	return err
+}
+
+// scoreRows saves the points of the activities selected by rows.
+func scoreRows(ctx context.Context, db querier, guildID string, c *Config, rows pgx.Rows) error {
+	as, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*activities.Activity, error) {
+		a := &activities.Activity{}
+		err := row.Scan(&a.ID, &a.UserID, &a.PrimaryType, &a.MediaType, &a.Duration, &a.Meta)
+		return a, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return savePoints(ctx, db, guildID, c, as)
+}
+
+func savePoints(ctx context.Context, db querier, guildID string, c *Config, as []*activities.Activity) error {
+	if len(as) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(as))
+	points := make([]float64, len(as))
+
+	for i, a := range as {
+		ids[i] = a.ID
+		points[i] = c.Score(a)
+	}
+
+	_, err := db.Exec(ctx, `
+		INSERT INTO activity_points (guild_id, activity_id, points)
+		SELECT $1, unnest($2::BIGINT[]), unnest($3::DOUBLE PRECISION[])
+		ON CONFLICT (guild_id, activity_id) DO UPDATE
+		SET points = EXCLUDED.points
+	`, guildID, ids, points)
+
+	return err
+}
+
+// GetTopMembers ranks the members of a guild by the points of activities
+// logged between start and end.
+func (s *ScoringService) GetTopMembers(ctx context.Context, guildID string, limit int, start, end time.Time) ([]*activities.MemberStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT a.user_id, COALESCE(SUM(p.points), 0) AS total_points
+		FROM activity_points p
+		JOIN activities a ON a.id = p.activity_id
+		JOIN guild_members m ON m.guild_id = p.guild_id AND m.user_id = a.user_id
+		WHERE p.guild_id = $1
+		AND a.date >= $2
+		AND a.date <= $3
+		AND a.deleted_at IS NULL
+		GROUP BY a.user_id
+		ORDER BY total_points DESC
+		LIMIT $4
+	`, guildID, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*activities.MemberStats, 0, limit)
+	for rows.Next() {
+		var member activities.MemberStats
+		if err := rows.Scan(&member.UserID, &member.TotalPoints); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
+
+// GetTotalByUserIDGrouped returns the points a user earned in a guild for each
+// day (YYYY-MM-DD) or month (YYYY-MM) between start and end in the user's
+// timezone, filling in missing periods with 0.
+func (s *ScoringService) GetTotalByUserIDGrouped(
+	ctx context.Context,
+	userID, guildID string,
+	start, end time.Time,
+	byMonth bool,
+) (orderedmap.Map[float64], error) {
+	period, format := "day", "YYYY-MM-DD"
+	if byMonth {
+		period, format = "month", "YYYY-MM"
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT
+			to_char(date_series.period, $6) AS period,
+			COALESCE(SUM(p.points), 0) AS total_points
+		FROM (
+			SELECT
+				generate_series(
+					date_trunc($5, $3::date),
+					$4::date,
+					('1 ' || $5)::interval
+				) AS period
+		) AS date_series
+		LEFT JOIN users u ON u.id = $1
+		LEFT JOIN guilds g ON g.id = $2
+		LEFT JOIN activities
+			ON date_series.period = date_trunc(
+				$5,
+				activities.date at time zone COALESCE(u.timezone, g.timezone, 'UTC')
+			)
+			AND activities.user_id = $1
+			AND activities.deleted_at IS NULL
+		LEFT JOIN activity_points p
+			ON p.activity_id = activities.id
+			AND p.guild_id = $2
+		GROUP BY date_series.period
+		ORDER BY date_series.period ASC
+	`, userID, guildID, start, end, period, format)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := orderedmap.NewWithCapacity[float64](int(end.Sub(start).Hours()/24) + 1)
+
+	for rows.Next() {
+		var (
+			key    string
+			points float64
+		)
+
+		if err := rows.Scan(&key, &points); err != nil {
+			return nil, err
+		}
+
+		totals.Set(key, points)
+	}
+
+	return totals, rows.Err()
+}
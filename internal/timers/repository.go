@@ -0,0 +1,184 @@
+package timers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTimerExists   = errors.New("timer already exists")
+	ErrTimerNotFound = errors.New("timer not found")
+)
+
+const timerColumns = `user_id, guild_id, channel_id, message_id, name, primary_type, media_type, started_at, resumed_at, paused_at, elapsed`
+
+type TimerRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTimerRepository(pool *pgxpool.Pool) *TimerRepository {
+	return &TimerRepository{pool}
+}
+
+func scanTimer(row pgx.Row) (*Timer, error) {
+	t := &Timer{}
+	err := row.Scan(
+		&t.UserID,
+		&t.GuildID,
+		&t.ChannelID,
+		&t.MessageID,
+		&t.Name,
+		&t.PrimaryType,
+		&t.MediaType,
+		&t.StartedAt,
+		&t.ResumedAt,
+		&t.PausedAt,
+		&t.Elapsed,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTimerNotFound
+	}
+
+	return t, err
+}
+
+// Create starts a new timer, returning ErrTimerExists if the user already has one.
+func (r *TimerRepository) Create(ctx context.Context, t *Timer) error {
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO timers (user_id, guild_id, channel_id, name, primary_type, media_type)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING `+timerColumns,
+		t.UserID,
+		t.GuildID,
+		t.ChannelID,
+		t.Name,
+		t.PrimaryType,
+		t.MediaType,
+	)
+
+	created, err := scanTimer(row)
+	if errors.Is(err, ErrTimerNotFound) {
+		return ErrTimerExists
+	} else if err != nil {
+		return err
+	}
+
+	*t = *created
+	return nil
+}
+
+func (r *TimerRepository) GetByUserID(ctx context.Context, userID string) (*Timer, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+timerColumns+`
+		FROM timers
+		WHERE user_id = $1
+	`, userID)
+
+	return scanTimer(row)
+}
+
+// SetMessage sets the message showing the timer, which is updated when the
+// timer is stopped by something other than its buttons.
+func (r *TimerRepository) SetMessage(ctx context.Context, userID, channelID, messageID string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE timers
+		SET channel_id = $2, message_id = $3
+		WHERE user_id = $1
+	`, userID, channelID, messageID)
+	return err
+}
+
+// Pause pauses the user's timer. Returns ErrTimerNotFound if the user has
+// no running timer.
+func (r *TimerRepository) Pause(ctx context.Context, userID string) (*Timer, error) {
+	row := r.pool.QueryRow(ctx, `
+		UPDATE timers
+		SET elapsed = elapsed + (EXTRACT(EPOCH FROM (NOW() - resumed_at)) * 1000000000)::BIGINT,
+			resumed_at = NULL,
+			paused_at = NOW()
+		WHERE user_id = $1
+		AND resumed_at IS NOT NULL
+		RETURNING `+timerColumns,
+		userID,
+	)
+
+	return scanTimer(row)
+}
+
+// Resume resumes the user's timer. Returns ErrTimerNotFound if the user has
+// no paused timer.
+func (r *TimerRepository) Resume(ctx context.Context, userID string) (*Timer, error) {
+	row := r.pool.QueryRow(ctx, `
+		UPDATE timers
+		SET resumed_at = NOW(),
+			paused_at = NULL
+		WHERE user_id = $1
+		AND resumed_at IS NULL
+		RETURNING `+timerColumns,
+		userID,
+	)
+
+	return scanTimer(row)
+}
+
+// BeginDeleteTx removes and returns the user's timer in a new transaction,
+// so that it is only removed if the transaction is committed. Only one caller
+// can successfully delete a timer, so this is used to stop it.
+func (r *TimerRepository) BeginDeleteTx(ctx context.Context, userID string) (t *Timer, tx pgx.Tx, err error) {
+	tx, err = r.pool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	row := tx.QueryRow(ctx, `
+		DELETE FROM timers
+		WHERE user_id = $1
+		RETURNING `+timerColumns,
+		userID,
+	)
+
+	t, err = scanTimer(row)
+	if err != nil {
+		tx.Rollback(ctx) //nolint:errcheck
+		return nil, nil, err
+	}
+
+	return
+}
+
+// GetExpired returns every running timer which has run for at least the
+// given duration, and every timer which has been paused for at least as long.
+func (r *TimerRepository) GetExpired(ctx context.Context, maxDuration time.Duration) ([]*Timer, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+timerColumns+`
+		FROM timers
+		WHERE (
+			resumed_at IS NOT NULL
+			AND elapsed + (EXTRACT(EPOCH FROM (NOW() - resumed_at)) * 1000000000)::BIGINT >= $1
+		) OR (
+			resumed_at IS NULL
+			AND (EXTRACT(EPOCH FROM (NOW() - paused_at)) * 1000000000)::BIGINT >= $1
+		)
+	`, maxDuration)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timers := make([]*Timer, 0)
+	for rows.Next() {
+		t, err := scanTimer(rows)
+		if err != nil {
+			return nil, err
+		}
+		timers = append(timers, t)
+	}
+
+	return timers, rows.Err()
+}
@@ -0,0 +1,54 @@
+package timers
+
+import (
+	"time"
+
+	"github.com/xoltia/botsu/internal/activities"
+)
+
+// Timer is a running or paused immersion session. Each user can have at most
+// one timer at a time.
+type Timer struct {
+	UserID      string
+	GuildID     *string
+	ChannelID   *string
+	MessageID   *string
+	Name        string
+	PrimaryType string
+	MediaType   *string
+	StartedAt   time.Time
+	// Nil while the timer is paused
+	ResumedAt *time.Time
+	// Nil while the timer is running
+	PausedAt *time.Time
+	// Time accumulated before ResumedAt
+	Elapsed time.Duration
+}
+
+func (t *Timer) IsPaused() bool {
+	return t.ResumedAt == nil
+}
+
+// ElapsedAt returns the total time the timer has been running for at the
+// given time, excluding any time spent paused.
+func (t *Timer) ElapsedAt(now time.Time) time.Duration {
+	if t.IsPaused() {
+		return t.Elapsed
+	}
+
+	return t.Elapsed + now.Sub(*t.ResumedAt)
+}
+
+// Activity creates the activity logged when the timer is stopped with the
+// given duration.
+func (t *Timer) Activity(duration time.Duration) *activities.Activity {
+	a := activities.NewActivity()
+	a.UserID = t.UserID
+	a.GuildID = t.GuildID
+	a.Name = t.Name
+	a.PrimaryType = t.PrimaryType
+	a.MediaType = t.MediaType
+	a.Duration = duration.Round(time.Second)
+	a.SetMeta("timer", true)
+	return a
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -60,14 +61,21 @@ func (r *UserRepository) FindByID(ctx context.Context, id string) (*User, error)
 		return cached, nil
 	}
 
-	var user User
+	var (
+		user      User
+		reminders []int64
+	)
 	err := r.pool.QueryRow(ctx,
 		`SELECT id,
        		timezone,
        		vn_reading_speed,
        		book_reading_speed,
        		manga_reading_speed,
-       		daily_goal
+       		daily_goal,
+       		daily_goal_reminder,
+       		goal_reminders,
+       		quiet_hours_start,
+       		quiet_hours_end
 		FROM users
 		WHERE id = $1;`, id).Scan(
 		&user.ID,
@@ -76,12 +84,20 @@ func (r *UserRepository) FindByID(ctx context.Context, id string) (*User, error)
 		&user.BookReadingSpeed,
 		&user.MangaReadingSpeed,
 		&user.DailyGoal,
+		&user.DailyGoalReminder,
+		&reminders,
+		&user.QuietHoursStart,
+		&user.QuietHoursEnd,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	for _, r := range reminders {
+		user.GoalReminders = append(user.GoalReminders, time.Duration(r))
+	}
+
 	r.cacheUser(&user)
 
 	return &user, nil
@@ -194,6 +210,152 @@ func (r *UserRepository) SetDailyGoal(ctx context.Context, userID string, goal i
 	return nil
 }
 
+// SetDailyGoalReminder sets how long before local midnight to remind the user
+// of an unmet daily goal, or turns the reminder off if reminder is nil.
+func (r *UserRepository) SetDailyGoalReminder(ctx context.Context, userID string, reminder *time.Duration) error {
+	query := `
+		INSERT INTO users (id, daily_goal_reminder)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET daily_goal_reminder = $2;
+	`
+
+	if _, err := r.pool.Exec(ctx, query, userID, reminder); err != nil {
+		return err
+	}
+
+	if user := r.getCachedUser(userID); user != nil {
+		user.DailyGoalReminder = reminder
+	}
+
+	return nil
+}
+
+// FindDailyGoalReminderIDs returns the IDs of users with a daily goal and its
+// reminder turned on.
+func (r *UserRepository) FindDailyGoalReminderIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id
+		FROM users
+		WHERE daily_goal > 0
+		AND daily_goal_reminder IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ClaimDailyGoalReminder records that the user was reminded of their daily
+// goal on the local date, returning false if they already were, so that only
+// one instance of the bot sends each reminder.
+func (r *UserRepository) ClaimDailyGoalReminder(ctx context.Context, userID string, date time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE users
+		SET daily_goal_reminded_on = $2
+		WHERE id = $1
+		AND daily_goal_reminded_on IS DISTINCT FROM $2
+	`, userID, date.Format(time.DateOnly))
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetGoalReminders sets how long before goals are due to remind the user.
+// No reminders are sent if reminders is empty.
+func (r *UserRepository) SetGoalReminders(ctx context.Context, userID string, reminders []time.Duration) error {
+	nanoseconds := make([]int64, len(reminders))
+	for i, d := range reminders {
+		nanoseconds[i] = int64(d)
+	}
+
+	query := `
+		INSERT INTO users (id, goal_reminders)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET goal_reminders = $2;
+	`
+
+	if _, err := r.pool.Exec(ctx, query, userID, nanoseconds); err != nil {
+		return err
+	}
+
+	if user := r.getCachedUser(userID); user != nil {
+		user.GoalReminders = reminders
+	}
+
+	return nil
+}
+
+// SetQuietHours sets the local hours between which the user is not sent
+// reminders, or removes them if start and end are nil.
+func (r *UserRepository) SetQuietHours(ctx context.Context, userID string, start, end *int) error {
+	query := `
+		INSERT INTO users (id, quiet_hours_start, quiet_hours_end)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET quiet_hours_start = $2, quiet_hours_end = $3;
+	`
+
+	if _, err := r.pool.Exec(ctx, query, userID, start, end); err != nil {
+		return err
+	}
+
+	if user := r.getCachedUser(userID); user != nil {
+		user.QuietHoursStart = start
+		user.QuietHoursEnd = end
+	}
+
+	return nil
+}
+
+// ClaimExport records an export by the user unless they already exported
+// within the given interval. If they did, false is returned along with the
+// time of their last export.
+func (r *UserRepository) ClaimExport(ctx context.Context, userID string, interval time.Duration) (bool, time.Time, error) {
+	var exportedAt time.Time
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO users (id, last_exported_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (id) DO UPDATE SET last_exported_at = NOW()
+		WHERE users.last_exported_at IS NULL
+		OR users.last_exported_at <= NOW() - make_interval(secs => $2)
+		RETURNING last_exported_at
+	`, userID, interval.Seconds()).Scan(&exportedAt)
+
+	if err == nil {
+		return true, exportedAt, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return false, time.Time{}, err
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		SELECT last_exported_at
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&exportedAt)
+
+	return false, exportedAt, err
+}
+
+// ReleaseExport undoes a claim made by ClaimExport at the given time, such as
+// when the export failed, so that the user can export again right away.
+func (r *UserRepository) ReleaseExport(ctx context.Context, userID string, claimedAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE users
+		SET last_exported_at = NULL
+		WHERE id = $1
+		AND last_exported_at = $2
+	`, userID, claimedAt)
+	return err
+}
+
+// Uncache removes a user from the cache, such as after their data has been
+// deleted outside of the repository.
+func (r *UserRepository) Uncache(id string) {
+	r.cache.Delete(id)
+}
+
 func (r *UserRepository) cacheUser(user *User) {
 	r.cache.Store(user.ID, user)
 }
@@ -70,3 +70,13 @@ func (s *UserTimeService) getGuildDefaultTimezone(ctx context.Context, guildID s
 
 	return *guild.Timezone, nil
 }
+
+// GetGuildTimeLocation returns the location of a guild's timezone, for times
+// which belong to the guild rather than any of its members.
+func (s *UserTimeService) GetGuildTimeLocation(ctx context.Context, guildID string) (*time.Location, error) {
+	timezone, err := s.getGuildDefaultTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(timezone)
+}
@@ -1,12 +1,23 @@
 package users
 
+import "time"
+
 type User struct {
 	ID                      string
 	Timezone                *string
 	VisualNovelReadingSpeed float32
 	BookReadingSpeed        float32
 	MangaReadingSpeed       float32
-	DailyGoal               int
+	// DailyGoal is in minutes, 0 if the user has no daily goal
+	DailyGoal int
+	// How long before local midnight to remind the user of an unmet daily
+	// goal, nil for no reminder
+	DailyGoalReminder *time.Duration
+	// How long before goals are due to remind the user of unfinished goals
+	GoalReminders []time.Duration
+	// Local hours between which no reminders are sent, e.g. 22 to 8
+	QuietHoursStart *int
+	QuietHoursEnd   *int
 }
 
 func NewUser(id string) *User {
@@ -19,3 +30,56 @@ func NewUser(id string) *User {
 		DailyGoal:               0,
 	}
 }
+
+// IsQuietHour reports whether t, in the user's location, is within the user's
+// quiet hours. Quiet hours may span midnight.
+func (u *User) IsQuietHour(t time.Time) bool {
+	if u.QuietHoursStart == nil || u.QuietHoursEnd == nil {
+		return false
+	}
+
+	start, end, hour := *u.QuietHoursStart, *u.QuietHoursEnd, t.Hour()
+
+	if start <= end {
+		return hour >= start && hour < end
+	}
+
+	return hour >= start || hour < end
+}
+
+// DailyGoalDuration returns the daily goal as a duration.
+func (u *User) DailyGoalDuration() time.Duration {
+	return time.Duration(u.DailyGoal) * time.Minute
+}
+
+// DailyGoalStreak returns the number of consecutive days on which the daily
+// goal was met, given the total of each day from oldest to newest ending
+// today. The streak isn't broken by today until it is over.
+func (u *User) DailyGoalStreak(totals []time.Duration) (streak int) {
+	if u.DailyGoal <= 0 {
+		return 0
+	}
+
+	goal := u.DailyGoalDuration()
+
+	for i := len(totals) - 1; i >= 0; i-- {
+		if totals[i] >= goal {
+			streak++
+		} else if i != len(totals)-1 {
+			break
+		}
+	}
+
+	return
+}
+
+// DailyGoalReminderDue reports whether t, in the user's location, is late
+// enough in the day to remind the user of their daily goal.
+func (u *User) DailyGoalReminderDue(t time.Time) bool {
+	if u.DailyGoal <= 0 || u.DailyGoalReminder == nil {
+		return false
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	return !t.Before(midnight.Add(-*u.DailyGoalReminder))
+}
@@ -0,0 +1,61 @@
+package users_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/users"
+)
+
+func TestIsQuietHour(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 5, 1, hour, 30, 0, 0, time.UTC)
+	}
+
+	u := users.NewUser("123456789012345678")
+	assert.False(t, u.IsQuietHour(at(3)))
+
+	start, end := 22, 8
+	u.QuietHoursStart, u.QuietHoursEnd = &start, &end
+	assert.True(t, u.IsQuietHour(at(23)))
+	assert.True(t, u.IsQuietHour(at(3)))
+	assert.False(t, u.IsQuietHour(at(8)))
+	assert.False(t, u.IsQuietHour(at(12)))
+
+	start, end = 1, 6
+	assert.True(t, u.IsQuietHour(at(1)))
+	assert.False(t, u.IsQuietHour(at(6)))
+	assert.False(t, u.IsQuietHour(at(23)))
+}
+
+func TestDailyGoalStreak(t *testing.T) {
+	u := users.NewUser("123456789012345678")
+	totals := []time.Duration{time.Hour, 0, time.Hour, time.Hour, 10 * time.Minute}
+	assert.Equal(t, 0, u.DailyGoalStreak(totals))
+
+	u.DailyGoal = 60
+	assert.Equal(t, 2, u.DailyGoalStreak(totals))
+	assert.Equal(t, 2, u.DailyGoalStreak(totals[:4]))
+	assert.Equal(t, 1, u.DailyGoalStreak(totals[:2]))
+	assert.Equal(t, 0, u.DailyGoalStreak(nil))
+}
+
+func TestDailyGoalReminderDue(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+	}
+
+	u := users.NewUser("123456789012345678")
+	reminder := 2 * time.Hour
+	u.DailyGoalReminder = &reminder
+	assert.False(t, u.DailyGoalReminderDue(at(23)))
+
+	u.DailyGoal = 30
+	assert.False(t, u.DailyGoalReminderDue(at(21)))
+	assert.True(t, u.DailyGoalReminderDue(at(22)))
+	assert.True(t, u.DailyGoalReminderDue(at(23)))
+
+	u.DailyGoalReminder = nil
+	assert.False(t, u.DailyGoalReminderDue(at(23)))
+}
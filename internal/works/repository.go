@@ -0,0 +1,333 @@
+package works
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrWorkExists   = errors.New("work already exists")
+	ErrWorkNotFound = errors.New("work not found")
+)
+
+const workColumns = `id, user_id, media_type, external_id, title, status, episodes, total_episodes,
+	volumes, total_volumes, characters, total_characters, pages, rating, started_at, finished_at,
+	created_at, updated_at`
+
+// Matches the work with the same external ID, or the same title if the work
+// has no external ID. Expects user_id, media_type, external_id and title as
+// $1 to $4.
+const workKeyCondition = `user_id = $1
+	AND media_type = $2
+	AND (($3::TEXT IS NOT NULL AND external_id = $3)
+		OR ($3::TEXT IS NULL AND external_id IS NULL AND lower(title) = lower($4)))`
+
+type UserWorkPage struct {
+	Works     []*Work
+	PageCount int
+	Page      int
+}
+
+type WorkRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWorkRepository(pool *pgxpool.Pool) *WorkRepository {
+	return &WorkRepository{pool}
+}
+
+func scanWork(row pgx.Row) (*Work, error) {
+	w := &Work{}
+	err := row.Scan(
+		&w.ID,
+		&w.UserID,
+		&w.MediaType,
+		&w.ExternalID,
+		&w.Title,
+		&w.Status,
+		&w.Episodes,
+		&w.TotalEpisodes,
+		&w.Volumes,
+		&w.TotalVolumes,
+		&w.Characters,
+		&w.TotalCharacters,
+		&w.Pages,
+		&w.Rating,
+		&w.StartedAt,
+		&w.FinishedAt,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWorkNotFound
+	}
+
+	return w, err
+}
+
+// Create adds a work to a user's library, returning ErrWorkExists if the
+// user already has it.
+func (r *WorkRepository) Create(ctx context.Context, w *Work) error {
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO works (user_id, media_type, external_id, title, status, total_episodes, total_volumes, total_characters, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+workColumns,
+		w.UserID,
+		w.MediaType,
+		w.ExternalID,
+		w.Title,
+		w.Status,
+		w.TotalEpisodes,
+		w.TotalVolumes,
+		w.TotalCharacters,
+		w.StartedAt,
+		w.FinishedAt,
+	)
+
+	created, err := scanWork(row)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrWorkExists
+	} else if err != nil {
+		return err
+	}
+
+	*w = *created
+	return nil
+}
+
+// AddProgressTx adds progress to the work matching the given one as part of
+// a larger transaction, adding the work to the user's library if it isn't
+// there yet.
+func (r *WorkRepository) AddProgressTx(ctx context.Context, tx pgx.Tx, key *Work, p Progress, at time.Time) (*Work, error) {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO works (user_id, media_type, external_id, title)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, key.UserID, key.MediaType, key.ExternalID, key.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `
+		SELECT `+workColumns+`
+		FROM works
+		WHERE `+workKeyCondition+`
+		FOR UPDATE
+	`, key.UserID, key.MediaType, key.ExternalID, key.Title)
+
+	w, err := scanWork(row)
+	if err != nil {
+		return nil, err
+	}
+
+	w.AddProgress(p, at)
+
+	if err := updateWork(ctx, tx, w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// UpdateByIDTx locks the user's work with the given ID and saves the changes
+// made to it by update as part of a larger transaction. Returns
+// ErrWorkNotFound if the work isn't in the user's library.
+func (r *WorkRepository) UpdateByIDTx(ctx context.Context, tx pgx.Tx, id int64, userID string, update func(w *Work)) error {
+	row := tx.QueryRow(ctx, `
+		SELECT `+workColumns+`
+		FROM works
+		WHERE id = $1
+		AND user_id = $2
+		FOR UPDATE
+	`, id, userID)
+
+	w, err := scanWork(row)
+	if err != nil {
+		return err
+	}
+
+	update(w)
+	return updateWork(ctx, tx, w)
+}
+
+// Update saves the status, progress, totals, rating and dates of a work.
+func (r *WorkRepository) Update(ctx context.Context, w *Work) error {
+	return updateWork(ctx, r.pool, w)
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func updateWork(ctx context.Context, db querier, w *Work) error {
+	return db.QueryRow(ctx, `
+		UPDATE works
+		SET status = $2,
+			episodes = $3,
+			total_episodes = $4,
+			volumes = $5,
+			total_volumes = $6,
+			characters = $7,
+			total_characters = $8,
+			pages = $9,
+			rating = $10,
+			started_at = $11,
+			finished_at = $12,
+			updated_at = (NOW() AT TIME ZONE 'utc')
+		WHERE id = $1
+		RETURNING updated_at
+	`,
+		w.ID,
+		w.Status,
+		w.Episodes,
+		w.TotalEpisodes,
+		w.Volumes,
+		w.TotalVolumes,
+		w.Characters,
+		w.TotalCharacters,
+		w.Pages,
+		w.Rating,
+		w.StartedAt,
+		w.FinishedAt,
+	).Scan(&w.UpdatedAt)
+}
+
+func (r *WorkRepository) GetByID(ctx context.Context, id int64, userID string) (*Work, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+workColumns+`
+		FROM works
+		WHERE id = $1
+		AND user_id = $2
+	`, id, userID)
+
+	return scanWork(row)
+}
+
+// PageByUserID returns a page of a user's library, most recently updated
+// first. If status is not nil, only works with that status are returned.
+func (r *WorkRepository) PageByUserID(ctx context.Context, userID string, status *string, limit, offset int) (*UserWorkPage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+workColumns+`,
+			CEIL(COUNT(*) OVER() / $3::float) AS page_count
+		FROM works
+		WHERE user_id = $1
+		AND ($2::work_status IS NULL OR status = $2)
+		ORDER BY updated_at DESC
+		LIMIT $3
+		OFFSET $4
+	`, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &UserWorkPage{
+		Works: make([]*Work, 0, limit),
+		Page:  offset/limit + 1,
+	}
+
+	for rows.Next() {
+		w := &Work{}
+		err := rows.Scan(
+			&w.ID,
+			&w.UserID,
+			&w.MediaType,
+			&w.ExternalID,
+			&w.Title,
+			&w.Status,
+			&w.Episodes,
+			&w.TotalEpisodes,
+			&w.Volumes,
+			&w.TotalVolumes,
+			&w.Characters,
+			&w.TotalCharacters,
+			&w.Pages,
+			&w.Rating,
+			&w.StartedAt,
+			&w.FinishedAt,
+			&w.CreatedAt,
+			&w.UpdatedAt,
+			&page.PageCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		page.Works = append(page.Works, w)
+	}
+
+	return page, rows.Err()
+}
+
+// GetAllByUserID returns the user's whole library, oldest first.
+func (r *WorkRepository) GetAllByUserID(ctx context.Context, userID string) ([]*Work, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+workColumns+`
+		FROM works
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	works := make([]*Work, 0)
+	for rows.Next() {
+		w, err := scanWork(rows)
+		if err != nil {
+			return nil, err
+		}
+		works = append(works, w)
+	}
+
+	return works, rows.Err()
+}
+
+// SearchByUserID returns the user's works with titles containing the query,
+// most recently updated first.
+func (r *WorkRepository) SearchByUserID(ctx context.Context, userID, query string, limit int) ([]*Work, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+workColumns+`
+		FROM works
+		WHERE user_id = $1
+		AND title ILIKE '%' || $2 || '%'
+		ORDER BY updated_at DESC
+		LIMIT $3
+	`, userID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	works := make([]*Work, 0, limit)
+	for rows.Next() {
+		w, err := scanWork(rows)
+		if err != nil {
+			return nil, err
+		}
+		works = append(works, w)
+	}
+
+	return works, rows.Err()
+}
+
+func (r *WorkRepository) DeleteByID(ctx context.Context, id int64, userID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM works
+		WHERE id = $1
+		AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,249 @@
+package works
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/xoltia/botsu/internal/activities"
+)
+
+// ActivityChange is how activities were changed.
+type ActivityChange int
+
+const (
+	ActivitiesLogged ActivityChange = iota
+	ActivitiesImported
+	ActivitiesDeleted
+	ActivitiesRestored
+)
+
+// ActivityHook is called in the transaction which changes the activities of
+// a user, after they have been changed. Returning an error rolls back the
+// change.
+type ActivityHook func(ctx context.Context, tx pgx.Tx, change ActivityChange, userID string, as []*activities.Activity) error
+
+// WorkService keeps the progress of works in step with the activities
+// logged for them. Activities are created, deleted and restored in the same
+// transaction as the progress they add to their work, so that progress is
+// only counted while an activity is. Other state kept from activities is
+// updated in the same transaction by hooks.
+type WorkService struct {
+	*WorkRepository
+	ar    *activities.ActivityRepository
+	hooks []ActivityHook
+}
+
+func NewWorkService(repo *WorkRepository, ar *activities.ActivityRepository) *WorkService {
+	return &WorkService{WorkRepository: repo, ar: ar}
+}
+
+// OnChange adds a hook called whenever activities are changed. Hooks must be
+// added before any activities are changed.
+func (s *WorkService) OnChange(h ActivityHook) {
+	s.hooks = append(s.hooks, h)
+}
+
+// runHooks calls the hooks once for each user whose activities changed.
+func (s *WorkService) runHooks(ctx context.Context, tx pgx.Tx, change ActivityChange, as []*activities.Activity) error {
+	byUser := make(map[string][]*activities.Activity)
+	userIDs := make([]string, 0, 1)
+
+	for _, a := range as {
+		if _, ok := byUser[a.UserID]; !ok {
+			userIDs = append(userIDs, a.UserID)
+		}
+		byUser[a.UserID] = append(byUser[a.UserID], a)
+	}
+
+	for _, userID := range userIDs {
+		for _, h := range s.hooks {
+			if err := h(ctx, tx, change, userID, byUser[userID]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Log creates activities, adding their progress to the works they belong to
+// in the user's library and linking them to those works. The returned works
+// line up with the activities, and are nil for activities which aren't
+// tracked as works.
+func (s *WorkService) Log(ctx context.Context, as ...*activities.Activity) ([]*Work, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	ws, err := s.LogTx(ctx, tx, as...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws, tx.Commit(ctx)
+}
+
+// LogTx is Log as part of a larger transaction.
+func (s *WorkService) LogTx(ctx context.Context, tx pgx.Tx, as ...*activities.Activity) (ws []*Work, err error) {
+	ws = make([]*Work, len(as))
+	for i, a := range as {
+		key, progress := FromActivity(a)
+		if key == nil || key.Title == "" {
+			continue
+		}
+
+		ws[i], err = s.AddProgressTx(ctx, tx, key, progress, a.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		a.SetMeta(workIDMetaKey, ws[i].ID)
+	}
+
+	if err = s.ar.CreateManyTx(ctx, tx, as); err != nil {
+		return nil, err
+	}
+
+	if err = s.runHooks(ctx, tx, ActivitiesLogged, as); err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// LogUntracked creates activities without adding progress to works, for
+// activities logged by hand rather than for a known title.
+func (s *WorkService) LogUntracked(ctx context.Context, as ...*activities.Activity) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err = s.ar.CreateManyTx(ctx, tx, as); err != nil {
+		return err
+	}
+
+	if err = s.runHooks(ctx, tx, ActivitiesLogged, as); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Import creates imported activities. Imported activities don't add
+// progress to works, so links to works, such as those in exports, are
+// removed to keep undoing the import from removing progress.
+func (s *WorkService) Import(ctx context.Context, as []*activities.Activity) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	for _, a := range as {
+		Unlink(a)
+	}
+
+	if err = s.ar.ImportManyTx(ctx, tx, as); err != nil {
+		return err
+	}
+
+	if err = s.runHooks(ctx, tx, ActivitiesImported, as); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteActivity deletes an activity, removing its progress from its work.
+func (s *WorkService) DeleteActivity(ctx context.Context, id uint64) error {
+	_, err := s.changeActivities(ctx, false, func(tx pgx.Tx) ([]*activities.Activity, error) {
+		return s.ar.DeleteByIDTx(ctx, tx, id)
+	})
+	return err
+}
+
+// UndoImport deletes the activities of an import, returning how many were
+// deleted. Imported activities aren't linked to works, so no progress is
+// removed.
+func (s *WorkService) UndoImport(ctx context.Context, userID string, timestamp time.Time) (int64, error) {
+	return s.changeActivities(ctx, false, func(tx pgx.Tx) ([]*activities.Activity, error) {
+		return s.ar.UndoImportByUserIDAndTimestampTx(ctx, tx, userID, timestamp)
+	})
+}
+
+// RestoreActivity restores a deleted activity of the user, adding its
+// progress back to its work. Returns false if no such activity was found.
+func (s *WorkService) RestoreActivity(ctx context.Context, id uint64, userID string) (bool, error) {
+	n, err := s.changeActivities(ctx, true, func(tx pgx.Tx) ([]*activities.Activity, error) {
+		return s.ar.RestoreByIDTx(ctx, tx, id, userID)
+	})
+	return n > 0, err
+}
+
+// RestoreImport restores the deleted activities of an import, returning how
+// many were restored.
+func (s *WorkService) RestoreImport(ctx context.Context, userID string, timestamp time.Time) (int64, error) {
+	return s.changeActivities(ctx, true, func(tx pgx.Tx) ([]*activities.Activity, error) {
+		return s.ar.RestoreImportByUserIDAndTimestampTx(ctx, tx, userID, timestamp)
+	})
+}
+
+// changeActivities deletes or restores activities with changeTx, then removes
+// or adds back their progress to the works they are linked to, all in one
+// transaction. Works removed from the user's library are left alone.
+func (s *WorkService) changeActivities(
+	ctx context.Context,
+	restore bool,
+	changeTx func(tx pgx.Tx) ([]*activities.Activity, error),
+) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	as, err := changeTx(tx)
+	if err != nil || len(as) == 0 {
+		return 0, err
+	}
+
+	for _, a := range as {
+		id, ok := LinkedWorkID(a)
+		if !ok {
+			continue
+		}
+
+		_, progress := FromActivity(a)
+		err = s.UpdateByIDTx(ctx, tx, id, a.UserID, func(w *Work) {
+			if restore {
+				w.AddProgress(progress, a.Date)
+			} else {
+				w.RemoveProgress(progress)
+			}
+		})
+		if err != nil && !errors.Is(err, ErrWorkNotFound) {
+			return 0, err
+		}
+	}
+
+	change := ActivitiesDeleted
+	if restore {
+		change = ActivitiesRestored
+	}
+
+	if err = s.runHooks(ctx, tx, change, as); err != nil {
+		return 0, err
+	}
+
+	return int64(len(as)), tx.Commit(ctx)
+}
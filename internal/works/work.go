@@ -0,0 +1,222 @@
+package works
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xoltia/botsu/internal/activities"
+)
+
+const (
+	StatusReading   = "reading"
+	StatusCompleted = "completed"
+	StatusDropped   = "dropped"
+	StatusOnHold    = "on_hold"
+)
+
+// Work is a title in a user's library. Works are identified by their AniDB
+// or VNDB ID when logged through autocomplete, and by title otherwise.
+type Work struct {
+	ID              int64
+	UserID          string
+	MediaType       string
+	ExternalID      *string
+	Title           string
+	Status          string
+	Episodes        int
+	TotalEpisodes   *int
+	Volumes         int
+	TotalVolumes    *int
+	Characters      int
+	TotalCharacters *int
+	Pages           int
+	Rating          *int
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Progress is the amount of a work consumed by a single activity.
+type Progress struct {
+	Episodes   int
+	Characters int
+	Pages      int
+}
+
+func (p Progress) IsZero() bool {
+	return p.Episodes == 0 && p.Characters == 0 && p.Pages == 0
+}
+
+// Meta key linking an activity to the work its progress was added to.
+const workIDMetaKey = "work_id"
+
+// LinkedWorkID returns the ID of the work an activity's progress was added
+// to, if any.
+func LinkedWorkID(a *activities.Activity) (int64, bool) {
+	id, ok := a.MetaNumber(workIDMetaKey)
+	return int64(id), ok
+}
+
+// Unlink removes the link from an activity to a work, for activities whose
+// progress was never added to it, such as imported ones.
+func Unlink(a *activities.Activity) {
+	if meta, ok := a.Meta.(map[string]interface{}); ok {
+		delete(meta, workIDMetaKey)
+	}
+}
+
+// ExternalIDMetaKeys maps media types to the activity meta key holding the
+// ID of the work in an external database.
+var ExternalIDMetaKeys = map[string]string{
+	activities.ActivityMediaTypeAnime:       "anidb_id",
+	activities.ActivityMediaTypeVisualNovel: "vndb_id",
+}
+
+// FromActivity returns the work an activity belongs to along with the
+// progress made by it. Returns nil for activities which aren't tracked as
+// works, such as videos.
+func FromActivity(a *activities.Activity) (*Work, Progress) {
+	if a.MediaType == nil {
+		return nil, Progress{}
+	}
+
+	switch *a.MediaType {
+	case activities.ActivityMediaTypeAnime,
+		activities.ActivityMediaTypeVisualNovel,
+		activities.ActivityMediaTypeBook,
+		activities.ActivityMediaTypeManga:
+	default:
+		return nil, Progress{}
+	}
+
+	w := &Work{
+		UserID:    a.UserID,
+		MediaType: *a.MediaType,
+		Title:     strings.TrimSpace(a.Name),
+		Status:    StatusReading,
+	}
+
+	meta, _ := a.Meta.(map[string]interface{})
+
+	if key, ok := ExternalIDMetaKeys[w.MediaType]; ok {
+		if id, ok := meta[key].(string); ok && id != "" {
+			w.ExternalID = &id
+		}
+	}
+
+	p := Progress{
+		Episodes:   metaInt(a, "episodes"),
+		Characters: metaInt(a, "characters"),
+		Pages:      metaInt(a, "pages"),
+	}
+
+	return w, p
+}
+
+func metaInt(a *activities.Activity, key string) int {
+	n, _ := a.MetaNumber(key)
+	return int(n)
+}
+
+// IsFinished reports whether the progress of the work has reached any of its
+// known totals.
+func (w *Work) IsFinished() bool {
+	return (w.TotalEpisodes != nil && *w.TotalEpisodes > 0 && w.Episodes >= *w.TotalEpisodes) ||
+		(w.TotalVolumes != nil && *w.TotalVolumes > 0 && w.Volumes >= *w.TotalVolumes) ||
+		(w.TotalCharacters != nil && *w.TotalCharacters > 0 && w.Characters >= *w.TotalCharacters)
+}
+
+// AddProgress adds the progress of an activity done at the given time.
+// Logging a dropped or on hold work resumes it, and reaching a total
+// completes it.
+func (w *Work) AddProgress(p Progress, at time.Time) {
+	w.Episodes += p.Episodes
+	w.Characters += p.Characters
+	w.Pages += p.Pages
+
+	if w.StartedAt == nil || at.Before(*w.StartedAt) {
+		w.StartedAt = &at
+	}
+
+	if w.Status == StatusCompleted {
+		return
+	}
+
+	w.Status = StatusReading
+	w.UpdateStatus(at)
+}
+
+// RemoveProgress removes the progress of an activity that no longer counts,
+// such as a deleted one. A work completed by reaching a total is reopened if
+// it no longer has.
+func (w *Work) RemoveProgress(p Progress) {
+	wasFinished := w.IsFinished()
+
+	w.Episodes = max(w.Episodes-p.Episodes, 0)
+	w.Characters = max(w.Characters-p.Characters, 0)
+	w.Pages = max(w.Pages-p.Pages, 0)
+
+	if w.Status == StatusCompleted && wasFinished && !w.IsFinished() {
+		w.Status = StatusReading
+		w.FinishedAt = nil
+	}
+}
+
+// UpdateStatus completes the work if it is finished, setting the finish date
+// to the given time if it isn't already set.
+func (w *Work) UpdateStatus(at time.Time) {
+	if w.Status == StatusReading && w.IsFinished() {
+		w.Status = StatusCompleted
+	}
+
+	if w.Status == StatusCompleted && w.FinishedAt == nil {
+		w.FinishedAt = &at
+	}
+}
+
+// DescribeProgress returns a short description of the progress made on the
+// work, such as "5/12 episodes".
+func (w *Work) DescribeProgress() string {
+	parts := make([]string, 0, 3)
+
+	describe := func(n int, total *int, unit string) {
+		if n == 0 && total == nil {
+			return
+		}
+
+		if total != nil {
+			parts = append(parts, fmt.Sprintf("%d/%d %s", n, *total, unit))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %s", n, unit))
+		}
+	}
+
+	describe(w.Episodes, w.TotalEpisodes, "episodes")
+	describe(w.Volumes, w.TotalVolumes, "volumes")
+	describe(w.Characters, w.TotalCharacters, "characters")
+	describe(w.Pages, nil, "pages")
+
+	if len(parts) == 0 {
+		return "No progress"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// StatusName returns a human readable name for a work status.
+func StatusName(status string) string {
+	switch status {
+	case StatusReading:
+		return "Reading"
+	case StatusCompleted:
+		return "Completed"
+	case StatusDropped:
+		return "Dropped"
+	case StatusOnHold:
+		return "On hold"
+	default:
+		return status
+	}
+}
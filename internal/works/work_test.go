@@ -0,0 +1,119 @@
+package works_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/internal/works"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+func TestFromActivity(t *testing.T) {
+	a := activities.NewActivity()
+	a.UserID = "1"
+	a.Name = " Frieren "
+	a.MediaType = ref.New(activities.ActivityMediaTypeAnime)
+	a.SetMeta("anidb_id", "17617")
+	a.SetMeta("episodes", uint(3))
+
+	w, p := works.FromActivity(a)
+	if assert.NotNil(t, w) {
+		assert.Equal(t, "1", w.UserID)
+		assert.Equal(t, "Frieren", w.Title)
+		assert.Equal(t, activities.ActivityMediaTypeAnime, w.MediaType)
+		assert.Equal(t, ref.New("17617"), w.ExternalID)
+	}
+	assert.Equal(t, works.Progress{Episodes: 3}, p)
+
+	book := activities.NewActivity()
+	book.Name = "Kokoro"
+	book.MediaType = ref.New(activities.ActivityMediaTypeBook)
+	book.SetMeta("pages", uint(50))
+
+	w, p = works.FromActivity(book)
+	if assert.NotNil(t, w) {
+		assert.Nil(t, w.ExternalID)
+	}
+	assert.Equal(t, works.Progress{Pages: 50}, p)
+
+	video := activities.NewActivity()
+	video.MediaType = ref.New(activities.ActivityMediaTypeVideo)
+
+	w, _ = works.FromActivity(video)
+	assert.Nil(t, w)
+}
+
+func TestAddProgress(t *testing.T) {
+	first := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+
+	w := &works.Work{Status: works.StatusOnHold, TotalEpisodes: ref.New(4)}
+
+	w.AddProgress(works.Progress{Episodes: 3}, second)
+	assert.Equal(t, works.StatusReading, w.Status)
+	assert.Equal(t, second, *w.StartedAt)
+	assert.Nil(t, w.FinishedAt)
+
+	// Logging an earlier activity moves the start date back
+	w.AddProgress(works.Progress{Episodes: 1}, first)
+	assert.Equal(t, works.StatusCompleted, w.Status)
+	assert.Equal(t, first, *w.StartedAt)
+	assert.Equal(t, first, *w.FinishedAt)
+	assert.Equal(t, "4/4 episodes", w.DescribeProgress())
+
+	// Rewatching a completed work keeps it completed
+	w.AddProgress(works.Progress{Episodes: 1}, second)
+	assert.Equal(t, works.StatusCompleted, w.Status)
+	assert.Equal(t, first, *w.FinishedAt)
+}
+
+func TestRemoveProgress(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	w := &works.Work{TotalEpisodes: ref.New(4)}
+	w.AddProgress(works.Progress{Episodes: 4}, at)
+	assert.Equal(t, works.StatusCompleted, w.Status)
+
+	// Removing progress reopens works completed by reaching a total
+	w.RemoveProgress(works.Progress{Episodes: 1})
+	assert.Equal(t, works.StatusReading, w.Status)
+	assert.Nil(t, w.FinishedAt)
+	assert.Equal(t, "3/4 episodes", w.DescribeProgress())
+
+	// Progress never goes below zero
+	w.RemoveProgress(works.Progress{Episodes: 5, Pages: 1})
+	assert.Equal(t, 0, w.Episodes)
+	assert.Equal(t, 0, w.Pages)
+
+	// Works completed by hand stay completed
+	manual := &works.Work{Status: works.StatusCompleted, FinishedAt: &at, Pages: 100}
+	manual.RemoveProgress(works.Progress{Pages: 50})
+	assert.Equal(t, works.StatusCompleted, manual.Status)
+	assert.Equal(t, 50, manual.Pages)
+}
+
+func TestLinkedWorkID(t *testing.T) {
+	a := activities.NewActivity()
+	_, ok := works.LinkedWorkID(a)
+	assert.False(t, ok)
+
+	a.SetMeta("work_id", int64(42))
+	id, ok := works.LinkedWorkID(a)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), id)
+
+	works.Unlink(a)
+	_, ok = works.LinkedWorkID(a)
+	assert.False(t, ok)
+}
+
+func TestDescribeProgress(t *testing.T) {
+	w := &works.Work{}
+	assert.Equal(t, "No progress", w.DescribeProgress())
+
+	w.Characters = 12000
+	w.TotalVolumes = ref.New(3)
+	assert.Equal(t, "0/3 volumes, 12000 characters", w.DescribeProgress())
+}
@@ -0,0 +1 @@
+DROP INDEX activities_name_trgm_index;
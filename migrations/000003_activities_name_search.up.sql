@@ -0,0 +1,3 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX activities_name_trgm_index ON activities USING GIN (name gin_trgm_ops);
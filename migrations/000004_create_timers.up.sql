@@ -0,0 +1,14 @@
+CREATE TABLE timers (
+    user_id VARCHAR(20) PRIMARY KEY,
+    guild_id VARCHAR(20),
+    channel_id VARCHAR(20),
+    message_id VARCHAR(20),
+    name TEXT NOT NULL,
+    primary_type activity_primary_type NOT NULL,
+    media_type activity_media_type,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- NULL while the timer is paused
+    resumed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- time accumulated before resumed_at, in nanoseconds
+    elapsed BIGINT NOT NULL DEFAULT 0
+);
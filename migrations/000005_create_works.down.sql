@@ -0,0 +1,2 @@
+DROP TABLE works;
+DROP TYPE work_status;
@@ -0,0 +1,29 @@
+CREATE TYPE work_status AS ENUM('reading', 'completed', 'dropped', 'on_hold');
+
+CREATE TABLE works (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(20) NOT NULL,
+    media_type activity_media_type NOT NULL,
+    -- AniDB or VNDB ID, NULL for works tracked by title
+    external_id TEXT,
+    title TEXT NOT NULL,
+    status work_status NOT NULL DEFAULT 'reading',
+    episodes INTEGER NOT NULL DEFAULT 0,
+    total_episodes INTEGER,
+    volumes INTEGER NOT NULL DEFAULT 0,
+    total_volumes INTEGER,
+    characters INTEGER NOT NULL DEFAULT 0,
+    total_characters INTEGER,
+    pages INTEGER NOT NULL DEFAULT 0,
+    rating SMALLINT CHECK (rating BETWEEN 1 AND 10),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc')
+);
+
+CREATE UNIQUE INDEX works_external_id_index ON works (user_id, media_type, external_id)
+WHERE external_id IS NOT NULL;
+
+CREATE UNIQUE INDEX works_title_index ON works (user_id, media_type, lower(title))
+WHERE external_id IS NULL;
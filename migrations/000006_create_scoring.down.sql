@@ -0,0 +1,2 @@
+DROP TABLE activity_points;
+ALTER TABLE guilds DROP COLUMN scoring;
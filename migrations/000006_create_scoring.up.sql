@@ -0,0 +1,11 @@
+-- NULL uses the default of one point per minute
+ALTER TABLE guilds ADD COLUMN scoring JSONB;
+
+CREATE TABLE activity_points (
+    PRIMARY KEY (guild_id, activity_id),
+    guild_id VARCHAR(20) NOT NULL REFERENCES guilds(id) ON DELETE CASCADE,
+    activity_id BIGINT NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+    points DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX activity_points_activity_id_index ON activity_points (activity_id);
@@ -0,0 +1,10 @@
+DROP TRIGGER rebuild_daily_user_totals_on_guild_timezone ON guilds;
+DROP TRIGGER rebuild_daily_user_totals_on_user_timezone ON users;
+DROP TRIGGER update_daily_user_totals ON activities;
+DROP FUNCTION rebuild_daily_user_totals_on_guild_timezone;
+DROP FUNCTION rebuild_daily_user_totals_on_user_timezone;
+DROP FUNCTION rebuild_daily_user_totals;
+DROP FUNCTION update_daily_user_totals;
+DROP FUNCTION add_daily_user_total;
+DROP FUNCTION activity_local_date;
+DROP TABLE daily_user_totals;
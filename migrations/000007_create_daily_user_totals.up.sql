@@ -0,0 +1,180 @@
+-- Rollup of activity durations per user and local day, kept current by
+-- triggers. Used by leaderboards and charts instead of scanning activities.
+CREATE TABLE daily_user_totals (
+    user_id VARCHAR(20) NOT NULL,
+    -- Guild the activities were logged in, NULL for DMs and imports
+    guild_id VARCHAR(20),
+    -- Date in the user's timezone, falling back to the guild's
+    local_date DATE NOT NULL,
+    primary_type activity_primary_type NOT NULL,
+    media_type activity_media_type,
+    -- Only set for videos
+    video_platform TEXT,
+    video_channel_id TEXT,
+    duration BIGINT NOT NULL DEFAULT 0,
+    activity_count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX daily_user_totals_key_index ON daily_user_totals (
+    user_id,
+    local_date,
+    guild_id,
+    primary_type,
+    media_type,
+    video_platform,
+    video_channel_id
+) NULLS NOT DISTINCT;
+
+CREATE FUNCTION activity_local_date(a activities)
+RETURNS DATE AS $$
+    SELECT (a.date AT TIME ZONE COALESCE(
+        (SELECT timezone FROM users WHERE id = a.user_id),
+        (SELECT timezone FROM guilds WHERE id = a.guild_id),
+        'UTC'
+    ))::DATE;
+$$ LANGUAGE sql STABLE;
+
+-- Adds (sign = 1) or removes (sign = -1) an activity from the totals
+CREATE FUNCTION add_daily_user_total(a activities, sign INTEGER)
+RETURNS VOID AS $$
+DECLARE
+    key_date DATE := activity_local_date(a);
+    key_platform TEXT := CASE WHEN a.media_type = 'video' THEN a.meta->>'platform' END;
+    key_channel_id TEXT := CASE WHEN a.media_type = 'video' THEN a.meta->>'channel_id' END;
+BEGIN
+    INSERT INTO daily_user_totals (
+        user_id,
+        guild_id,
+        local_date,
+        primary_type,
+        media_type,
+        video_platform,
+        video_channel_id,
+        duration,
+        activity_count
+    )
+    VALUES (
+        a.user_id,
+        a.guild_id,
+        key_date,
+        a.primary_type,
+        a.media_type,
+        key_platform,
+        key_channel_id,
+        sign * a.duration,
+        sign
+    )
+    ON CONFLICT (user_id, local_date, guild_id, primary_type, media_type, video_platform, video_channel_id)
+    DO UPDATE SET
+        duration = daily_user_totals.duration + EXCLUDED.duration,
+        activity_count = daily_user_totals.activity_count + EXCLUDED.activity_count;
+
+    IF sign < 0 THEN
+        DELETE FROM daily_user_totals
+        WHERE user_id = a.user_id
+        AND local_date = key_date
+        AND guild_id IS NOT DISTINCT FROM a.guild_id
+        AND primary_type = a.primary_type
+        AND media_type IS NOT DISTINCT FROM a.media_type
+        AND video_platform IS NOT DISTINCT FROM key_platform
+        AND video_channel_id IS NOT DISTINCT FROM key_channel_id
+        AND activity_count <= 0;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION update_daily_user_totals()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.deleted_at IS NULL THEN
+            PERFORM add_daily_user_total(OLD, -1);
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.deleted_at IS NULL THEN
+            PERFORM add_daily_user_total(NEW, 1);
+        END IF;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_daily_user_totals
+AFTER INSERT OR UPDATE OR DELETE ON activities
+FOR EACH ROW EXECUTE PROCEDURE update_daily_user_totals();
+
+-- Recomputes all totals of a user, such as after changing timezones
+CREATE FUNCTION rebuild_daily_user_totals(target_user_id VARCHAR(20))
+RETURNS VOID AS $$
+BEGIN
+    DELETE FROM daily_user_totals
+    WHERE user_id = target_user_id;
+
+    INSERT INTO daily_user_totals (
+        user_id,
+        guild_id,
+        local_date,
+        primary_type,
+        media_type,
+        video_platform,
+        video_channel_id,
+        duration,
+        activity_count
+    )
+    SELECT
+        a.user_id,
+        a.guild_id,
+        activity_local_date(a),
+        a.primary_type,
+        a.media_type,
+        CASE WHEN a.media_type = 'video' THEN a.meta->>'platform' END,
+        CASE WHEN a.media_type = 'video' THEN a.meta->>'channel_id' END,
+        SUM(a.duration),
+        COUNT(*)
+    FROM activities a
+    WHERE a.user_id = target_user_id
+    AND a.deleted_at IS NULL
+    GROUP BY 1, 2, 3, 4, 5, 6, 7;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION rebuild_daily_user_totals_on_user_timezone()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM rebuild_daily_user_totals(NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER rebuild_daily_user_totals_on_user_timezone
+AFTER UPDATE OF timezone ON users
+FOR EACH ROW
+WHEN (OLD.timezone IS DISTINCT FROM NEW.timezone)
+EXECUTE PROCEDURE rebuild_daily_user_totals_on_user_timezone();
+
+-- Users without a timezone fall back to the timezone of the guild each
+-- activity was logged in
+CREATE FUNCTION rebuild_daily_user_totals_on_guild_timezone()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM rebuild_daily_user_totals(u.id)
+    FROM users u
+    WHERE u.timezone IS NULL
+    AND EXISTS (
+        SELECT 1
+        FROM activities a
+        WHERE a.user_id = u.id
+        AND a.guild_id = NEW.id
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER rebuild_daily_user_totals_on_guild_timezone
+AFTER UPDATE OF timezone ON guilds
+FOR EACH ROW
+WHEN (OLD.timezone IS DISTINCT FROM NEW.timezone)
+EXECUTE PROCEDURE rebuild_daily_user_totals_on_guild_timezone();
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN last_exported_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,3 @@
+-- Nanoseconds, like activities.duration. NULL uses the default window and 0
+-- disables duplicate warnings.
+ALTER TABLE guilds ADD COLUMN duplicate_window BIGINT;
@@ -0,0 +1,6 @@
+DELETE FROM goals WHERE unit <> 'duration';
+
+ALTER TABLE goals
+    DROP COLUMN unit,
+    ALTER COLUMN target TYPE BIGINT USING round(target),
+    ALTER COLUMN current TYPE BIGINT USING round(current);
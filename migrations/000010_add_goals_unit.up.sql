@@ -0,0 +1,5 @@
+-- Durations stay in nanoseconds, other units are counts or points.
+ALTER TABLE goals
+    ADD COLUMN unit TEXT NOT NULL DEFAULT 'duration',
+    ALTER COLUMN target TYPE DOUBLE PRECISION,
+    ALTER COLUMN current TYPE DOUBLE PRECISION;
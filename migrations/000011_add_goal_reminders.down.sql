@@ -0,0 +1,8 @@
+ALTER TABLE goals
+    DROP COLUMN summary,
+    DROP COLUMN reminded_at;
+
+ALTER TABLE users
+    DROP COLUMN goal_reminders,
+    DROP COLUMN quiet_hours_start,
+    DROP COLUMN quiet_hours_end;
@@ -0,0 +1,10 @@
+-- How long before goals are due to remind the user, in nanoseconds. NULL or
+-- empty disables reminders.
+ALTER TABLE users
+    ADD COLUMN goal_reminders BIGINT[],
+    ADD COLUMN quiet_hours_start SMALLINT,
+    ADD COLUMN quiet_hours_end SMALLINT;
+
+ALTER TABLE goals
+    ADD COLUMN summary BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN reminded_at TIMESTAMP WITH TIME ZONE;
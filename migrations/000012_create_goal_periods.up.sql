@@ -0,0 +1,11 @@
+CREATE TABLE goal_periods (
+    id BIGSERIAL PRIMARY KEY,
+    goal_id BIGINT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- In the unit of the goal, see goals.unit
+    target DOUBLE PRECISION NOT NULL,
+    achieved DOUBLE PRECISION NOT NULL,
+    completed BOOLEAN NOT NULL,
+    UNIQUE (goal_id, end_at)
+);
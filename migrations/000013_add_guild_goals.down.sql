@@ -0,0 +1,11 @@
+DROP TABLE goal_contributions;
+
+DELETE FROM goals WHERE guild_id IS NOT NULL;
+
+DROP INDEX goals_guild_id_idx;
+
+ALTER TABLE goals
+    DROP COLUMN guild_id,
+    DROP COLUMN channel_id,
+    DROP COLUMN message_id,
+    DROP COLUMN message_stale;
@@ -0,0 +1,18 @@
+-- Guild goals are goals with a guild_id, counting the activities of every
+-- member of the guild. Their user_id is the member who created them.
+ALTER TABLE goals
+    ADD COLUMN guild_id VARCHAR(20) REFERENCES guilds(id),
+    ADD COLUMN channel_id VARCHAR(20),
+    ADD COLUMN message_id VARCHAR(20),
+    ADD COLUMN message_stale BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX goals_guild_id_idx ON goals (guild_id) WHERE guild_id IS NOT NULL;
+
+CREATE TABLE goal_contributions (
+    goal_id BIGINT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    -- due_at of the goal during the period contributed to
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_id VARCHAR(20) NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (goal_id, period_end, user_id)
+);
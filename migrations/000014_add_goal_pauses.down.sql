@@ -0,0 +1,3 @@
+DROP TABLE goal_changes;
+
+ALTER TABLE goals DROP COLUMN paused_at;
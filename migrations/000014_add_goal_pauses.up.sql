@@ -0,0 +1,11 @@
+ALTER TABLE goals ADD COLUMN paused_at TIMESTAMP WITH TIME ZONE;
+
+-- Edits, pauses and resumptions of goals, shown in their history
+CREATE TABLE goal_changes (
+    id BIGSERIAL PRIMARY KEY,
+    goal_id BIGINT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    description TEXT NOT NULL
+);
+
+CREATE INDEX goal_changes_goal_id_idx ON goal_changes (goal_id, changed_at);
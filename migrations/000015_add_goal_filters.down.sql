@@ -0,0 +1,5 @@
+ALTER TABLE goals
+    DROP COLUMN work,
+    DROP COLUMN name_contains,
+    DROP COLUMN tag,
+    DROP COLUMN youtube_channel_ids;
@@ -0,0 +1,7 @@
+ALTER TABLE goals
+    -- An AniDB or VNDB work, e.g. anidb:69 or vndb:v17
+    ADD COLUMN work TEXT,
+    ADD COLUMN name_contains TEXT,
+    ADD COLUMN tag TEXT,
+    -- IDs of youtube_channels, resolved when the goal is created
+    ADD COLUMN youtube_channel_ids TEXT[];
@@ -0,0 +1 @@
+DROP INDEX goals_due_at_idx;
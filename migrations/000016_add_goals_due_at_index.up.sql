@@ -0,0 +1,2 @@
+-- Used by the rollover worker to find due goals
+CREATE INDEX goals_due_at_idx ON goals (due_at) WHERE deleted_at IS NULL AND paused_at IS NULL;
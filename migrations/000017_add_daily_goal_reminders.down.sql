@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN daily_goal_reminder,
+    DROP COLUMN daily_goal_reminded_on;
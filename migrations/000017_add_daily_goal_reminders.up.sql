@@ -0,0 +1,5 @@
+-- How long before local midnight to remind the user of an unmet daily goal,
+-- in nanoseconds. NULL disables the reminder.
+ALTER TABLE users
+    ADD COLUMN daily_goal_reminder BIGINT,
+    ADD COLUMN daily_goal_reminded_on DATE;
@@ -0,0 +1,2 @@
+ALTER TABLE goals
+    DROP COLUMN interval_days;
@@ -0,0 +1,4 @@
+ALTER TABLE goals
+    -- Set for goals which reset every number of days rather than on a cron
+    -- schedule. Goals with neither end once, at due_at.
+    ADD COLUMN interval_days INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE timers
+    DROP COLUMN paused_at;
@@ -0,0 +1,7 @@
+ALTER TABLE timers
+    -- Set while the timer is paused, so that abandoned timers can be stopped
+    ADD COLUMN paused_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE timers
+SET paused_at = CURRENT_TIMESTAMP
+WHERE resumed_at IS NULL;
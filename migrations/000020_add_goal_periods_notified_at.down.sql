@@ -0,0 +1,4 @@
+DROP INDEX goal_periods_unnotified_index;
+
+ALTER TABLE goal_periods
+    DROP COLUMN notified_at;
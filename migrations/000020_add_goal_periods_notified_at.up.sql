@@ -0,0 +1,10 @@
+ALTER TABLE goal_periods
+    -- Set once the period has been passed on to the rollover worker's
+    -- handlers, such as summaries, however the period was closed
+    ADD COLUMN notified_at TIMESTAMP WITH TIME ZONE;
+
+-- Periods closed before this were already summarized or have been missed
+UPDATE goal_periods
+SET notified_at = end_at;
+
+CREATE INDEX goal_periods_unnotified_index ON goal_periods (end_at) WHERE notified_at IS NULL;
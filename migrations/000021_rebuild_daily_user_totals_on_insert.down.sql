@@ -0,0 +1,39 @@
+CREATE OR REPLACE FUNCTION rebuild_daily_user_totals_on_user_timezone()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM rebuild_daily_user_totals(NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER rebuild_daily_user_totals_on_user_timezone ON users;
+
+CREATE TRIGGER rebuild_daily_user_totals_on_user_timezone
+AFTER UPDATE OF timezone ON users
+FOR EACH ROW
+WHEN (OLD.timezone IS DISTINCT FROM NEW.timezone)
+EXECUTE PROCEDURE rebuild_daily_user_totals_on_user_timezone();
+
+CREATE OR REPLACE FUNCTION rebuild_daily_user_totals_on_guild_timezone()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM rebuild_daily_user_totals(u.id)
+    FROM users u
+    WHERE u.timezone IS NULL
+    AND EXISTS (
+        SELECT 1
+        FROM activities a
+        WHERE a.user_id = u.id
+        AND a.guild_id = NEW.id
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER rebuild_daily_user_totals_on_guild_timezone ON guilds;
+
+CREATE TRIGGER rebuild_daily_user_totals_on_guild_timezone
+AFTER UPDATE OF timezone ON guilds
+FOR EACH ROW
+WHEN (OLD.timezone IS DISTINCT FROM NEW.timezone)
+EXECUTE PROCEDURE rebuild_daily_user_totals_on_guild_timezone();
@@ -0,0 +1,59 @@
+-- Timezones are upserted, so users and guilds setting their first timezone
+-- insert rows rather than update them
+CREATE OR REPLACE FUNCTION rebuild_daily_user_totals_on_user_timezone()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.timezone IS NULL THEN
+            RETURN NULL;
+        END IF;
+    ELSIF OLD.timezone IS NOT DISTINCT FROM NEW.timezone THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM rebuild_daily_user_totals(NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER rebuild_daily_user_totals_on_user_timezone ON users;
+
+CREATE TRIGGER rebuild_daily_user_totals_on_user_timezone
+AFTER INSERT OR UPDATE OF timezone ON users
+FOR EACH ROW
+EXECUTE PROCEDURE rebuild_daily_user_totals_on_user_timezone();
+
+-- Users without a row in users have no timezone either
+CREATE OR REPLACE FUNCTION rebuild_daily_user_totals_on_guild_timezone()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.timezone IS NULL THEN
+            RETURN NULL;
+        END IF;
+    ELSIF OLD.timezone IS NOT DISTINCT FROM NEW.timezone THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM rebuild_daily_user_totals(a.user_id)
+    FROM (
+        SELECT DISTINCT user_id
+        FROM activities
+        WHERE guild_id = NEW.id
+    ) a
+    WHERE NOT EXISTS (
+        SELECT 1
+        FROM users u
+        WHERE u.id = a.user_id
+        AND u.timezone IS NOT NULL
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER rebuild_daily_user_totals_on_guild_timezone ON guilds;
+
+CREATE TRIGGER rebuild_daily_user_totals_on_guild_timezone
+AFTER INSERT OR UPDATE OF timezone ON guilds
+FOR EACH ROW
+EXECUTE PROCEDURE rebuild_daily_user_totals_on_guild_timezone();
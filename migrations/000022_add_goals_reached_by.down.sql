@@ -0,0 +1,2 @@
+ALTER TABLE goals
+    DROP COLUMN reached_by;
@@ -0,0 +1,4 @@
+ALTER TABLE goals
+    -- Member who took the current period of a guild goal past its target,
+    -- until that is announced
+    ADD COLUMN reached_by VARCHAR(20);
@@ -0,0 +1,2 @@
+ALTER TABLE guild_members
+    DROP COLUMN scored;
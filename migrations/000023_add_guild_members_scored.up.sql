@@ -0,0 +1,4 @@
+ALTER TABLE guild_members
+    -- Whether the activities the member logged before joining have been
+    -- scored for the guild, after which only new activities are scored
+    ADD COLUMN scored BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,419 @@
+package activities
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	internal "github.com/xoltia/botsu/internal/activities"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+var (
+	ErrInvalidMapping = errors.New("invalid column mapping")
+	ErrMissingColumn  = errors.New("missing column")
+)
+
+// CSVMapping names the header column each activity field is read from. Empty
+// fields are not read.
+type CSVMapping struct {
+	Name      string
+	Type      string
+	MediaType string
+	Duration  string
+	Date      string
+	Notes     string
+}
+
+var csvFields = []string{"name", "type", "media-type", "duration", "date", "notes"}
+
+// Header names recognized by DetectCSVMapping, compared after normalizeHeader.
+var csvFieldAliases = map[string][]string{
+	"name":       {"name", "title", "activity", "work"},
+	"type":       {"type", "primarytype", "immersiontype", "category"},
+	"media-type": {"mediatype", "media", "medium"},
+	"duration":   {"duration", "durationminutes", "minutes", "mins", "time", "timespent", "length", "hours"},
+	"date":       {"date", "day", "datetime", "timestamp", "loggedat", "when"},
+	"notes":      {"notes", "note", "comment", "comments", "description"},
+}
+
+func (m *CSVMapping) field(name string) *string {
+	switch name {
+	case "name":
+		return &m.Name
+	case "type":
+		return &m.Type
+	case "media-type", "media_type", "mediatype":
+		return &m.MediaType
+	case "duration":
+		return &m.Duration
+	case "date":
+		return &m.Date
+	case "notes":
+		return &m.Notes
+	default:
+		return nil
+	}
+}
+
+// String formats the mapping the way ParseCSVMapping reads it.
+func (m CSVMapping) String() string {
+	var parts []string
+	for _, field := range csvFields {
+		if column := *m.field(field); column != "" {
+			parts = append(parts, field+"="+column)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseCSVMapping parses a mapping such as "name=Title, duration=Minutes".
+// Fields that are not given are left empty, and a field mapped to "-" is
+// explicitly not read.
+func ParseCSVMapping(s string) (m CSVMapping, err error) {
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		field, column, ok := strings.Cut(part, "=")
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return m, fmt.Errorf("%w: expected field=column, got %q", ErrInvalidMapping, strings.TrimSpace(part))
+		}
+
+		target := m.field(strings.ToLower(strings.TrimSpace(field)))
+		if target == nil {
+			return m, fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, strings.TrimSpace(field))
+		}
+
+		*target = column
+	}
+
+	return m, nil
+}
+
+func normalizeHeader(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// DetectCSVMapping guesses the mapping of a header row from common column
+// names, including those of CSV exports.
+func DetectCSVMapping(header []string) CSVMapping {
+	var m CSVMapping
+
+	for field, aliases := range csvFieldAliases {
+		target := m.field(field)
+		for _, alias := range aliases {
+			for _, column := range header {
+				if normalizeHeader(column) == alias {
+					*target = column
+					break
+				}
+			}
+			if *target != "" {
+				break
+			}
+		}
+	}
+
+	return m
+}
+
+// Override returns m with every field set in o replacing the field of m.
+// Fields of o set to "-" clear the field.
+func (m CSVMapping) Override(o CSVMapping) CSVMapping {
+	for _, field := range csvFields {
+		switch column := *o.field(field); column {
+		case "":
+		case "-":
+			*m.field(field) = ""
+		default:
+			*m.field(field) = column
+		}
+	}
+
+	return m
+}
+
+// CSVRow is a row read by ReadCSV. Err is set if the row could not be read,
+// in which case Activity is nil.
+type CSVRow = internal.ImportRow
+
+// Type keywords accepted in the type and media type columns.
+var csvMediaTypes = map[string]string{
+	"anime":        internal.ActivityMediaTypeAnime,
+	"manga":        internal.ActivityMediaTypeManga,
+	"book":         internal.ActivityMediaTypeBook,
+	"books":        internal.ActivityMediaTypeBook,
+	"novel":        internal.ActivityMediaTypeBook,
+	"ln":           internal.ActivityMediaTypeBook,
+	"vn":           internal.ActivityMediaTypeVisualNovel,
+	"visualnovel":  internal.ActivityMediaTypeVisualNovel,
+	"video":        internal.ActivityMediaTypeVideo,
+	"videos":       internal.ActivityMediaTypeVideo,
+	"youtube":      internal.ActivityMediaTypeVideo,
+	"アニメ":          internal.ActivityMediaTypeAnime,
+	"漫画":           internal.ActivityMediaTypeManga,
+	"本":            internal.ActivityMediaTypeBook,
+	"ビジュアルノベル":     internal.ActivityMediaTypeVisualNovel,
+	"動画":           internal.ActivityMediaTypeVideo,
+	"youtubevideo": internal.ActivityMediaTypeVideo,
+}
+
+var csvPrimaryTypes = map[string]string{
+	"listening": internal.ActivityImmersionTypeListening,
+	"listen":    internal.ActivityImmersionTypeListening,
+	"reading":   internal.ActivityImmersionTypeReading,
+	"read":      internal.ActivityImmersionTypeReading,
+	"聴解":        internal.ActivityImmersionTypeListening,
+	"読書":        internal.ActivityImmersionTypeReading,
+}
+
+func primaryTypeOf(mediaType string) string {
+	switch mediaType {
+	case internal.ActivityMediaTypeAnime, internal.ActivityMediaTypeVideo:
+		return internal.ActivityImmersionTypeListening
+	default:
+		return internal.ActivityImmersionTypeReading
+	}
+}
+
+var hourMinutePattern = regexp.MustCompile(`^(\d+):(\d{2})$`)
+
+// Formats tried for dates that timeparse.ParseDate does not accept.
+var csvDateLayouts = []string{
+	"1/2/2006",
+	"1/2/2006 15:04",
+	"1/2/2006 15:04:05",
+	"1/2/2006 3:04 PM",
+	"1/2/2006 3:04:05 PM",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Mon, 02 Jan 2006 15:04:05",
+}
+
+// ReadCSV reads activities from a CSV file with a header row. Columns not
+// set in mapping are detected from the header. Dates are resolved relative
+// to now, and rows without a date are dated now.
+func ReadCSV(r io.Reader, mapping CSVMapping, now time.Time) ([]CSVRow, CSVMapping, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, mapping, err
+	}
+
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	mapping = DetectCSVMapping(header).Override(mapping)
+
+	if mapping.Name == "" {
+		return nil, mapping, fmt.Errorf("%w: name", ErrMissingColumn)
+	}
+
+	if mapping.Duration == "" {
+		return nil, mapping, fmt.Errorf("%w: duration", ErrMissingColumn)
+	}
+
+	if mapping.Type == "" && mapping.MediaType == "" {
+		return nil, mapping, fmt.Errorf("%w: type or media-type", ErrMissingColumn)
+	}
+
+	columns := make(map[string]int, 6)
+	for _, field := range csvFields {
+		column := *mapping.field(field)
+		if column == "" {
+			continue
+		}
+
+		index := -1
+		for i, h := range header {
+			if normalizeHeader(h) == normalizeHeader(column) {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			return nil, mapping, fmt.Errorf("%w: no column named %q", ErrMissingColumn, column)
+		}
+
+		columns[field] = index
+	}
+
+	// Bare numbers are minutes unless the column says otherwise
+	durationUnit := time.Minute
+	if h := normalizeHeader(mapping.Duration); strings.Contains(h, "hour") {
+		durationUnit = time.Hour
+	} else if strings.Contains(h, "second") {
+		durationUnit = time.Second
+	}
+
+	var rows []CSVRow
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, CSVRow{Line: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, mapping, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		get := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		a, err := readCSVActivity(get, durationUnit, now)
+		rows = append(rows, CSVRow{Line: line, Activity: a, Err: err})
+	}
+
+	return rows, mapping, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func readCSVActivity(get func(field string) string, durationUnit time.Duration, now time.Time) (*Activity, error) {
+	a := internal.NewActivity()
+	a.Name = get("name")
+	a.Date = now
+	a.CreatedAt = now
+
+	if a.Name == "" {
+		return nil, errors.New("name is empty")
+	}
+
+	if mediaType := get("media-type"); mediaType != "" {
+		t, ok := csvMediaTypes[normalizeHeader(mediaType)]
+		if !ok {
+			return nil, fmt.Errorf("unknown media type %q", mediaType)
+		}
+		a.MediaType = &t
+		a.PrimaryType = primaryTypeOf(t)
+	}
+
+	// The type column may hold either a primary type or a media type
+	if primaryType := get("type"); primaryType != "" {
+		key := normalizeHeader(primaryType)
+		if t, ok := csvPrimaryTypes[key]; ok {
+			a.PrimaryType = t
+		} else if t, ok := csvMediaTypes[key]; ok {
+			if a.MediaType == nil {
+				a.MediaType = &t
+			}
+			a.PrimaryType = primaryTypeOf(t)
+		} else {
+			return nil, fmt.Errorf("unknown type %q", primaryType)
+		}
+	}
+
+	if a.PrimaryType == "" {
+		return nil, errors.New("type is empty")
+	}
+
+	duration, err := parseCSVDuration(get("duration"), durationUnit)
+	if err != nil {
+		return nil, err
+	}
+	a.Duration = duration
+
+	if date := get("date"); date != "" {
+		if a.Date, err = parseCSVDate(date, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if notes := get("notes"); notes != "" {
+		a.SetMeta("notes", notes)
+	}
+
+	return a, nil
+}
+
+func parseCSVDuration(s string, unit time.Duration) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("duration is empty")
+	}
+
+	invalid := fmt.Errorf("%w: %s", timeparse.ErrInvalidDuration, s)
+
+	var d time.Duration
+	if m := hourMinutePattern.FindStringSubmatch(s); m != nil {
+		// h:mm, which timeparse would not accept without seconds
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		if minutes > 59 {
+			return 0, invalid
+		}
+		d = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	} else if n, err := strconv.ParseFloat(s, 64); err == nil && unit != time.Minute {
+		d = time.Duration(n * float64(unit))
+	} else {
+		if d, err = timeparse.ParseDuration(s); err != nil {
+			return 0, invalid
+		}
+	}
+
+	if d <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+
+	return d, nil
+}
+
+func parseCSVDate(s string, now time.Time) (time.Time, error) {
+	if t, err := timeparse.ParseDate(s, now); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range csvDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %s", timeparse.ErrInvalidDate, s)
+}
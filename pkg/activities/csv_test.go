@@ -0,0 +1,104 @@
+package activities_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/pkg/activities"
+)
+
+func TestParseCSVMapping(t *testing.T) {
+	m, err := activities.ParseCSVMapping("name=Title, duration = Time Spent,date=-")
+	assert.NoError(t, err)
+	assert.Equal(t, activities.CSVMapping{Name: "Title", Duration: "Time Spent", Date: "-"}, m)
+
+	_, err = activities.ParseCSVMapping("title=Name")
+	assert.ErrorIs(t, err, activities.ErrInvalidMapping)
+
+	_, err = activities.ParseCSVMapping("name")
+	assert.ErrorIs(t, err, activities.ErrInvalidMapping)
+}
+
+func TestDetectCSVMapping(t *testing.T) {
+	m := activities.DetectCSVMapping([]string{"Title", "Media Type", "Time Spent", "Day", "Comment"})
+	assert.Equal(t, activities.CSVMapping{
+		Name:      "Title",
+		MediaType: "Media Type",
+		Duration:  "Time Spent",
+		Date:      "Day",
+		Notes:     "Comment",
+	}, m)
+}
+
+func TestReadCSV(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	input := "Title,Type,Hours,When,Notes\n" +
+		"Frieren,anime,1.5,2024-05-01,good\n" +
+		"\n" +
+		"Podcast,listening,0:45,5/2/2024,\n" +
+		"Book,novel,2,,\n" +
+		"Broken,painting,1,,\n" +
+		"Later,reading,soon,,\n"
+
+	rows, mapping, err := activities.ReadCSV(strings.NewReader(input), activities.CSVMapping{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hours", mapping.Duration)
+	assert.Len(t, rows, 5)
+
+	a := rows[0].Activity
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "Frieren", a.Name)
+	assert.Equal(t, "listening", a.PrimaryType)
+	assert.Equal(t, "anime", *a.MediaType)
+	assert.Equal(t, 90*time.Minute, a.Duration)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), a.Date)
+	assert.Equal(t, "good", a.Meta.(map[string]interface{})["notes"])
+
+	a = rows[1].Activity
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Nil(t, a.MediaType)
+	assert.Equal(t, 45*time.Minute, a.Duration)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), a.Date)
+
+	a = rows[2].Activity
+	assert.Equal(t, "reading", a.PrimaryType)
+	assert.Equal(t, "book", *a.MediaType)
+	assert.Equal(t, now, a.Date)
+
+	assert.Error(t, rows[3].Err)
+	assert.Error(t, rows[4].Err)
+}
+
+func TestReadCSVMapping(t *testing.T) {
+	input := "Activity,Minutes,Category\nFrieren,24,anime\n"
+
+	_, _, err := activities.ReadCSV(strings.NewReader(input), activities.CSVMapping{Name: "Show"}, time.Now())
+	assert.ErrorIs(t, err, activities.ErrMissingColumn)
+
+	_, _, err = activities.ReadCSV(strings.NewReader(input), activities.CSVMapping{Duration: "-"}, time.Now())
+	assert.ErrorIs(t, err, activities.ErrMissingColumn)
+
+	rows, _, err := activities.ReadCSV(strings.NewReader(input), activities.CSVMapping{}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Minute, rows[0].Activity.Duration)
+}
+
+func TestReadCSVExport(t *testing.T) {
+	exported := writeAll(t, activities.FormatCSV, testActivities())
+
+	rows, _, err := activities.ReadCSV(bytes.NewBufferString(exported), activities.CSVMapping{}, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	for i, a := range testActivities() {
+		assert.NoError(t, rows[i].Err)
+		assert.Equal(t, a.Name, rows[i].Activity.Name)
+		assert.Equal(t, a.PrimaryType, rows[i].Activity.PrimaryType)
+		assert.Equal(t, a.MediaType, rows[i].Activity.MediaType)
+		assert.Equal(t, a.Duration, rows[i].Activity.Duration)
+		assert.True(t, a.Date.Equal(rows[i].Activity.Date))
+	}
+}
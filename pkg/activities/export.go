@@ -0,0 +1,282 @@
+package activities
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCompressedJSONL = "jsonl.gz"
+	FormatJSONL           = "jsonl"
+	FormatJSON            = "json"
+	FormatCSV             = "csv"
+	FormatICS             = "ics"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// CSVHeader is the header row written by CSV exports.
+var CSVHeader = []string{
+	"id",
+	"date",
+	"name",
+	"primary_type",
+	"media_type",
+	"duration_minutes",
+	"guild_id",
+	"created_at",
+	"imported_at",
+	"meta",
+}
+
+// Writer encodes activities one at a time. Close must be called to write
+// any trailing data, but does not close the underlying writer.
+type Writer interface {
+	Write(a *Activity) error
+	Close() error
+}
+
+// NewWriter returns a writer for the given format, which is also the file
+// extension of its output.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCompressedJSONL:
+		gz := gzip.NewWriter(w)
+		return &jsonlWriter{encoder: json.NewEncoder(gz), closer: gz}, nil
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatICS:
+		return &icsWriter{w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCompressedJSONL:
+		return "application/gzip"
+	case FormatJSONL:
+		return "application/jsonl"
+	case FormatJSON:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv"
+	case FormatICS:
+		return "text/calendar"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+func (w *jsonlWriter) Write(a *Activity) error {
+	return w.encoder.Encode(a)
+}
+
+func (w *jsonlWriter) Close() error {
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (w *jsonWriter) Write(a *Activity) error {
+	separator := ",\n"
+	if w.count == 0 {
+		separator = "[\n"
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w.w, separator); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+
+	w.count++
+	return nil
+}
+
+func (w *jsonWriter) Close() error {
+	closing := "\n]\n"
+	if w.count == 0 {
+		closing = "[]\n"
+	}
+
+	_, err := io.WriteString(w.w, closing)
+	return err
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatOptionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+
+	w.headerWritten = true
+	return w.w.Write(CSVHeader)
+}
+
+func (w *csvWriter) Write(a *Activity) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(a.Meta)
+	if err != nil {
+		return err
+	}
+
+	return w.w.Write([]string{
+		strconv.FormatUint(a.ID, 10),
+		a.Date.Format(time.RFC3339),
+		a.Name,
+		a.PrimaryType,
+		formatOptionalString(a.MediaType),
+		strconv.FormatFloat(a.Duration.Minutes(), 'f', -1, 64),
+		formatOptionalString(a.GuildID),
+		a.CreatedAt.Format(time.RFC3339),
+		formatOptionalTime(a.ImportedAt),
+		string(meta),
+	})
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// icsWriter writes activities as events of an iCalendar file (RFC 5545),
+// ending at the activity's date.
+type icsWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+const icsTimeFormat = "20060102T150405Z"
+
+var icsEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// writeLine writes a content line, folding it to lines of at most 75 bytes
+// without splitting UTF-8 sequences.
+func (w *icsWriter) writeLine(line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+
+		w.w.WriteString(line[:cut])
+		w.w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = 74
+	}
+
+	w.w.WriteString(line)
+	w.w.WriteString("\r\n")
+}
+
+func isUTF8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func (w *icsWriter) start() {
+	if w.started {
+		return
+	}
+
+	w.started = true
+	w.writeLine("BEGIN:VCALENDAR")
+	w.writeLine("VERSION:2.0")
+	w.writeLine("PRODID:-//botsu//activities//EN")
+	w.writeLine("CALSCALE:GREGORIAN")
+}
+
+func (w *icsWriter) Write(a *Activity) error {
+	w.start()
+
+	end := a.Date.UTC()
+	start := end.Add(-a.Duration)
+
+	categories := a.PrimaryType
+	if a.MediaType != nil {
+		categories += "," + *a.MediaType
+	}
+
+	w.writeLine("BEGIN:VEVENT")
+	w.writeLine(fmt.Sprintf("UID:activity-%d@botsu", a.ID))
+	w.writeLine("DTSTAMP:" + a.CreatedAt.UTC().Format(icsTimeFormat))
+	w.writeLine("DTSTART:" + start.Format(icsTimeFormat))
+	w.writeLine("DTEND:" + end.Format(icsTimeFormat))
+	w.writeLine("SUMMARY:" + icsEscaper.Replace(a.Name))
+	w.writeLine("CATEGORIES:" + categories)
+	w.writeLine("DESCRIPTION:" + icsEscaper.Replace(a.Duration.String()+" of "+a.PrimaryType))
+	w.writeLine("END:VEVENT")
+
+	return nil
+}
+
+func (w *icsWriter) Close() error {
+	w.start()
+	w.writeLine("END:VCALENDAR")
+	return w.w.Flush()
+}
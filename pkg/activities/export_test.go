@@ -0,0 +1,86 @@
+package activities_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/pkg/activities"
+	"github.com/xoltia/botsu/pkg/ref"
+)
+
+func testActivities() []*activities.Activity {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []*activities.Activity{
+		{
+			ID:          1,
+			Name:        "Frieren, episode 1",
+			PrimaryType: "listening",
+			MediaType:   ref.New("anime"),
+			Duration:    24 * time.Minute,
+			Date:        date,
+			CreatedAt:   date,
+			Meta:        map[string]interface{}{"episodes": 1},
+		},
+		{
+			ID:          2,
+			Name:        "Podcast",
+			PrimaryType: "listening",
+			Duration:    90 * time.Second,
+			Date:        date.Add(time.Hour),
+			CreatedAt:   date.Add(time.Hour),
+			Meta:        map[string]interface{}{},
+		},
+	}
+}
+
+func writeAll(t *testing.T, format string, as []*activities.Activity) string {
+	var b bytes.Buffer
+	w, err := activities.NewWriter(format, &b)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, a := range as {
+		assert.NoError(t, w.Write(a))
+	}
+	assert.NoError(t, w.Close())
+
+	return b.String()
+}
+
+func TestJSONWriter(t *testing.T) {
+	var decoded []*activities.Activity
+	assert.NoError(t, json.Unmarshal([]byte(writeAll(t, activities.FormatJSON, testActivities())), &decoded))
+	assert.Len(t, decoded, 2)
+	assert.Equal(t, "Podcast", decoded[1].Name)
+
+	assert.Equal(t, "[]\n", writeAll(t, activities.FormatJSON, nil))
+}
+
+func TestCSVWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(writeAll(t, activities.FormatCSV, testActivities())), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, strings.Join(activities.CSVHeader, ","), lines[0])
+	assert.Equal(t, `1,2024-05-01T12:00:00Z,"Frieren, episode 1",listening,anime,24,,2024-05-01T12:00:00Z,,"{""episodes"":1}"`, lines[1])
+	assert.Equal(t, `2,2024-05-01T13:00:00Z,Podcast,listening,,1.5,,2024-05-01T13:00:00Z,,{}`, lines[2])
+}
+
+func TestICSWriter(t *testing.T) {
+	as := testActivities()
+	as[1].Name = strings.Repeat("長", 40)
+
+	out := writeAll(t, activities.FormatICS, as)
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "DTSTART:20240501T113600Z\r\nDTEND:20240501T120000Z\r\n")
+	assert.Contains(t, out, `SUMMARY:Frieren\, episode 1`)
+	assert.Contains(t, out, "CATEGORIES:listening,anime\r\n")
+
+	for _, line := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+}
@@ -0,0 +1,188 @@
+package chart
+
+import (
+	"math"
+	"strconv"
+)
+
+// Space around the edges of charts.
+const padding = 10
+
+// Widest a category label is drawn, so that long labels don't take up more
+// room than other labels.
+const maxLabelWidth = 120
+
+// Series is a named set of values, one for each label of a chart.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// value returns the ith value of the series, or 0 if it has none. Negative
+// values are drawn as 0.
+func (s *Series) value(i int) float64 {
+	if i >= len(s.Values) || math.IsNaN(s.Values[i]) {
+		return 0
+	}
+	return max(s.Values[i], 0)
+}
+
+// plot is the area of a bar or line chart within its axes, which values are
+// plotted in.
+type plot struct {
+	left, top, right, bottom float64
+	max                      float64
+	n                        int
+}
+
+func (p *plot) y(v float64) float64 {
+	return p.bottom - max(v, 0)/p.max*(p.bottom-p.top)
+}
+
+// slot returns the width given to each label.
+func (p *plot) slot() float64 {
+	return (p.right - p.left) / float64(p.n)
+}
+
+func (p *plot) center(i int) float64 {
+	return p.left + p.slot()*(float64(i)+0.5)
+}
+
+// niceScale returns a round step between the ticks of an axis going up to at
+// least maxValue, and the top of the axis.
+func niceScale(maxValue float64) (step, top float64) {
+	if !(maxValue > 0) || math.IsInf(maxValue, 0) {
+		maxValue = 1
+	}
+
+	rough := maxValue / 5
+	mag := math.Pow(10, math.Floor(math.Log10(rough)))
+
+	switch norm := rough / mag; {
+	case norm <= 1:
+		step = mag
+	case norm <= 2:
+		step = 2 * mag
+	case norm <= 5:
+		step = 5 * mag
+	default:
+		step = 10 * mag
+	}
+
+	return step, math.Ceil(maxValue/step-1e-9) * step
+}
+
+// formatTick formats the value of a tick with as many decimals as the step
+// between ticks needs.
+func formatTick(v, step float64) string {
+	decimals := max(0, int(-math.Floor(math.Log10(step))))
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// drawAxes draws the grid, axis labels and legend of a bar or line chart
+// whose values go up to maxValue. Series are only named by the legend if
+// there are several.
+func (c *canvas) drawAxes(labels []string, series []Series, maxValue float64) *plot {
+	size := c.theme.FontSize
+
+	top := padding + size
+	if len(series) > 1 {
+		c.drawLegend(series)
+		top += size + padding
+	}
+
+	step, axisTop := niceScale(maxValue)
+
+	var ticks []float64
+	for i := 0; float64(i)*step <= axisTop+step/2; i++ {
+		ticks = append(ticks, float64(i)*step)
+	}
+
+	tickLabels := make([]string, len(ticks))
+	labelWidth := 0.0
+	for i, v := range ticks {
+		tickLabels[i] = formatTick(v, step)
+		labelWidth = max(labelWidth, c.measureText(tickLabels[i]))
+	}
+
+	p := &plot{
+		left:   padding + labelWidth + 8,
+		top:    top,
+		right:  c.width() - padding,
+		bottom: c.height() - padding - size - 8,
+		max:    axisTop,
+		n:      max(len(labels), 1),
+	}
+
+	for i, v := range ticks {
+		y := p.y(v)
+
+		col := c.theme.Grid
+		if i == 0 {
+			col = c.theme.Axis
+		}
+
+		c.hline(p.left, p.right, y, 0, col)
+		c.drawText(tickLabels[i], p.left-8, y, 1, c.theme.Axis)
+	}
+
+	c.drawCategoryLabels(p, labels)
+	return p
+}
+
+// drawCategoryLabels draws the labels under the plot. If they don't all fit,
+// only every few labels are drawn.
+func (c *canvas) drawCategoryLabels(p *plot, labels []string) {
+	widest := 0.0
+	for _, l := range labels {
+		widest = max(widest, min(c.measureText(l), maxLabelWidth))
+	}
+
+	every := max(1, int(math.Ceil((widest+8)/p.slot())))
+	width := p.slot()*float64(every) - 8
+	y := p.bottom + 8 + c.theme.FontSize/2
+
+	for i := 0; i < len(labels); i += every {
+		l := c.truncateText(labels[i], width)
+
+		// Labels wider than their slot are kept within the image
+		half := c.measureText(l) / 2
+		x := min(max(p.center(i), half), c.width()-half)
+
+		c.drawText(l, x, y, 0.5, c.theme.Axis)
+	}
+}
+
+// drawLegend draws the names of the series in a row across the top of the
+// chart.
+func (c *canvas) drawLegend(series []Series) {
+	size := c.theme.FontSize
+	itemWidth := func(name string) float64 {
+		return size + 4 + c.measureText(name) + 12
+	}
+
+	names := make([]string, len(series))
+	total := 0.0
+	for i, s := range series {
+		names[i] = s.Name
+		total += itemWidth(s.Name)
+	}
+
+	// Names are shortened to share the width equally if they don't fit
+	if available := c.width() - 2*padding; total > available {
+		total = 0
+		for i := range names {
+			names[i] = c.truncateText(names[i], available/float64(len(names))-size-16)
+			total += itemWidth(names[i])
+		}
+	}
+
+	x := (c.width() - total + 12) / 2
+	y := padding + size/2
+
+	for i, name := range names {
+		c.rect(x, y-size/2, x+size, y+size/2, c.theme.color(i))
+		c.drawText(name, x+size+4, y, 0, c.theme.Text)
+		x += itemWidth(name)
+	}
+}
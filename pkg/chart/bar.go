@@ -0,0 +1,55 @@
+package chart
+
+// BarChart compares values by label, with the bars of each series side by
+// side, or stacked if Stacked is set.
+type BarChart struct {
+	Labels  []string
+	Series  []Series
+	Stacked bool
+	// Reference is the value of a dashed line drawn across the chart, such as
+	// a goal, if non-zero
+	Reference float64
+}
+
+func (b *BarChart) draw(c *canvas) {
+	maxValue := b.Reference
+	for i := range b.Labels {
+		sum := 0.0
+		for j := range b.Series {
+			v := b.Series[j].value(i)
+			if b.Stacked {
+				sum += v
+			} else {
+				maxValue = max(maxValue, v)
+			}
+		}
+		maxValue = max(maxValue, sum)
+	}
+
+	p := c.drawAxes(b.Labels, b.Series, maxValue)
+
+	// Bars leave a gap between labels, and between the bars of each label
+	group := p.slot() * 0.8
+
+	for i := range b.Labels {
+		base := 0.0
+		for j := range b.Series {
+			v := b.Series[j].value(i)
+
+			if b.Stacked {
+				x := p.center(i) - group*0.45
+				c.rect(x, p.y(base+v), x+group*0.9, p.y(base), c.theme.color(j))
+				base += v
+				continue
+			}
+
+			width := group / float64(len(b.Series))
+			x := p.center(i) - group/2 + width*float64(j) + width*0.05
+			c.rect(x, p.y(v), x+width*0.9, p.y(0), c.theme.color(j))
+		}
+	}
+
+	if b.Reference != 0 {
+		c.hline(p.left, p.right, p.y(b.Reference), 5, c.theme.Reference)
+	}
+}
@@ -0,0 +1,185 @@
+// Package chart draws bar, line, pie and heatmap charts as PNG images.
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/xoltia/botsu/pkg/discordutil"
+	"golang.org/x/image/vector"
+)
+
+// Theme is the colors and text size charts are drawn with.
+type Theme struct {
+	Background color.Color
+	Grid       color.Color
+	// Axis colors axis labels and the zero line
+	Axis color.Color
+	// Text colors legends and other labels
+	Text color.Color
+	// Reference colors reference lines, such as goals
+	Reference color.Color
+	// Palette colors the series of charts, or the slices of pie charts, in
+	// order
+	Palette  []color.Color
+	FontSize float64
+}
+
+// DiscordTheme blends in with Discord's dark theme, using the bot's colors.
+var DiscordTheme = &Theme{
+	Background: color.RGBA{R: 0x23, G: 0x24, B: 0x28, A: 0xFF},
+	Grid:       color.RGBA{R: 0x2B, G: 0x2D, B: 0x31, A: 0xFF},
+	Axis:       color.RGBA{R: 0x9E, G: 0x9E, B: 0x9E, A: 0xFF},
+	Text:       color.RGBA{R: 0xCC, G: 0xCC, B: 0xCC, A: 0xFF},
+	Reference:  discordutil.ColorPrimary,
+	Palette: []color.Color{
+		discordutil.ColorSecondary,
+		discordutil.ColorPrimary,
+		discordutil.ColorInfo,
+		discordutil.ColorSuccess,
+		discordutil.ColorWarning,
+		discordutil.ColorDanger,
+	},
+	FontSize: 12,
+}
+
+// color returns the ith color of the palette. Once the palette runs out its
+// colors are repeated, faded towards the background.
+func (t *Theme) color(i int) color.Color {
+	c := t.Palette[i%len(t.Palette)]
+	if n := i / len(t.Palette); n > 0 {
+		return mix(c, t.Background, min(0.3*float64(n), 0.9))
+	}
+	return c
+}
+
+// mix blends a towards b by f, from 0 to 1.
+func mix(a, b color.Color, f float64) color.Color {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	blend := func(x, y uint32) uint8 {
+		return uint8(math.Round((float64(x)*(1-f) + float64(y)*f) / 0x101))
+	}
+	return color.RGBA{R: blend(ar, br), G: blend(ag, bg), B: blend(ab, bb), A: 0xFF}
+}
+
+// Chart is one of the charts of this package.
+type Chart interface {
+	draw(c *canvas)
+}
+
+// Render draws a chart of the given size.
+func Render(ch Chart, t *Theme, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(t.Background), image.Point{}, draw.Src)
+
+	ch.draw(newCanvas(img, t))
+	return img
+}
+
+// WritePNG draws a chart of the given size to w as a PNG.
+func WritePNG(w io.Writer, ch Chart, t *Theme, width, height int) error {
+	return png.Encode(w, Render(ch, t, width, height))
+}
+
+type point struct {
+	x, y float64
+}
+
+// canvas draws antialiased shapes and text onto an image.
+type canvas struct {
+	img   *image.RGBA
+	theme *Theme
+	z     *vector.Rasterizer
+	text  *textDrawer
+}
+
+func newCanvas(img *image.RGBA, t *Theme) *canvas {
+	return &canvas{
+		img:   img,
+		theme: t,
+		z:     vector.NewRasterizer(img.Bounds().Dx(), img.Bounds().Dy()),
+		text:  newTextDrawer(),
+	}
+}
+
+func (c *canvas) width() float64 {
+	return float64(c.img.Bounds().Dx())
+}
+
+func (c *canvas) height() float64 {
+	return float64(c.img.Bounds().Dy())
+}
+
+// fill fills the polygon with the given points.
+func (c *canvas) fill(col color.Color, pts ...point) {
+	if len(pts) < 3 {
+		return
+	}
+
+	c.z.Reset(c.img.Bounds().Dx(), c.img.Bounds().Dy())
+	c.z.MoveTo(float32(pts[0].x), float32(pts[0].y))
+	for _, p := range pts[1:] {
+		c.z.LineTo(float32(p.x), float32(p.y))
+	}
+	c.z.ClosePath()
+	c.z.Draw(c.img, c.img.Bounds(), image.NewUniform(col), image.Point{})
+}
+
+func (c *canvas) rect(x0, y0, x1, y1 float64, col color.Color) {
+	c.fill(col, point{x0, y0}, point{x1, y0}, point{x1, y1}, point{x0, y1})
+}
+
+// line draws a line of the given width between two points.
+func (c *canvas) line(p0, p1 point, width float64, col color.Color) {
+	dx, dy := p1.x-p0.x, p1.y-p0.y
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return
+	}
+
+	nx, ny := -dy/l*width/2, dx/l*width/2
+	c.fill(col,
+		point{p0.x + nx, p0.y + ny},
+		point{p1.x + nx, p1.y + ny},
+		point{p1.x - nx, p1.y - ny},
+		point{p0.x - nx, p0.y - ny},
+	)
+}
+
+// hline draws a horizontal line one pixel thick, aligned to the pixel grid
+// so that it stays sharp. Lines are dashed if dash is non-zero.
+func (c *canvas) hline(x0, x1, y, dash float64, col color.Color) {
+	y = math.Floor(y)
+	if dash == 0 {
+		c.rect(x0, y, x1, y+1, col)
+		return
+	}
+
+	for x := x0; x < x1; x += 2 * dash {
+		c.rect(x, y, min(x+dash, x1), y+1, col)
+	}
+}
+
+func (c *canvas) circle(center point, r float64, col color.Color) {
+	c.fill(col, arc(center, r, 0, 2*math.Pi)...)
+}
+
+// arc returns points along an arc clockwise from angle a0 to a1, in radians
+// clockwise from the right.
+func arc(center point, r, a0, a1 float64) []point {
+	// Enough segments for the arc to look round at the sizes drawn
+	n := max(2, int(math.Ceil((a1-a0)/(2*math.Pi)*96)))
+
+	pts := make([]point, 0, n+1)
+	for i := 0; i <= n; i++ {
+		a := a0 + (a1-a0)*float64(i)/float64(n)
+		pts = append(pts, point{center.x + r*math.Cos(a), center.y + r*math.Sin(a)})
+	}
+
+	return pts
+}
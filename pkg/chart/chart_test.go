@@ -0,0 +1,171 @@
+package chart_test
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xoltia/botsu/pkg/chart"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata")
+
+// Largest difference in a color channel between a chart and its golden image
+// allowed, for floating point differences between platforms.
+const goldenTolerance = 8
+
+var days = []string{"2024-05-01", "2024-05-02", "2024-05-03", "2024-05-04", "2024-05-05", "2024-05-06", "2024-05-07"}
+
+func TestGolden(t *testing.T) {
+	heatmapValues := make([]float64, 120)
+	for i := range heatmapValues {
+		heatmapValues[i] = float64((i * 37) % 90)
+	}
+
+	cases := []struct {
+		name          string
+		chart         chart.Chart
+		width, height int
+	}{
+		{
+			name: "bar",
+			chart: &chart.BarChart{
+				Labels:    days,
+				Series:    []chart.Series{{Values: []float64{45, 80, 0, 120, 62, 30, 95}}},
+				Reference: 60,
+			},
+			width:  500,
+			height: 300,
+		},
+		{
+			name: "bar_long_labels",
+			chart: &chart.BarChart{
+				Labels: []string{"A channel with a very long name", "Short", "Another channel name", "Other"},
+				Series: []chart.Series{{Values: []float64{120, 45, 90, 15}}},
+			},
+			width:  500,
+			height: 300,
+		},
+		{
+			name: "stacked_bar",
+			chart: &chart.BarChart{
+				Labels: days,
+				Series: []chart.Series{
+					{Name: "Anime", Values: []float64{20, 40, 0, 60, 30, 10, 45}},
+					{Name: "Reading", Values: []float64{15, 30, 10, 40, 22, 20, 30}},
+					{Name: "Video", Values: []float64{10, 10, 5, 20, 10, 0, 20}},
+				},
+				Stacked:   true,
+				Reference: 90,
+			},
+			width:  500,
+			height: 300,
+		},
+		{
+			name: "line",
+			chart: &chart.LineChart{
+				Labels: days,
+				Series: []chart.Series{
+					{Name: "Listening", Values: []float64{1.5, 2.25, 0.5, 3, 2, 1, 2.5}},
+					{Name: "Reading", Values: []float64{0.5, 1, 1.25, 0.75, 2, 2.5, 1.5}},
+				},
+			},
+			width:  500,
+			height: 300,
+		},
+		{
+			name: "pie",
+			chart: &chart.PieChart{
+				Labels: []string{"Channel A", "Channel B", "Channel C", "Channel D", "Channel E", "Channel F", "Channel G", "Other"},
+				Values: []float64{120, 90, 60, 45, 30, 20, 10, 25},
+			},
+			width:  600,
+			height: 500,
+		},
+		{
+			name: "pie_empty",
+			chart: &chart.PieChart{
+				Labels: []string{"Channel A"},
+				Values: []float64{0},
+			},
+			width:  600,
+			height: 500,
+		},
+		{
+			name: "heatmap",
+			chart: &chart.Heatmap{
+				Start:  time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC),
+				Values: heatmapValues,
+			},
+			width:  500,
+			height: 150,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			img := chart.Render(c.chart, chart.DiscordTheme, c.width, c.height)
+			path := filepath.Join("testdata", c.name+".png")
+
+			if *update {
+				var buf bytes.Buffer
+				require.NoError(t, png.Encode(&buf, img))
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+				return
+			}
+
+			f, err := os.Open(path)
+			require.NoError(t, err)
+			defer f.Close()
+
+			golden, err := png.Decode(f)
+			require.NoError(t, err)
+			require.Equal(t, golden.Bounds(), img.Bounds())
+
+			assert.Zero(t, differentPixels(golden, img), "pixels differ from %s", path)
+		})
+	}
+}
+
+func differentPixels(a, b image.Image) (n int) {
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ar, ag, ab, aa := a.At(x, y).RGBA()
+			br, bg, bb, ba := b.At(x, y).RGBA()
+
+			for _, d := range [...][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+				if max(d[0], d[1])-min(d[0], d[1]) > goldenTolerance*0x101 {
+					n++
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+func TestWritePNG(t *testing.T) {
+	var buf bytes.Buffer
+	err := chart.WritePNG(&buf, &chart.BarChart{Labels: days[:1]}, chart.DiscordTheme, 200, 100)
+	require.NoError(t, err)
+
+	config, err := png.DecodeConfig(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 200, config.Width)
+	assert.Equal(t, 100, config.Height)
+}
+
+func TestCanDraw(t *testing.T) {
+	assert.True(t, chart.CanDraw("Frieren 2024 – 100%"))
+
+	// The Go font has no Japanese characters, and no fallbacks are loaded
+	assert.False(t, chart.CanDraw("葬送のフリーレン"))
+}
@@ -0,0 +1,79 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+	"time"
+)
+
+// Heatmap is a calendar of daily values, with a column for each week and a
+// row for each day of the week from Sunday. Days are shaded by how their
+// value compares to the highest value.
+type Heatmap struct {
+	// Start is the date of the first value
+	Start  time.Time
+	Values []float64
+}
+
+// Number of shades of days with values.
+const heatmapLevels = 4
+
+// shade returns the color of a day with the value v.
+func (h *Heatmap) shade(t *Theme, v, maxValue float64) color.Color {
+	if !(v > 0) {
+		return t.Grid
+	}
+
+	level := math.Ceil(v / maxValue * heatmapLevels)
+	return mix(t.Palette[0], t.Grid, 1-level/heatmapLevels)
+}
+
+func (h *Heatmap) draw(c *canvas) {
+	size := c.theme.FontSize
+	start := time.Date(h.Start.Year(), h.Start.Month(), h.Start.Day(), 0, 0, 0, 0, h.Start.Location())
+	offset := int(start.Weekday())
+	weeks := max(1, (offset+len(h.Values)+6)/7)
+
+	left := padding + c.measureText("Wed") + 6
+	top := padding + size + 6
+	cell := min((c.width()-left-padding)/float64(weeks), (c.height()-top-padding)/7)
+	gap := max(1, cell*0.15)
+
+	// The calendar is centered in the space beside the weekday labels
+	left += (c.width() - left - padding - cell*float64(weeks)) / 2
+
+	maxValue := 0.0
+	for _, v := range h.Values {
+		maxValue = max(maxValue, v)
+	}
+
+	for i, v := range h.Values {
+		day := offset + i
+		x := left + float64(day/7)*cell
+		y := top + float64(day%7)*cell
+		c.rect(x, y, x+cell-gap, y+cell-gap, h.shade(c.theme, v, maxValue))
+	}
+
+	for _, d := range []time.Weekday{time.Monday, time.Wednesday, time.Friday} {
+		y := top + float64(d)*cell + (cell-gap)/2
+		c.drawText(d.String()[:3], left-6, y, 1, c.theme.Axis)
+	}
+
+	// Months are labelled above the week of their first day
+	labelEnd := math.Inf(-1)
+	for i := range h.Values {
+		date := start.AddDate(0, 0, i)
+		if i > 0 && date.Day() != 1 {
+			continue
+		}
+
+		x := left + float64((offset+i)/7)*cell
+		label := date.Format("Jan")
+		if x < labelEnd+4 {
+			continue
+		}
+
+		c.drawText(label, x, padding+size/2, 0, c.theme.Axis)
+		labelEnd = x + c.measureText(label)
+	}
+}
@@ -0,0 +1,40 @@
+package chart
+
+// LineChart shows how values change over its labels, with a line for each
+// series.
+type LineChart struct {
+	Labels []string
+	Series []Series
+	// Reference is the value of a dashed line drawn across the chart, such as
+	// a goal, if non-zero
+	Reference float64
+}
+
+func (l *LineChart) draw(c *canvas) {
+	maxValue := l.Reference
+	for i := range l.Labels {
+		for j := range l.Series {
+			maxValue = max(maxValue, l.Series[j].value(i))
+		}
+	}
+
+	p := c.drawAxes(l.Labels, l.Series, maxValue)
+
+	if l.Reference != 0 {
+		c.hline(p.left, p.right, p.y(l.Reference), 5, c.theme.Reference)
+	}
+
+	for j := range l.Series {
+		col := c.theme.color(j)
+
+		var prev point
+		for i := range l.Labels {
+			pt := point{p.center(i), p.y(l.Series[j].value(i))}
+			if i > 0 {
+				c.line(prev, pt, 2, col)
+			}
+			c.circle(pt, 3, col)
+			prev = pt
+		}
+	}
+}
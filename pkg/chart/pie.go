@@ -0,0 +1,104 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+)
+
+// PieChart shows how a total is divided between its labels, with a legend
+// beside it.
+type PieChart struct {
+	Labels []string
+	Values []float64
+}
+
+func (pc *PieChart) value(i int) float64 {
+	if i >= len(pc.Values) || math.IsNaN(pc.Values[i]) {
+		return 0
+	}
+	return max(pc.Values[i], 0)
+}
+
+func (pc *PieChart) draw(c *canvas) {
+	size := c.theme.FontSize
+
+	total := 0.0
+	for i := range pc.Labels {
+		total += pc.value(i)
+	}
+
+	legendWidth := 0.0
+	for _, l := range pc.Labels {
+		legendWidth = max(legendWidth, size+6+c.measureText(l))
+	}
+	legendWidth = min(legendWidth, c.width()*0.4)
+
+	r := max(min((c.width()-legendWidth-6*padding)/2, c.height()/2-2*padding), 1)
+	center := point{2*padding + r, c.height() / 2}
+
+	if total <= 0 {
+		c.circle(center, r, c.theme.Grid)
+	} else {
+		pc.drawSlices(c, center, r, total)
+	}
+
+	// The legend is centered vertically beside the pie
+	x := center.x + r + 2*padding
+	lineHeight := min(size*1.8, (c.height()-2*padding)/float64(max(len(pc.Labels), 1)))
+	y := c.height()/2 - lineHeight*float64(len(pc.Labels)-1)/2
+
+	for i, l := range pc.Labels {
+		c.rect(x, y-size/2, x+size, y+size/2, c.theme.color(i))
+		c.drawText(c.truncateText(l, c.width()-x-size-6-padding), x+size+6, y, 0, c.theme.Text)
+		y += lineHeight
+	}
+}
+
+// drawSlices draws the slices clockwise from the top, labelled with their
+// percentage of the total if there is room.
+func (pc *PieChart) drawSlices(c *canvas, center point, r, total float64) {
+	start := -math.Pi / 2
+	angles := make([]float64, len(pc.Labels)+1)
+	angles[0] = start
+	for i := range pc.Labels {
+		angles[i+1] = angles[i] + pc.value(i)/total*2*math.Pi
+	}
+
+	slices := 0
+	for i := range pc.Labels {
+		if pc.value(i) == 0 {
+			continue
+		}
+
+		pts := append([]point{center}, arc(center, r, angles[i], angles[i+1])...)
+		c.fill(c.theme.color(i), pts...)
+		slices++
+	}
+
+	// Slices are separated by lines of the background color
+	if slices > 1 {
+		for i := range pc.Labels {
+			if pc.value(i) == 0 {
+				continue
+			}
+
+			edge := point{center.x + r*math.Cos(angles[i]), center.y + r*math.Sin(angles[i])}
+			c.line(center, edge, 2, c.theme.Background)
+		}
+	}
+
+	for i := range pc.Labels {
+		share := pc.value(i) / total
+		if share < 0.05 {
+			continue
+		}
+
+		mid := (angles[i] + angles[i+1]) / 2
+		if slices == 1 {
+			mid = 0
+		}
+
+		label := fmt.Sprintf("%.0f%%", share*100)
+		c.drawText(label, center.x+r*0.65*math.Cos(mid), center.y+r*0.65*math.Sin(mid), 0.5, c.theme.Background)
+	}
+}
@@ -0,0 +1,169 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+var goFont = func() *opentype.Font {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}()
+
+// Fonts used for characters missing from the Go font, in order.
+var fallbackFonts []*opentype.Font
+
+// LoadFallbackFont loads a font file to draw characters missing from the Go
+// font, such as Japanese text. The first font of collections is used. Meant
+// to be called at startup, before any charts are drawn.
+func LoadFallbackFont(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	collection, err := opentype.ParseCollection(b)
+	if err != nil {
+		return err
+	}
+
+	f, err := collection.Font(0)
+	if err != nil {
+		return err
+	}
+
+	fallbackFonts = append(fallbackFonts, f)
+	return nil
+}
+
+// CanDraw reports whether the Go font or a loaded fallback font has a glyph
+// for every character of s.
+func CanDraw(s string) bool {
+	var buf sfnt.Buffer
+	fonts := append([]*opentype.Font{goFont}, fallbackFonts...)
+
+	for _, r := range s {
+		found := false
+		for _, f := range fonts {
+			if x, err := f.GlyphIndex(&buf, r); err == nil && x != 0 {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// textDrawer measures and draws text, choosing the font of each character.
+// Faces aren't safe for concurrent use, so each canvas has its own.
+type textDrawer struct {
+	fonts []*opentype.Font
+	faces map[float64][]font.Face
+	buf   sfnt.Buffer
+}
+
+func newTextDrawer() *textDrawer {
+	return &textDrawer{
+		fonts: append([]*opentype.Font{goFont}, fallbackFonts...),
+		faces: make(map[float64][]font.Face),
+	}
+}
+
+// face returns the face of the first font with a glyph for r, or of the Go
+// font if none have one.
+func (d *textDrawer) face(r rune, size float64) font.Face {
+	faces, ok := d.faces[size]
+	if !ok {
+		faces = make([]font.Face, len(d.fonts))
+		for i, f := range d.fonts {
+			faces[i], _ = opentype.NewFace(f, &opentype.FaceOptions{
+				Size:    size,
+				DPI:     72,
+				Hinting: font.HintingNone,
+			})
+		}
+		d.faces[size] = faces
+	}
+
+	for i, f := range d.fonts {
+		if x, err := f.GlyphIndex(&d.buf, r); err == nil && x != 0 && faces[i] != nil {
+			return faces[i]
+		}
+	}
+
+	return faces[0]
+}
+
+func (d *textDrawer) measure(s string, size float64) float64 {
+	var width fixed.Int26_6
+	for _, r := range s {
+		advance, _ := d.face(r, size).GlyphAdvance(r)
+		width += advance
+	}
+	return float64(width) / 64
+}
+
+// truncate shortens s with an ellipsis to fit within width.
+func (d *textDrawer) truncate(s string, size, width float64) string {
+	if d.measure(s, size) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		if t := string(runes[:n]) + "…"; d.measure(t, size) <= width {
+			return t
+		}
+	}
+
+	return ""
+}
+
+// draw draws s with its baseline at y. The text is aligned to x by align,
+// 0 for the left edge, 0.5 for the center and 1 for the right edge.
+func (d *textDrawer) draw(dst *image.RGBA, s string, x, y, align, size float64, col color.Color) {
+	x -= d.measure(s, size) * align
+
+	drawer := font.Drawer{
+		Dst: dst,
+		Src: image.NewUniform(col),
+		Dot: fixed.Point26_6{
+			X: fixed.Int26_6(math.Round(x * 64)),
+			Y: fixed.Int26_6(math.Round(y * 64)),
+		},
+	}
+
+	for _, r := range s {
+		drawer.Face = d.face(r, size)
+		drawer.DrawString(string(r))
+	}
+}
+
+// drawText draws text in the theme's font size, vertically centered on y.
+func (c *canvas) drawText(s string, x, y, align float64, col color.Color) {
+	c.text.draw(c.img, s, x, y+c.theme.FontSize*0.35, align, c.theme.FontSize, col)
+}
+
+func (c *canvas) measureText(s string) float64 {
+	return c.text.measure(s, c.theme.FontSize)
+}
+
+func (c *canvas) truncateText(s string, width float64) string {
+	return c.text.truncate(s, c.theme.FontSize, width)
+}
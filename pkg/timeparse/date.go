@@ -0,0 +1,412 @@
+// Package timeparse parses the loosely formatted dates and durations users
+// type into command options.
+package timeparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+var (
+	isoDatePattern      = regexp.MustCompile(`^(\d{4})[-/](\d{1,2})[-/](\d{1,2})$`)
+	monthDayPattern     = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})$`)
+	japaneseDatePattern = regexp.MustCompile(`^(?:(\d{4})年)?(\d{1,2})月(\d{1,2})日$`)
+
+	clockPattern         = regexp.MustCompile(`(?:^|\s)(\d{1,2}):(\d{2})(?::(\d{2}))?\s*(am|pm)?$`)
+	meridiemClockPattern = regexp.MustCompile(`(?:^|\s)(\d{1,2})\s*(am|pm)$`)
+	japaneseClockPattern = regexp.MustCompile(`(午前|午後)?(\d{1,2})時(?:(\d{1,2})分|半)?$`)
+
+	relativePattern         = regexp.MustCompile(`^-\s*(.+)$`)
+	agoPattern              = regexp.MustCompile(`^(.+?)\s*ago$`)
+	japaneseRelativePattern = regexp.MustCompile(`^(\d+)\s*(秒|分|時間|日|週間)前$`)
+	relativeUnitPattern     = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"sun":       time.Sunday,
+	"日曜日":       time.Sunday,
+	"日曜":        time.Sunday,
+	"monday":    time.Monday,
+	"mon":       time.Monday,
+	"月曜日":       time.Monday,
+	"月曜":        time.Monday,
+	"tuesday":   time.Tuesday,
+	"tue":       time.Tuesday,
+	"tues":      time.Tuesday,
+	"火曜日":       time.Tuesday,
+	"火曜":        time.Tuesday,
+	"wednesday": time.Wednesday,
+	"wed":       time.Wednesday,
+	"水曜日":       time.Wednesday,
+	"水曜":        time.Wednesday,
+	"thursday":  time.Thursday,
+	"thu":       time.Thursday,
+	"thurs":     time.Thursday,
+	"木曜日":       time.Thursday,
+	"木曜":        time.Thursday,
+	"friday":    time.Friday,
+	"fri":       time.Friday,
+	"金曜日":       time.Friday,
+	"金曜":        time.Friday,
+	"saturday":  time.Saturday,
+	"sat":       time.Saturday,
+	"土曜日":       time.Saturday,
+	"土曜":        time.Saturday,
+}
+
+// Days relative to today, keeping the current time of day when no time is given.
+var relativeDays = map[string]int{
+	"now":       0,
+	"today":     0,
+	"今日":        0,
+	"本日":        0,
+	"yesterday": -1,
+	"昨日":        -1,
+	"一昨日":       -2,
+	"おととい":      -2,
+}
+
+// ParseDate parses a date relative to now, resolving it in the location of
+// now. Accepted inputs include:
+//
+//	2024-05-01, 2024/05/01 21:30, 2024-05-01T21:30:00Z  absolute dates
+//	5/1, 5/1 9pm, 5月1日, 2024年5月1日 21時            month and day (the most recent one)
+//	today 21:00, yesterday, 昨日, 一昨日                 relative days
+//	friday, last friday 8:30pm, 金曜日, 先週の金曜日      the most recent weekday
+//	-2h, -1d12h, 3 days ago, 2時間前                     offsets into the past
+//	21:00, 21時半                                       a time today
+//
+// Relative days keep the current time of day unless a time is given, all
+// other dates without a time resolve to midnight.
+func ParseDate(input string, now time.Time) (time.Time, error) {
+	t, _, err := parseDate(input, now)
+	return t, err
+}
+
+// ParseDateEnd is like ParseDate, but dates given without a time resolve to
+// the end of that day, which is useful for the end of inclusive ranges.
+func ParseDateEnd(input string, now time.Time) (time.Time, error) {
+	t, hasClock, err := parseDate(input, now)
+	if err != nil || hasClock {
+		return t, err
+	}
+
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 23, 59, 59, int(time.Second-time.Nanosecond), t.Location()), nil
+}
+
+func parseDate(input string, now time.Time) (t time.Time, hasClock bool, err error) {
+	if t, err = time.Parse(time.RFC3339, strings.TrimSpace(input)); err == nil {
+		return t.In(now.Location()), true, nil
+	}
+
+	s := normalize(input)
+	invalid := fmt.Errorf("%w: %s", ErrInvalidDate, strings.TrimSpace(input))
+
+	if s == "" {
+		return time.Time{}, false, invalid
+	}
+
+	if offset, ok := parseRelative(s, now); ok {
+		return offset, true, nil
+	}
+
+	datePart, clock, hasClock, ok := splitClock(s)
+	if !ok {
+		return time.Time{}, false, invalid
+	}
+
+	loc := now.Location()
+	year, month, day := now.Date()
+
+	if days, ok := relativeDays[datePart]; ok {
+		if datePart == "now" && hasClock {
+			return time.Time{}, false, invalid
+		}
+
+		t = now.AddDate(0, 0, days)
+		if !hasClock {
+			return t, true, nil
+		}
+		year, month, day = t.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, loc).Add(clock), true, nil
+	}
+
+	switch {
+	case datePart == "":
+		// Only a time of day, e.g. "21:00"
+		if !hasClock {
+			return time.Time{}, false, invalid
+		}
+		t = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case isWeekday(datePart):
+		t, _ = parseWeekday(datePart, now)
+	case isoDatePattern.MatchString(datePart):
+		m := isoDatePattern.FindStringSubmatch(datePart)
+		t, ok = makeDate(atoi(m[1]), atoi(m[2]), atoi(m[3]), loc)
+	case monthDayPattern.MatchString(datePart):
+		m := monthDayPattern.FindStringSubmatch(datePart)
+		t, ok = mostRecentMonthDay(atoi(m[1]), atoi(m[2]), now)
+	case japaneseDatePattern.MatchString(datePart):
+		m := japaneseDatePattern.FindStringSubmatch(datePart)
+		if m[1] != "" {
+			t, ok = makeDate(atoi(m[1]), atoi(m[2]), atoi(m[3]), loc)
+		} else {
+			t, ok = mostRecentMonthDay(atoi(m[2]), atoi(m[3]), now)
+		}
+	default:
+		ok = false
+	}
+
+	if !ok {
+		return time.Time{}, false, invalid
+	}
+
+	return t.Add(clock), hasClock, nil
+}
+
+// normalize lowercases the input, converts full-width characters commonly
+// typed with a Japanese IME to their ASCII equivalents, and collapses spaces.
+func normalize(input string) string {
+	s := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９':
+			return '0' + (r - '０')
+		case r >= 'Ａ' && r <= 'Ｚ':
+			return 'a' + (r - 'Ａ')
+		case r >= 'ａ' && r <= 'ｚ':
+			return 'a' + (r - 'ａ')
+		case r == '：':
+			return ':'
+		case r == '／':
+			return '/'
+		case r == '－':
+			return '-'
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, strings.ToLower(input))
+
+	s = strings.Join(strings.Fields(s), " ")
+
+	// ISO 8601 date and time separator
+	if len(s) > 10 && s[10] == 't' && isoDatePattern.MatchString(s[:10]) {
+		s = s[:10] + " " + s[11:]
+	}
+
+	return s
+}
+
+// splitClock removes a trailing time of day from the input, returning the
+// remaining date and the time as an offset from midnight.
+func splitClock(s string) (datePart string, clock time.Duration, hasClock bool, ok bool) {
+	if m := clockPattern.FindStringSubmatchIndex(s); m != nil {
+		hour := atoi(s[m[2]:m[3]])
+		minute := atoi(s[m[4]:m[5]])
+		second := 0
+		if m[6] != -1 {
+			second = atoi(s[m[6]:m[7]])
+		}
+		meridiem := ""
+		if m[8] != -1 {
+			meridiem = s[m[8]:m[9]]
+		}
+
+		clock, ok = makeClock(hour, minute, second, meridiem)
+		return strings.TrimSpace(s[:m[0]]), clock, true, ok
+	}
+
+	if m := meridiemClockPattern.FindStringSubmatchIndex(s); m != nil {
+		clock, ok = makeClock(atoi(s[m[2]:m[3]]), 0, 0, s[m[4]:m[5]])
+		return strings.TrimSpace(s[:m[0]]), clock, true, ok
+	}
+
+	if m := japaneseClockPattern.FindStringSubmatchIndex(s); m != nil {
+		meridiem := ""
+		if m[2] != -1 {
+			meridiem = "am"
+			if s[m[2]:m[3]] == "午後" {
+				meridiem = "pm"
+			}
+		}
+
+		minute := 0
+		if m[6] != -1 {
+			minute = atoi(s[m[6]:m[7]])
+		} else if strings.HasSuffix(s, "半") {
+			minute = 30
+		}
+
+		clock, ok = makeClock(atoi(s[m[4]:m[5]]), minute, 0, meridiem)
+		return strings.TrimSpace(s[:m[0]]), clock, true, ok
+	}
+
+	return s, 0, false, true
+}
+
+func makeClock(hour, minute, second int, meridiem string) (time.Duration, bool) {
+	switch meridiem {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+
+	if hour > 23 || minute > 59 || second > 59 {
+		return 0, false
+	}
+
+	return time.Duration(hour)*time.Hour +
+		time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second, true
+}
+
+func makeDate(year, month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+
+	// time.Date normalizes out of range values, e.g. 2024-02-31
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// mostRecentMonthDay returns the given month and day in the current year,
+// or the previous year if it has not happened yet.
+func mostRecentMonthDay(month, day int, now time.Time) (time.Time, bool) {
+	t, ok := makeDate(now.Year(), month, day, now.Location())
+	if !ok {
+		// e.g. 2/29 outside of leap years
+		return makeDate(now.Year()-1, month, day, now.Location())
+	}
+
+	if t.After(now) {
+		return makeDate(now.Year()-1, month, day, now.Location())
+	}
+
+	return t, true
+}
+
+func isWeekday(s string) bool {
+	_, ok := parseWeekday(s, time.Time{})
+	return ok
+}
+
+// parseWeekday parses "friday", "last friday", "金曜日" or "先週の金曜日" as the most
+// recent such day. "last" always refers to a day before today.
+func parseWeekday(s string, now time.Time) (time.Time, bool) {
+	last := false
+	for _, prefix := range []string{"last ", "先週の", "先週"} {
+		if strings.HasPrefix(s, prefix) {
+			s = strings.TrimPrefix(s, prefix)
+			last = true
+			break
+		}
+	}
+
+	weekday, ok := weekdays[s]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	daysAgo := (int(now.Weekday()) - int(weekday) + 7) % 7
+	if daysAgo == 0 && last {
+		daysAgo = 7
+	}
+
+	year, month, day := now.AddDate(0, 0, -daysAgo).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location()), true
+}
+
+// parseRelative parses offsets into the past such as "-2h", "-1d12h",
+// "3 days ago" and "2時間前".
+func parseRelative(s string, now time.Time) (time.Time, bool) {
+	if m := japaneseRelativePattern.FindStringSubmatch(s); m != nil {
+		n := atoi(m[1])
+		switch m[2] {
+		case "秒":
+			return now.Add(-time.Duration(n) * time.Second), true
+		case "分":
+			return now.Add(-time.Duration(n) * time.Minute), true
+		case "時間":
+			return now.Add(-time.Duration(n) * time.Hour), true
+		case "日":
+			return now.AddDate(0, 0, -n), true
+		case "週間":
+			return now.AddDate(0, 0, -7*n), true
+		}
+	}
+
+	var offset string
+	if m := relativePattern.FindStringSubmatch(s); m != nil {
+		offset = m[1]
+	} else if m := agoPattern.FindStringSubmatch(s); m != nil {
+		offset = m[1]
+	} else {
+		return time.Time{}, false
+	}
+
+	matches := relativeUnitPattern.FindAllStringSubmatchIndex(offset, -1)
+	if len(matches) == 0 {
+		return time.Time{}, false
+	}
+
+	t := now
+	consumed := 0
+	for _, m := range matches {
+		if strings.TrimSpace(offset[consumed:m[0]]) != "" {
+			return time.Time{}, false
+		}
+		consumed = m[1]
+
+		n, err := strconv.ParseFloat(offset[m[2]:m[3]], 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		switch offset[m[4]:m[5]] {
+		case "s", "sec", "secs", "second", "seconds":
+			t = t.Add(-time.Duration(n * float64(time.Second)))
+		case "m", "min", "mins", "minute", "minutes":
+			t = t.Add(-time.Duration(n * float64(time.Minute)))
+		case "h", "hr", "hrs", "hour", "hours":
+			t = t.Add(-time.Duration(n * float64(time.Hour)))
+		case "d", "day", "days":
+			if n != float64(int(n)) {
+				return time.Time{}, false
+			}
+			t = t.AddDate(0, 0, -int(n))
+		case "w", "week", "weeks":
+			if n != float64(int(n)) {
+				return time.Time{}, false
+			}
+			t = t.AddDate(0, 0, -7*int(n))
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	if strings.TrimSpace(offset[consumed:]) != "" {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,104 @@
+package timeparse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+func TestParseDate(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	// Monday
+	now := time.Date(2024, 5, 6, 21, 30, 15, 0, tokyo)
+	date := func(year int, month time.Month, day, hour, minute, second int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, tokyo)
+	}
+
+	cases := map[string]time.Time{
+		"now":                  now,
+		"today":                now,
+		"yesterday":            now.AddDate(0, 0, -1),
+		"Yesterday 21:00":      date(2024, 5, 5, 21, 0, 0),
+		"today 9pm":            date(2024, 5, 6, 21, 0, 0),
+		"today 12am":           date(2024, 5, 6, 0, 0, 0),
+		"8:15":                 date(2024, 5, 6, 8, 15, 0),
+		"-2h":                  now.Add(-2 * time.Hour),
+		"-1h30m":               now.Add(-90 * time.Minute),
+		"-2d":                  now.AddDate(0, 0, -2),
+		"3 days ago":           now.AddDate(0, 0, -3),
+		"45 minutes ago":       now.Add(-45 * time.Minute),
+		"friday":               date(2024, 5, 3, 0, 0, 0),
+		"last friday":          date(2024, 5, 3, 0, 0, 0),
+		"monday":               date(2024, 5, 6, 0, 0, 0),
+		"last monday":          date(2024, 4, 29, 0, 0, 0),
+		"last fri 8:30pm":      date(2024, 5, 3, 20, 30, 0),
+		"5/1":                  date(2024, 5, 1, 0, 0, 0),
+		"5/1 21:30":            date(2024, 5, 1, 21, 30, 0),
+		"12/31":                date(2023, 12, 31, 0, 0, 0),
+		"2024-05-01":           date(2024, 5, 1, 0, 0, 0),
+		"2024/5/1":             date(2024, 5, 1, 0, 0, 0),
+		"2024-05-01 21:30:00":  date(2024, 5, 1, 21, 30, 0),
+		"2024-05-01T21:30":     date(2024, 5, 1, 21, 30, 0),
+		"2024-05-01T12:30:00Z": date(2024, 5, 1, 21, 30, 0),
+		"昨日":                   now.AddDate(0, 0, -1),
+		"一昨日":                  now.AddDate(0, 0, -2),
+		"昨日21時":                date(2024, 5, 5, 21, 0, 0),
+		"今日 午後9時半":             date(2024, 5, 6, 21, 30, 0),
+		"5月1日":                 date(2024, 5, 1, 0, 0, 0),
+		"2023年5月1日 21:00":      date(2023, 5, 1, 21, 0, 0),
+		"５月１日":                 date(2024, 5, 1, 0, 0, 0),
+		"金曜日":                  date(2024, 5, 3, 0, 0, 0),
+		"先週の月曜日":               date(2024, 4, 29, 0, 0, 0),
+		"2時間前":                 now.Add(-2 * time.Hour),
+		"3日前":                  now.AddDate(0, 0, -3),
+	}
+
+	for input, expected := range cases {
+		actual, err := timeparse.ParseDate(input, now)
+		if assert.NoError(t, err, input) {
+			assert.True(t, expected.Equal(actual), "%s: expected %s, got %s", input, expected, actual)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	now := time.Date(2024, 5, 6, 21, 30, 0, 0, time.UTC)
+
+	for _, input := range []string{
+		"",
+		"someday",
+		"2024-02-30",
+		"13/1",
+		"25:00",
+		"today 13pm",
+		"now 21:00",
+		"-2 fortnights",
+		"-1.5d",
+		"5",
+	} {
+		_, err := timeparse.ParseDate(input, now)
+		assert.ErrorIs(t, err, timeparse.ErrInvalidDate, input)
+	}
+}
+
+func TestParseDateEnd(t *testing.T) {
+	now := time.Date(2024, 5, 6, 21, 30, 0, 0, time.UTC)
+
+	end, err := timeparse.ParseDateEnd("2024-05-01", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 23, 59, 59, 999999999, time.UTC), end)
+
+	end, err = timeparse.ParseDateEnd("2024-05-01 12:00", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), end)
+
+	end, err = timeparse.ParseDateEnd("today", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now, end)
+}
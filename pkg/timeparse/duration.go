@@ -0,0 +1,114 @@
+package timeparse
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDuration = errors.New("invalid duration")
+
+var (
+	minutesPattern       = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+	clockDurationPattern = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})$`)
+	durationUnitPattern  = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+|時間|分|秒)`)
+)
+
+var durationUnits = map[string]time.Duration{
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"秒":       time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"分":       time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hrs":     time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"時間":      time.Hour,
+}
+
+// ParseDuration parses a non-negative duration such as "1h30m", "1.5h",
+// "1 hour 30 mins", "1:30:00" or "1時間30分". Numbers without a unit are
+// taken as minutes, so "90" is an hour and a half.
+func ParseDuration(input string) (time.Duration, error) {
+	s := normalize(input)
+	if s == "" {
+		return 0, ErrInvalidDuration
+	}
+
+	if minutesPattern.MatchString(s) {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, ErrInvalidDuration
+		}
+		return scaleDuration(n, time.Minute)
+	}
+
+	if m := clockDurationPattern.FindStringSubmatch(s); m != nil {
+		hours, minutes, seconds := atoi(m[1]), atoi(m[2]), atoi(m[3])
+		if minutes > 59 || seconds > 59 {
+			return 0, ErrInvalidDuration
+		}
+		return time.Duration(hours)*time.Hour +
+			time.Duration(minutes)*time.Minute +
+			time.Duration(seconds)*time.Second, nil
+	}
+
+	s = strings.ReplaceAll(s, "時間半", "時間30分")
+
+	matches := durationUnitPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return 0, ErrInvalidDuration
+	}
+
+	var d time.Duration
+	consumed := 0
+	for _, m := range matches {
+		if strings.TrimSpace(s[consumed:m[0]]) != "" {
+			return 0, ErrInvalidDuration
+		}
+		consumed = m[1]
+
+		unit, ok := durationUnits[s[m[4]:m[5]]]
+		if !ok {
+			return 0, ErrInvalidDuration
+		}
+
+		n, err := strconv.ParseFloat(s[m[2]:m[3]], 64)
+		if err != nil {
+			return 0, ErrInvalidDuration
+		}
+
+		part, err := scaleDuration(n, unit)
+		if err != nil || d > math.MaxInt64-part {
+			return 0, ErrInvalidDuration
+		}
+		d += part
+	}
+
+	if strings.TrimSpace(s[consumed:]) != "" {
+		return 0, ErrInvalidDuration
+	}
+
+	return d, nil
+}
+
+func scaleDuration(n float64, unit time.Duration) (time.Duration, error) {
+	d := n * float64(unit)
+	if d >= math.MaxInt64 {
+		return 0, ErrInvalidDuration
+	}
+
+	return time.Duration(d).Round(time.Second), nil
+}
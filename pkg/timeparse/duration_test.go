@@ -0,0 +1,59 @@
+package timeparse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xoltia/botsu/pkg/timeparse"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"90":               90 * time.Minute,
+		"1.5":              90 * time.Second,
+		"1h30m":            90 * time.Minute,
+		"1h30m0s":          90 * time.Minute,
+		"1.5h":             90 * time.Minute,
+		"45min":            45 * time.Minute,
+		"1 hour 30 mins":   90 * time.Minute,
+		"2 hours":          2 * time.Hour,
+		"30s":              30 * time.Second,
+		"1:30:00":          90 * time.Minute,
+		"0:05:30":          5*time.Minute + 30*time.Second,
+		"1時間30分":           90 * time.Minute,
+		"1時間半":             90 * time.Minute,
+		"45分":              45 * time.Minute,
+		"１時間":              time.Hour,
+		" 1H 30M ":         90 * time.Minute,
+		"0":                0,
+		"10h0m0s":          10 * time.Hour,
+		"1 hr 2 min 3 sec": time.Hour + 2*time.Minute + 3*time.Second,
+	}
+
+	for input, expected := range cases {
+		actual, err := timeparse.ParseDuration(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, expected, actual, input)
+		}
+	}
+}
+
+func TestParseDurationInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"-1h",
+		"1:30",
+		"1:60:00",
+		"1 fortnight",
+		"h",
+		"1h and 30m",
+		"1e3",
+		"99999999999999h",
+	}
+
+	for _, input := range inputs {
+		_, err := timeparse.ParseDuration(input)
+		assert.ErrorIs(t, err, timeparse.ErrInvalidDuration, input)
+	}
+}